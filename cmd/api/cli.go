@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"myproject/internal/repository"
+	"myproject/internal/service"
+)
+
+const cliUsage = `用法:
+  api import -user <用户名> -format <csv|json|ndjson|todotxt|markdown> [-dry-run] [文件]
+  api export -user <用户名> -format <csv|json|ndjson|todotxt|markdown> [-o 文件]
+  api role -user <用户名> -role <user|admin>
+
+不指定文件时从标准输入读取、向标准输出写入。
+`
+
+// runCommand 执行命令行子命令，返回进程退出码
+func runCommand(args []string) int {
+	switch args[0] {
+	case "import":
+		return runImport(args[1:])
+	case "export":
+		return runExport(args[1:])
+	case "role":
+		return runRole(args[1:])
+	default:
+		fmt.Fprint(os.Stderr, cliUsage)
+		return 2
+	}
+}
+
+func runImport(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	username := fs.String("user", "", "导入到该用户")
+	format := fs.String("format", service.FormatTodoTxt, "文件格式")
+	dryRun := fs.Bool("dry-run", false, "只校验不写入")
+	if err := fs.Parse(args); err != nil || *username == "" || fs.NArg() > 1 {
+		fmt.Fprint(os.Stderr, cliUsage)
+		return 2
+	}
+
+	user, err := repository.GetUserByUsername(*username)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "用户不存在: %s\n", *username)
+		return 1
+	}
+
+	var in io.Reader = os.Stdin
+	if fs.NArg() == 1 {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		in = f
+	}
+
+	result, err := service.ImportTasks(*user, *format, in, *dryRun)
+	if result != nil {
+		for _, rowErr := range result.Errors {
+			if rowErr.Line > 0 {
+				fmt.Fprintf(os.Stderr, "第 %d 行: %s\n", rowErr.Line, rowErr.Error)
+			} else {
+				fmt.Fprintf(os.Stderr, "第 %d 条: %s\n", rowErr.Row, rowErr.Error)
+			}
+		}
+		summary, _ := json.Marshal(result)
+		fmt.Println(string(summary))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "导入失败:", err)
+		return 1
+	}
+	if result.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	username := fs.String("user", "", "导出该用户的任务")
+	format := fs.String("format", service.FormatTodoTxt, "文件格式")
+	output := fs.String("o", "", "输出文件")
+	if err := fs.Parse(args); err != nil || *username == "" || fs.NArg() > 0 {
+		fmt.Fprint(os.Stderr, cliUsage)
+		return 2
+	}
+
+	user, err := repository.GetUserByUsername(*username)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "用户不存在: %s\n", *username)
+		return 1
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if err := service.ExportTasks(*user, *format, out); err != nil {
+		fmt.Fprintln(os.Stderr, "导出失败:", err)
+		return 1
+	}
+	return 0
+}
+
+// runRole 设置用户角色，用于创建第一个管理员
+func runRole(args []string) int {
+	fs := flag.NewFlagSet("role", flag.ContinueOnError)
+	username := fs.String("user", "", "用户名")
+	role := fs.String("role", "", "角色")
+	if err := fs.Parse(args); err != nil || *username == "" || *role == "" || fs.NArg() > 0 {
+		fmt.Fprint(os.Stderr, cliUsage)
+		return 2
+	}
+
+	if _, err := service.AssignRole(*username, *role); err != nil {
+		switch err {
+		case service.ErrUserNotFound:
+			fmt.Fprintf(os.Stderr, "用户不存在: %s\n", *username)
+		case service.ErrUnknownRole:
+			fmt.Fprintf(os.Stderr, "未知角色: %s\n", *role)
+		default:
+			fmt.Fprintln(os.Stderr, "设置角色失败:", err)
+		}
+		return 1
+	}
+	fmt.Printf("已将 %s 的角色设置为 %s\n", *username, *role)
+	return 0
+}
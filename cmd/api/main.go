@@ -1,10 +1,28 @@
 package main
 
 import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"myproject/config"
 	_ "myproject/docs"
+	"myproject/internal/events"
+	"myproject/internal/mail"
+	"myproject/internal/middleware"
 	models "myproject/internal/model"
+	"myproject/internal/notify"
+	"myproject/internal/oidc"
+	"myproject/internal/realtime"
 	"myproject/internal/routes"
+	"myproject/internal/scheduler"
+	"myproject/internal/service"
+	"myproject/internal/throttle"
+	"myproject/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -36,14 +54,65 @@ func main() {
 	config.ConnectDB()
 
 	// 自动迁移
-	config.DB.AutoMigrate(&models.User{}, &models.Task{})
+	config.DB.AutoMigrate(models.All()...)
+
+	// 任务变更时为订阅的 webhook 加入投递队列，并推送给在线客户端
+	events.Subscribe(service.HandleTaskEvent)
+	events.Subscribe(realtime.DefaultHub.HandleTaskEvent)
+
+	// 命令行子命令（导入导出），执行完直接退出
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+
+	// 没有可用的令牌签名密钥时拒绝启动
+	if err := utils.InitJWT(); err != nil {
+		log.Fatalf("JWT 签名密钥配置错误: %v", err)
+	}
 
-	// 创建路由
-	r := gin.Default()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// 后台发送到期提醒、每日摘要和 webhook，生成个人数据导出
+	mailer := mail.FromEnv()
+	service.SetMailer(mailer)
+	service.SetOIDCProviders(oidc.ConfigsFromEnv())
+
+	// 登录失败计数默认保存在数据库中，多实例共享；单实例可以设置 LOGIN_THROTTLE_STORE=memory
+	if os.Getenv("LOGIN_THROTTLE_STORE") == "memory" {
+		service.SetLoginThrottleStore(throttle.NewMemoryStore())
+	} else {
+		service.SetLoginThrottleStore(throttle.NewDBStore(config.DB))
+	}
+	reminders := scheduler.NewReminderScheduler(notify.NewInAppChannel(), notify.NewEmailChannel(mailer))
+	go reminders.Run(ctx)
+	go scheduler.NewDigestScheduler(mailer).Run(ctx)
+	go scheduler.NewWebhookDispatcher().Run(ctx)
+	go scheduler.NewDataExportWorker().Run(ctx)
+	go realtime.DefaultHub.Run(ctx)
+	go scheduler.RunCleanup(ctx)
+
+	// 创建路由。访问日志隐去查询参数中的 token
+	r := gin.New()
+	r.Use(middleware.Logger(), gin.Recovery())
+	// 只采信 TRUSTED_PROXIES 中的反向代理转发的客户端 IP，默认不信任任何代理
+	if err := routes.SetTrustedProxies(r, os.Getenv("TRUSTED_PROXIES")); err != nil {
+		log.Fatalf("TRUSTED_PROXIES 配置错误: %v", err)
+	}
 
 	// 设置路由
-	routes.SetupRoutes(r)
+	routes.SetupRoutes(r, routes.NewHandlers(config.DB))
+
+	// 启动服务，收到退出信号后优雅关闭
+	srv := &http.Server{Addr: ":8080", Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("服务启动失败: %v", err)
+		}
+	}()
 
-	// 启动服务
-	r.Run(":8080")
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	srv.Shutdown(shutdownCtx)
 }
\ No newline at end of file
@@ -3,30 +3,153 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/glebarez/sqlite"
 	"github.com/joho/godotenv"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
 var DB *gorm.DB
 
+// 支持的数据库
+const (
+    DriverMySQL    = "mysql"
+    DriverPostgres = "postgres"
+    DriverSQLite   = "sqlite"
+)
+
+// ConnectDB 连接数据库。DB_DRIVER 选择 mysql（默认）、postgres 或 sqlite；
+// 设置了 DATABASE_URL 时直接用作连接串，否则由 DB_HOST、DB_PORT、DB_USER、DB_PASSWORD、DB_NAME 拼接，
+// sqlite 的 DB_NAME 是数据库文件路径
 func ConnectDB() {
     // 加载 .env 文件
     godotenv.Load()
-    
-    dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-        os.Getenv("DB_USER"),
-        os.Getenv("DB_PASSWORD"),
-        os.Getenv("DB_HOST"),
-        os.Getenv("DB_PORT"),
-        os.Getenv("DB_NAME"),
-    )
-    
-    var err error
-    DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{})
+
+    driver, dsn, err := DatabaseFromEnv()
+    if err != nil {
+        panic("数据库配置错误: " + err.Error())
+    }
+
+    DB, err = Open(driver, dsn)
     if err != nil {
         panic("数据库连接失败: " + err.Error())
     }
-    fmt.Println("数据库连接成功")
-}
\ No newline at end of file
+    fmt.Printf("数据库连接成功 (%s)\n", driver)
+}
+
+// DatabaseFromEnv 从环境变量读取数据库类型和连接串。
+// 没有设置 DB_DRIVER 时按 DATABASE_URL 的前缀判断，例如 postgres://、sqlite:
+func DatabaseFromEnv() (driver, dsn string, err error) {
+    url := os.Getenv("DATABASE_URL")
+    driver, err = normalizeDriver(os.Getenv("DB_DRIVER"), url)
+    if err != nil {
+        return "", "", err
+    }
+    if url != "" {
+        return driver, url, nil
+    }
+
+    host, port := os.Getenv("DB_HOST"), os.Getenv("DB_PORT")
+    user, password, name := os.Getenv("DB_USER"), os.Getenv("DB_PASSWORD"), os.Getenv("DB_NAME")
+    switch driver {
+    case DriverMySQL:
+        dsn = fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local", user, password, host, port, name)
+    case DriverPostgres:
+        sslMode := os.Getenv("DB_SSLMODE")
+        if sslMode == "" {
+            sslMode = "disable"
+        }
+        dsn = fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s", host, port, user, password, name, sslMode)
+    case DriverSQLite:
+        dsn = name
+        if dsn == "" {
+            dsn = "taskflow.db"
+        }
+    }
+    return driver, dsn, nil
+}
+
+// normalizeDriver 统一数据库类型的写法
+func normalizeDriver(driver, url string) (string, error) {
+    driver = strings.ToLower(strings.TrimSpace(driver))
+    if driver == "" {
+        switch {
+        case strings.HasPrefix(url, "postgres://"), strings.HasPrefix(url, "postgresql://"):
+            driver = DriverPostgres
+        case strings.HasPrefix(url, "sqlite:"), strings.HasPrefix(url, "file:"):
+            driver = DriverSQLite
+        default:
+            driver = DriverMySQL
+        }
+    }
+
+    switch driver {
+    case DriverMySQL:
+        return DriverMySQL, nil
+    case DriverPostgres, "postgresql", "pgx":
+        return DriverPostgres, nil
+    case DriverSQLite, "sqlite3":
+        return DriverSQLite, nil
+    }
+    return "", fmt.Errorf("不支持的数据库类型 %q，可选 mysql、postgres、sqlite", driver)
+}
+
+// Open 按数据库类型打开连接
+func Open(driver, dsn string) (*gorm.DB, error) {
+    var dialector gorm.Dialector
+    switch driver {
+    case DriverMySQL:
+        dialector = mysql.Open(strings.TrimPrefix(dsn, "mysql://"))
+    case DriverPostgres:
+        dialector = postgres.Open(dsn)
+    case DriverSQLite:
+        dialector = sqlite.Open(sqliteDSN(dsn))
+    default:
+        return nil, fmt.Errorf("不支持的数据库类型 %q", driver)
+    }
+
+    db, err := gorm.Open(dialector, &gorm.Config{})
+    if err != nil {
+        return nil, err
+    }
+
+    if driver == DriverSQLite && strings.Contains(dsn, ":memory:") {
+        // 内存数据库只存在于创建它的连接中，所有请求共用一个连接
+        sqlDB, err := db.DB()
+        if err != nil {
+            return nil, err
+        }
+        sqlDB.SetMaxOpenConns(1)
+    }
+    return db, nil
+}
+
+// sqliteDSN 为 SQLite 连接补上默认设置：与其它数据库一样检查外键；
+// 写入时等待其它连接释放锁而不是立即报错；文件数据库使用 WAL，读写互不阻塞
+func sqliteDSN(dsn string) string {
+    dsn = strings.TrimPrefix(strings.TrimPrefix(dsn, "sqlite://"), "sqlite:")
+
+    pragmas := []string{"_pragma=foreign_keys(1)", "_pragma=busy_timeout(5000)"}
+    if !strings.Contains(dsn, ":memory:") {
+        pragmas = append(pragmas, "_pragma=journal_mode(WAL)")
+    }
+
+    var missing []string
+    for _, pragma := range pragmas {
+        name := pragma[:strings.Index(pragma, "(")]
+        if !strings.Contains(dsn, name) {
+            missing = append(missing, pragma)
+        }
+    }
+    if len(missing) == 0 {
+        return dsn
+    }
+    sep := "?"
+    if strings.Contains(dsn, "?") {
+        sep = "&"
+    }
+    return dsn + sep + strings.Join(missing, "&")
+}
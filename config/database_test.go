@@ -0,0 +1,141 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeDriver(t *testing.T) {
+	tests := []struct {
+		driver, url, want string
+	}{
+		{"", "", DriverMySQL},
+		{"", "user:pass@tcp(localhost:3306)/app", DriverMySQL},
+		{"", "postgres://localhost/app", DriverPostgres},
+		{"", "postgresql://localhost/app", DriverPostgres},
+		{"", "sqlite:app.db", DriverSQLite},
+		{"", "file:app.db?cache=shared", DriverSQLite},
+		{" PostgreSQL ", "", DriverPostgres},
+		{"pgx", "", DriverPostgres},
+		{"sqlite3", "", DriverSQLite},
+		// 显式指定的类型优先于连接串前缀
+		{"mysql", "postgres://localhost/app", DriverMySQL},
+	}
+	for _, tt := range tests {
+		got, err := normalizeDriver(tt.driver, tt.url)
+		if err != nil || got != tt.want {
+			t.Errorf("normalizeDriver(%q, %q) = %q, %v, want %q", tt.driver, tt.url, got, err, tt.want)
+		}
+	}
+
+	if _, err := normalizeDriver("oracle", ""); err == nil {
+		t.Error("normalizeDriver accepted an unsupported driver")
+	}
+}
+
+func TestDatabaseFromEnv(t *testing.T) {
+	for _, key := range []string{"DATABASE_URL", "DB_DRIVER", "DB_HOST", "DB_PORT", "DB_USER", "DB_PASSWORD", "DB_NAME", "DB_SSLMODE"} {
+		t.Setenv(key, "")
+	}
+	set := func(env map[string]string) {
+		for key, value := range env {
+			t.Setenv(key, value)
+		}
+	}
+
+	set(map[string]string{"DB_HOST": "db", "DB_PORT": "5432", "DB_USER": "app", "DB_PASSWORD": "secret", "DB_NAME": "tasks"})
+
+	driver, dsn, err := DatabaseFromEnv()
+	if err != nil || driver != DriverMySQL || dsn != "app:secret@tcp(db:5432)/tasks?charset=utf8mb4&parseTime=True&loc=Local" {
+		t.Errorf("mysql: driver=%q dsn=%q err=%v", driver, dsn, err)
+	}
+
+	set(map[string]string{"DB_DRIVER": "postgres"})
+	driver, dsn, err = DatabaseFromEnv()
+	if err != nil || driver != DriverPostgres || dsn != "host=db port=5432 user=app password=secret dbname=tasks sslmode=disable" {
+		t.Errorf("postgres: driver=%q dsn=%q err=%v", driver, dsn, err)
+	}
+
+	set(map[string]string{"DB_DRIVER": "sqlite", "DB_NAME": ""})
+	if driver, dsn, err = DatabaseFromEnv(); err != nil || driver != DriverSQLite || dsn != "taskflow.db" {
+		t.Errorf("sqlite: driver=%q dsn=%q err=%v", driver, dsn, err)
+	}
+
+	// DATABASE_URL 直接作为连接串，未设置 DB_DRIVER 时按前缀判断类型
+	set(map[string]string{"DB_DRIVER": "", "DATABASE_URL": "postgres://app@db/tasks"})
+	if driver, dsn, err = DatabaseFromEnv(); err != nil || driver != DriverPostgres || dsn != "postgres://app@db/tasks" {
+		t.Errorf("url: driver=%q dsn=%q err=%v", driver, dsn, err)
+	}
+
+	set(map[string]string{"DB_DRIVER": "mongodb"})
+	if _, _, err = DatabaseFromEnv(); err == nil {
+		t.Error("unsupported DB_DRIVER was accepted")
+	}
+}
+
+func TestSQLiteDSN(t *testing.T) {
+	tests := []struct {
+		dsn, want string
+	}{
+		{":memory:", ":memory:?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"},
+		{"sqlite::memory:", ":memory:?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"},
+		{"app.db", "app.db?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"},
+		{"sqlite://app.db?cache=shared", "app.db?cache=shared&_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"},
+		// 已经设置的项保持不变
+		{"app.db?_pragma=journal_mode(DELETE)", "app.db?_pragma=journal_mode(DELETE)&_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"},
+		{"app.db?_pragma=foreign_keys(0)&_pragma=busy_timeout(1)&_pragma=journal_mode(WAL)", "app.db?_pragma=foreign_keys(0)&_pragma=busy_timeout(1)&_pragma=journal_mode(WAL)"},
+	}
+	for _, tt := range tests {
+		if got := sqliteDSN(tt.dsn); got != tt.want {
+			t.Errorf("sqliteDSN(%q) = %q, want %q", tt.dsn, got, tt.want)
+		}
+	}
+}
+
+func TestOpenSQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.db")
+	db, err := Open(DriverSQLite, "sqlite:"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	defer sqlDB.Close()
+
+	if name := db.Dialector.Name(); name != "sqlite" {
+		t.Errorf("dialector = %q, want sqlite", name)
+	}
+	var foreignKeys int
+	var journalMode string
+	db.Raw("PRAGMA foreign_keys").Scan(&foreignKeys)
+	db.Raw("PRAGMA journal_mode").Scan(&journalMode)
+	if foreignKeys != 1 || !strings.EqualFold(journalMode, "wal") {
+		t.Errorf("foreign_keys=%d journal_mode=%q, want 1 and wal", foreignKeys, journalMode)
+	}
+}
+
+func TestOpenMemorySQLiteUsesOneConnection(t *testing.T) {
+	db, err := Open(DriverSQLite, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	defer sqlDB.Close()
+
+	if max := sqlDB.Stats().MaxOpenConnections; max != 1 {
+		t.Errorf("max open connections = %d, want 1", max)
+	}
+	// 每个连接都有自己的内存数据库，建的表必须对后续查询可见
+	if err := db.Exec("CREATE TABLE t (id INTEGER)").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("INSERT INTO t VALUES (1)").Error; err != nil {
+		t.Errorf("table is not visible to the next query: %v", err)
+	}
+}
+
+func TestOpenRejectsUnknownDriver(t *testing.T) {
+	if _, err := Open("oracle", "whatever"); err == nil {
+		t.Error("Open accepted an unsupported driver")
+	}
+}
@@ -0,0 +1,117 @@
+// Package dataexport 把用户的个人数据打包为 ZIP：每类记录一个 JSON 文件，外加一份可直接阅读的 HTML 摘要。
+package dataexport
+
+import (
+	"archive/zip"
+	"embed"
+	"encoding/json"
+	htmltemplate "html/template"
+	"io"
+	"time"
+
+	models "myproject/internal/model"
+)
+
+//go:embed templates/*
+var templateFS embed.FS
+
+var funcs = map[string]interface{}{
+	"datetime": func(t interface{}, loc *time.Location) string {
+		switch v := t.(type) {
+		case time.Time:
+			return v.In(loc).Format("2006-01-02 15:04")
+		case *time.Time:
+			if v == nil {
+				return ""
+			}
+			return v.In(loc).Format("2006-01-02 15:04")
+		}
+		return ""
+	},
+}
+
+var summaryTemplate = htmltemplate.Must(htmltemplate.New("summary.html.tmpl").Funcs(funcs).ParseFS(templateFS, "templates/summary.html.tmpl"))
+
+// Archive 一个用户的全部个人数据
+type Archive struct {
+	GeneratedAt       time.Time
+	User              models.User
+	Tasks             []models.Task
+	Tags              []models.Tag
+	Reminders         []models.Reminder
+	Notifications     []models.Notification
+	Templates         []models.Template
+	DigestSettings    []models.DigestSetting
+	DigestLogs        []models.DigestLog
+	Webhooks          []models.Webhook
+	WebhookDeliveries []models.WebhookDelivery
+	Sessions          []models.Session
+	AccessTokens      []models.PersonalAccessToken
+	Identities        []models.UserIdentity
+	AuditLogs         []models.AuditLog
+}
+
+// Location 摘要中格式化时间使用的时区
+func (a Archive) Location() *time.Location {
+	return a.User.Location()
+}
+
+// Section 归档中的一个 JSON 文件
+type Section struct {
+	File  string
+	Title string
+	Count int
+	Data  interface{}
+}
+
+// Sections 按固定顺序列出归档中的 JSON 文件
+func (a Archive) Sections() []Section {
+	return []Section{
+		{"tasks.json", "任务", len(a.Tasks), a.Tasks},
+		{"tags.json", "标签", len(a.Tags), a.Tags},
+		{"reminders.json", "提醒", len(a.Reminders), a.Reminders},
+		{"notifications.json", "站内通知", len(a.Notifications), a.Notifications},
+		{"templates.json", "任务模板", len(a.Templates), a.Templates},
+		{"digest_settings.json", "每日摘要设置", len(a.DigestSettings), a.DigestSettings},
+		{"digest_logs.json", "每日摘要发送记录", len(a.DigestLogs), a.DigestLogs},
+		{"webhooks.json", "Webhook", len(a.Webhooks), a.Webhooks},
+		{"webhook_deliveries.json", "Webhook 投递记录", len(a.WebhookDeliveries), a.WebhookDeliveries},
+		{"sessions.json", "登录会话", len(a.Sessions), a.Sessions},
+		{"access_tokens.json", "个人访问令牌", len(a.AccessTokens), a.AccessTokens},
+		{"identities.json", "外部登录账号", len(a.Identities), a.Identities},
+		{"audit_logs.json", "安全审计记录", len(a.AuditLogs), a.AuditLogs},
+	}
+}
+
+// Write 把归档写为 ZIP
+func Write(w io.Writer, a Archive) error {
+	zw := zip.NewWriter(w)
+
+	if err := writeJSON(zw, "profile.json", a.User, a.GeneratedAt); err != nil {
+		return err
+	}
+	for _, section := range a.Sections() {
+		if err := writeJSON(zw, section.File, section.Data, a.GeneratedAt); err != nil {
+			return err
+		}
+	}
+
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: "index.html", Method: zip.Deflate, Modified: a.GeneratedAt})
+	if err != nil {
+		return err
+	}
+	if err := summaryTemplate.Execute(f, a); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func writeJSON(zw *zip.Writer, name string, v interface{}, modified time.Time) error {
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
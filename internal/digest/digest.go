@@ -0,0 +1,60 @@
+// Package digest 渲染每日任务摘要邮件。
+package digest
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+	"time"
+
+	models "myproject/internal/model"
+)
+
+//go:embed templates/*
+var templateFS embed.FS
+
+var funcs = map[string]interface{}{
+	"due": func(t *time.Time, loc *time.Location) string {
+		if t == nil {
+			return ""
+		}
+		return t.In(loc).Format("01-02 15:04")
+	},
+}
+
+var (
+	textTemplate = texttemplate.Must(texttemplate.New("digest.txt.tmpl").Funcs(funcs).ParseFS(templateFS, "templates/digest.txt.tmpl"))
+	htmlTemplate = htmltemplate.Must(htmltemplate.New("digest.html.tmpl").Funcs(funcs).ParseFS(templateFS, "templates/digest.html.tmpl"))
+)
+
+// Digest 一封摘要的内容
+type Digest struct {
+	User      models.User
+	Date      time.Time // 用户时区下的当天零点
+	Today     []models.Task
+	Overdue   []models.Task
+	Completed []models.Task // 昨天完成的任务
+}
+
+// Location 模板中格式化时间使用的时区
+func (d Digest) Location() *time.Location {
+	return d.Date.Location()
+}
+
+// Subject 邮件标题
+func (d Digest) Subject() string {
+	return "TaskFlow 每日摘要 " + d.Date.Format("2006-01-02")
+}
+
+// Render 渲染纯文本和 HTML 两个版本
+func Render(d Digest) (text, html string, err error) {
+	var textBuf, htmlBuf bytes.Buffer
+	if err := textTemplate.Execute(&textBuf, d); err != nil {
+		return "", "", err
+	}
+	if err := htmlTemplate.Execute(&htmlBuf, d); err != nil {
+		return "", "", err
+	}
+	return textBuf.String(), htmlBuf.String(), nil
+}
@@ -0,0 +1,66 @@
+// Package events 在进程内分发任务变更事件，供 webhook、实时推送等模块订阅。
+package events
+
+import (
+	"sync"
+	"time"
+
+	models "myproject/internal/model"
+)
+
+// 任务事件类型
+const (
+	TaskCreated   = "task.created"
+	TaskUpdated   = "task.updated"
+	TaskDeleted   = "task.deleted"
+	TaskCompleted = "task.completed"
+)
+
+// Types 全部事件类型
+var Types = []string{TaskCreated, TaskUpdated, TaskDeleted, TaskCompleted}
+
+// Event 一次任务变更
+type Event struct {
+	Type       string      `json:"type"`
+	UserID     uint        `json:"user_id"`
+	Task       models.Task `json:"task"`
+	OccurredAt time.Time   `json:"occurred_at"`
+}
+
+// Handler 事件处理函数，在发布者的 goroutine 中同步调用，不应长时间阻塞
+type Handler func(Event)
+
+var (
+	mu       sync.RWMutex
+	handlers []Handler
+)
+
+// Subscribe 注册事件处理函数
+func Subscribe(h Handler) {
+	mu.Lock()
+	defer mu.Unlock()
+	handlers = append(handlers, h)
+}
+
+// Publish 向所有订阅者分发事件
+func Publish(e Event) {
+	if e.OccurredAt.IsZero() {
+		e.OccurredAt = time.Now()
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, h := range handlers {
+		h(e)
+	}
+}
+
+// IsValidType 判断事件类型是否合法
+func IsValidType(t string) bool {
+	for _, typ := range Types {
+		if typ == t {
+			return true
+		}
+	}
+	return false
+}
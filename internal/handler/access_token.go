@@ -0,0 +1,116 @@
+package handler
+
+import (
+	models "myproject/internal/model"
+	"myproject/internal/service"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreateAccessTokenInput struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`                   // 例如 tasks:read、tasks:write
+	ExpiresInDays *int     `json:"expires_in_days" binding:"omitempty,min=1,max=365"` // 不填表示永不过期
+}
+
+// GetAccessTokens 获取个人访问令牌列表
+// @Summary      获取个人访问令牌
+// @Description  获取当前用户创建的个人访问令牌，不包含令牌明文
+// @Tags         访问令牌
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string]interface{}  "令牌列表"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Router       /api/access-tokens [get]
+func GetAccessTokens(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	tokens, err := service.GetAccessTokens(currentUser)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"access_tokens": tokens})
+}
+
+// CreateAccessToken 创建个人访问令牌
+// @Summary      创建个人访问令牌
+// @Description  创建供脚本使用的访问令牌，以 Bearer 方式传入 Authorization。令牌明文只在创建时返回一次；写权限包含对应的读权限
+// @Tags         访问令牌
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body CreateAccessTokenInput true "令牌信息"
+// @Success      200  {object}  map[string]interface{}  "创建成功"
+// @Failure      400  {object}  map[string]interface{}  "请求参数错误"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Router       /api/access-tokens [post]
+func CreateAccessToken(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	var input CreateAccessTokenInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var expiresAt *time.Time
+	if input.ExpiresInDays != nil {
+		t := time.Now().AddDate(0, 0, *input.ExpiresInDays)
+		expiresAt = &t
+	}
+
+	token, raw, err := service.CreateAccessToken(currentUser, input.Name, input.Scopes, expiresAt)
+	if err != nil {
+		switch err {
+		case service.ErrInvalidScope:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的权限", "scopes": service.Scopes})
+		case service.ErrCreateAccessTokenFail:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "创建失败"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "创建成功",
+		"access_token": token,
+		"token":        raw,
+	})
+}
+
+// DeleteAccessToken 吊销个人访问令牌
+// @Summary      吊销个人访问令牌
+// @Description  删除后使用该令牌的请求立即失效
+// @Tags         访问令牌
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "令牌ID"
+// @Success      200  {object}  map[string]interface{}  "已吊销"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Failure      404  {object}  map[string]interface{}  "令牌不存在"
+// @Router       /api/access-tokens/{id} [delete]
+func DeleteAccessToken(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	if err := service.DeleteAccessToken(currentUser, c.Param("id")); err != nil {
+		switch err {
+		case service.ErrAccessTokenNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "令牌不存在"})
+		case service.ErrDeleteAccessTokenFail:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "删除失败"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已吊销"})
+}
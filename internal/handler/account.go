@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"myproject/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type VerifyEmailInput struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type EmailInput struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordInput struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+// VerifyEmail 验证邮箱
+// @Summary      验证邮箱
+// @Description  使用验证邮件中的令牌确认邮箱，令牌只能使用一次
+// @Tags         用户
+// @Accept       json
+// @Produce      json
+// @Param        request body VerifyEmailInput true "验证令牌"
+// @Success      200  {object}  map[string]interface{}  "邮箱已验证"
+// @Failure      400  {object}  map[string]interface{}  "令牌无效或已过期"
+// @Router       /email/verify [post]
+func VerifyEmail(c *gin.Context) {
+	var input VerifyEmailInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := service.VerifyEmail(input.Token); err != nil {
+		switch err {
+		case service.ErrInvalidActionToken:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "链接无效或已过期"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "邮箱已验证"})
+}
+
+// ResendVerificationEmail 重新发送验证邮件
+// @Summary      重新发送验证邮件
+// @Description  向未验证的邮箱重新发送验证链接，无论邮箱是否存在都返回成功
+// @Tags         用户
+// @Accept       json
+// @Produce      json
+// @Param        request body EmailInput true "邮箱"
+// @Success      200  {object}  map[string]interface{}  "已发送"
+// @Failure      400  {object}  map[string]interface{}  "请求参数错误"
+// @Router       /email/verify/resend [post]
+func ResendVerificationEmail(c *gin.Context) {
+	var input EmailInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := service.ResendVerificationEmail(input.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "如果该邮箱已注册且未验证，验证邮件已发送"})
+}
+
+// ForgotPassword 忘记密码
+// @Summary      忘记密码
+// @Description  向邮箱发送重置密码链接，无论邮箱是否存在都返回成功
+// @Tags         用户
+// @Accept       json
+// @Produce      json
+// @Param        request body EmailInput true "邮箱"
+// @Success      200  {object}  map[string]interface{}  "已发送"
+// @Failure      400  {object}  map[string]interface{}  "请求参数错误"
+// @Router       /password/forgot [post]
+func ForgotPassword(c *gin.Context) {
+	var input EmailInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := service.ForgotPassword(input.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "如果该邮箱已注册，重置密码邮件已发送"})
+}
+
+// ResetPassword 重置密码
+// @Summary      重置密码
+// @Description  使用重置邮件中的令牌设置新密码，成功后所有设备都需要重新登录
+// @Tags         用户
+// @Accept       json
+// @Produce      json
+// @Param        request body ResetPasswordInput true "令牌和新密码"
+// @Success      200  {object}  map[string]interface{}  "密码已重置"
+// @Failure      400  {object}  map[string]interface{}  "令牌无效或已过期"
+// @Failure      500  {object}  map[string]interface{}  "服务器错误"
+// @Router       /password/reset [post]
+func ResetPassword(c *gin.Context) {
+	var input ResetPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := service.ResetPassword(input.Token, input.Password); err != nil {
+		switch err {
+		case service.ErrInvalidActionToken:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "链接无效或已过期"})
+		case service.ErrHashPasswordFailed:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "密码加密失败"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "重置密码失败"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "密码已重置，请重新登录"})
+}
@@ -0,0 +1,332 @@
+package handler
+
+import (
+	models "myproject/internal/model"
+	"myproject/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+type DisableUserInput struct {
+	Reason string `json:"reason" binding:"max=255"`
+}
+
+type SetUserRoleInput struct {
+	Role        string   `json:"role" binding:"required"`
+	Permissions []string `json:"permissions"` // 角色之外额外授予的权限
+}
+
+// AdminListUsers 搜索用户
+// @Summary      搜索用户
+// @Description  按用户名或邮箱搜索用户，可按角色和是否禁用筛选，分页返回
+// @Tags         管理
+// @Produce      json
+// @Security     BearerAuth
+// @Param        q          query  string  false  "用户名或邮箱关键字"
+// @Param        role       query  string  false  "角色"
+// @Param        disabled   query  bool    false  "是否已禁用"
+// @Param        page       query  int     false  "页码，从 1 开始"
+// @Param        page_size  query  int     false  "每页数量，最大 100"
+// @Success      200  {object}  map[string]interface{}  "用户列表"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Failure      403  {object}  map[string]interface{}  "没有权限"
+// @Router       /api/admin/users [get]
+func AdminListUsers(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	page, pageSize := parsePage(c)
+	filter := service.UserFilter{
+		Query:    c.Query("q"),
+		Role:     c.Query("role"),
+		Page:     page,
+		PageSize: pageSize,
+	}
+	if v := c.Query("disabled"); v != "" {
+		disabled, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "disabled 参数错误"})
+			return
+		}
+		filter.Disabled = &disabled
+	}
+
+	users, total, err := service.ListUsers(currentUser, filter, adminClient(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users":     users,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// AdminGetUser 查看用户
+// @Summary      查看用户
+// @Description  获取用户详情及其拥有的全部权限
+// @Tags         管理
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "用户ID"
+// @Success      200  {object}  map[string]interface{}  "用户详情"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Failure      403  {object}  map[string]interface{}  "没有权限"
+// @Failure      404  {object}  map[string]interface{}  "用户不存在"
+// @Router       /api/admin/users/{id} [get]
+func AdminGetUser(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	target, err := service.GetUser(currentUser, c.Param("id"), adminClient(c))
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user":         target,
+		"has_password": target.HasPassword(),
+		"permissions":  service.UserPermissions(*target),
+	})
+}
+
+// AdminDisableUser 禁用用户
+// @Summary      禁用用户
+// @Description  被禁用的用户不能登录，已登录的设备立即退出，个人访问令牌在重新启用前不可用
+// @Tags         管理
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path  string            true   "用户ID"
+// @Param        request  body  DisableUserInput  false  "禁用原因"
+// @Success      200  {object}  map[string]interface{}  "已禁用"
+// @Failure      400  {object}  map[string]interface{}  "不能禁用自己"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Failure      403  {object}  map[string]interface{}  "没有权限"
+// @Failure      404  {object}  map[string]interface{}  "用户不存在"
+// @Router       /api/admin/users/{id}/disable [post]
+func AdminDisableUser(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	var input DisableUserInput
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	target, err := service.DisableUser(currentUser, c.Param("id"), input.Reason, adminClient(c))
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "用户已禁用", "user": target})
+}
+
+// AdminEnableUser 启用用户
+// @Summary      启用用户
+// @Description  重新启用被禁用的用户
+// @Tags         管理
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "用户ID"
+// @Success      200  {object}  map[string]interface{}  "已启用"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Failure      403  {object}  map[string]interface{}  "没有权限"
+// @Failure      404  {object}  map[string]interface{}  "用户不存在"
+// @Router       /api/admin/users/{id}/enable [post]
+func AdminEnableUser(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	target, err := service.EnableUser(currentUser, c.Param("id"), adminClient(c))
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "用户已启用", "user": target})
+}
+
+// AdminResetPassword 重置用户密码
+// @Summary      重置用户密码
+// @Description  原密码立即失效，已登录的设备全部退出，并向用户邮箱发送设置新密码的链接
+// @Tags         管理
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "用户ID"
+// @Success      200  {object}  map[string]interface{}  "已重置"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Failure      403  {object}  map[string]interface{}  "没有权限"
+// @Failure      404  {object}  map[string]interface{}  "用户不存在"
+// @Router       /api/admin/users/{id}/reset-password [post]
+func AdminResetPassword(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	if err := service.ResetUserPassword(currentUser, c.Param("id"), adminClient(c)); err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "密码已重置，已向用户发送设置新密码的邮件"})
+}
+
+// AdminSetUserRole 修改用户角色
+// @Summary      修改用户角色
+// @Description  设置用户的角色和额外授予的权限，不能修改自己的角色
+// @Tags         管理
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path  string            true  "用户ID"
+// @Param        request  body  SetUserRoleInput  true  "角色和权限"
+// @Success      200  {object}  map[string]interface{}  "已修改"
+// @Failure      400  {object}  map[string]interface{}  "角色或权限不存在"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Failure      403  {object}  map[string]interface{}  "没有权限"
+// @Failure      404  {object}  map[string]interface{}  "用户不存在"
+// @Router       /api/admin/users/{id}/role [put]
+func AdminSetUserRole(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	var input SetUserRoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	target, err := service.SetUserRole(currentUser, c.Param("id"), input.Role, input.Permissions, adminClient(c))
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "角色已修改",
+		"user":        target,
+		"permissions": service.UserPermissions(*target),
+	})
+}
+
+// AdminGetStats 系统概况
+// @Summary      系统概况
+// @Description  用户、任务、会话、webhook 投递和数据导出的统计
+// @Tags         管理
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  service.SystemStats     "统计数据"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Failure      403  {object}  map[string]interface{}  "没有权限"
+// @Router       /api/admin/stats [get]
+func AdminGetStats(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	stats, err := service.GetSystemStats(currentUser, adminClient(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// AdminGetAuditLogs 查询审计记录
+// @Summary      查询审计记录
+// @Description  按动作和用户筛选审计记录，最新的在前
+// @Tags         管理
+// @Produce      json
+// @Security     BearerAuth
+// @Param        action     query  string  false  "动作，例如 admin.user_disabled"
+// @Param        user_id    query  int     false  "操作者用户ID"
+// @Param        page       query  int     false  "页码，从 1 开始"
+// @Param        page_size  query  int     false  "每页数量，最大 100"
+// @Success      200  {object}  map[string]interface{}  "审计记录"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Failure      403  {object}  map[string]interface{}  "没有权限"
+// @Router       /api/admin/audit-logs [get]
+func AdminGetAuditLogs(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	page, pageSize := parsePage(c)
+	filter := service.AuditFilter{Action: c.Query("action"), Page: page, PageSize: pageSize}
+	if v := c.Query("user_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user_id 参数错误"})
+			return
+		}
+		uid := uint(id)
+		filter.UserID = &uid
+	}
+
+	logs, total, err := service.GetAuditLogs(currentUser, filter, adminClient(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"audit_logs": logs,
+		"total":      total,
+		"page":       page,
+		"page_size":  pageSize,
+	})
+}
+
+// parsePage 读取分页参数，非法值使用默认值
+func parsePage(c *gin.Context) (page, pageSize int) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err = strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultPageSize)))
+	if err != nil || pageSize < 1 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	return page, pageSize
+}
+
+func adminClient(c *gin.Context) service.ClientInfo {
+	return service.ClientInfo{UserAgent: c.Request.UserAgent(), IP: c.ClientIP()}
+}
+
+// respondAdminError 把管理操作的错误转换为响应
+func respondAdminError(c *gin.Context, err error) {
+	switch err {
+	case service.ErrUserNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+	case service.ErrCannotModifySelf:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不能对自己执行该操作"})
+	case service.ErrUnknownRole:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "角色不存在"})
+	case service.ErrUnknownPermission:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "权限不存在"})
+	case service.ErrUpdatePasswordFail:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "重置密码失败"})
+	case service.ErrUpdateUserFail:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新用户失败"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+	}
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	models "myproject/internal/model"
+	"myproject/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UpdateDigestSettingInput struct {
+	Enabled  bool   `json:"enabled"`
+	SendTime string `json:"send_time"` // 用户时区下的 HH:MM，默认 08:00
+}
+
+// GetDigestSetting 获取每日摘要设置
+// @Summary      获取每日摘要设置
+// @Description  获取当前用户的每日摘要邮件设置
+// @Tags         每日摘要
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string]interface{}  "摘要设置"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Router       /api/digest/settings [get]
+func GetDigestSetting(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	setting, err := service.GetDigestSetting(currentUser)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"setting": setting})
+}
+
+// UpdateDigestSetting 更新每日摘要设置
+// @Summary      更新每日摘要设置
+// @Description  开启或关闭每日摘要邮件，并设置发送时间（用户时区）
+// @Tags         每日摘要
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body UpdateDigestSettingInput true "摘要设置"
+// @Success      200  {object}  map[string]interface{}  "更新成功"
+// @Failure      400  {object}  map[string]interface{}  "请求参数错误"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Router       /api/digest/settings [put]
+func UpdateDigestSetting(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	var input UpdateDigestSettingInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	setting, err := service.UpdateDigestSetting(currentUser, input.Enabled, input.SendTime)
+	if err != nil {
+		switch err {
+		case service.ErrInvalidSendTime:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "发送时间格式应为 HH:MM"})
+		case service.ErrSaveDigestFail:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新失败"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "更新成功",
+		"setting": setting,
+	})
+}
+
+// PreviewDigest 预览今天的摘要
+// @Summary      预览每日摘要
+// @Description  渲染当前用户今天的摘要，format=html 时返回 HTML，否则返回纯文本
+// @Tags         每日摘要
+// @Produce      plain
+// @Produce      html
+// @Security     BearerAuth
+// @Param        format  query     string  false  "text 或 html，默认 text"
+// @Success      200     {string}  string  "摘要内容"
+// @Failure      401     {object}  map[string]interface{}  "未认证"
+// @Router       /api/digest/preview [get]
+func PreviewDigest(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	text, html, err := service.PreviewDigest(currentUser)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		return
+	}
+
+	if c.Query("format") == "html" {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(html))
+		return
+	}
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(text))
+}
+
+// GetDigestHistory 获取摘要发送记录
+// @Summary      获取每日摘要发送记录
+// @Description  获取最近的每日摘要发送记录
+// @Tags         每日摘要
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string]interface{}  "发送记录"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Router       /api/digest/history [get]
+func GetDigestHistory(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	logs, err := service.GetDigestHistory(currentUser)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"history": logs})
+}
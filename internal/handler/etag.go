@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	models "myproject/internal/model"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// taskETag 以任务版本号作为强 ETag
+func taskETag(task *models.Task) string {
+	return `"` + strconv.FormatUint(task.Revision, 10) + `"`
+}
+
+// tasksETag 任务列表的弱 ETag，由各任务的 ID 和版本号计算
+func tasksETag(tasks []models.Task) string {
+	h := sha256.New()
+	for _, task := range tasks {
+		fmt.Fprintf(h, "%d:%d;", task.ID, task.Revision)
+	}
+	return `W/"` + hex.EncodeToString(h.Sum(nil))[:32] + `"`
+}
+
+// ifMatchRevisions 解析 If-Match 请求头。没有该请求头或为 * 时返回 nil，表示不做检查；
+// 否则返回其中的版本号，无法识别的 ETag（包括弱 ETag）不会与任何版本匹配
+func ifMatchRevisions(c *gin.Context) []uint64 {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return nil
+	}
+
+	revisions := []uint64{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		if revision, err := strconv.ParseUint(tag[1:len(tag)-1], 10, 64); err == nil {
+			revisions = append(revisions, revision)
+		}
+	}
+	return revisions
+}
+
+// notModified 设置 ETag，并在 If-None-Match 命中时返回 304。If-None-Match 使用弱比较
+func notModified(c *gin.Context, etag string) bool {
+	c.Header("ETag", etag)
+
+	header := c.GetHeader("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
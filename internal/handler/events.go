@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	models "myproject/internal/model"
+	"myproject/internal/realtime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+// 心跳间隔，避免代理因连接空闲而断开
+const streamHeartbeat = 25 * time.Second
+
+// StreamEvents 通过 SSE 推送任务事件
+// @Summary      订阅任务事件（SSE）
+// @Description  以 Server-Sent Events 推送当前用户的 task.created / task.updated / task.deleted / task.completed 事件。
+// @Description  浏览器 EventSource 无法设置请求头时可用 access_token 查询参数传 token；
+// @Description  断线重连时携带 Last-Event-ID 续传，无法续传时推送 reset 事件，客户端应重新拉取任务列表
+// @Tags         实时推送
+// @Produce      text/event-stream
+// @Security     BearerAuth
+// @Param        access_token   query   string  false  "登录 JWT，无法设置 Authorization 时使用；个人访问令牌不能用此参数"
+// @Param        Last-Event-ID  header  string  false  "最后收到的事件ID"
+// @Success      200  {string}  string  "事件流"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Router       /api/events [get]
+func StreamEvents(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	lastEventID := parseLastEventID(c)
+	client, replay, reset, err := realtime.DefaultHub.Connect(currentUser.ID, lastEventID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		return
+	}
+	defer realtime.DefaultHub.Disconnect(client)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	w := c.Writer
+	send := func(msg realtime.Message) error {
+		if msg.ID != 0 {
+			fmt.Fprintf(w, "id: %d\n", msg.ID)
+		}
+		data := msg.Data
+		if data == nil {
+			data = json.RawMessage("{}")
+		}
+		_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", msg.Type, data)
+		w.Flush()
+		return err
+	}
+	ping := func() error {
+		_, err := fmt.Fprint(w, ": ping\n\n")
+		w.Flush()
+		return err
+	}
+
+	fmt.Fprint(w, "retry: 3000\n\n")
+	w.Flush()
+	pumpEvents(c.Request.Context(), client, lastEventID, replay, reset, send, ping)
+}
+
+// TaskEventsWebSocket 通过 WebSocket 推送任务事件
+// @Summary      订阅任务事件（WebSocket）
+// @Description  与 /events 推送相同的事件，每条消息为 {"id","type","data"} 形式的 JSON；
+// @Description  用 last_event_id 查询参数续传，type 为 reset 时客户端应重新拉取任务列表
+// @Tags         实时推送
+// @Security     BearerAuth
+// @Param        access_token   query   string  false  "登录 JWT，无法设置 Authorization 时使用；个人访问令牌不能用此参数"
+// @Param        last_event_id  query   string  false  "最后收到的事件ID"
+// @Success      101  {string}  string  "切换到 WebSocket"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Router       /api/events/ws [get]
+func TaskEventsWebSocket(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	lastEventID := parseLastEventID(c)
+	client, replay, reset, err := realtime.DefaultHub.Connect(currentUser.ID, lastEventID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		return
+	}
+	defer realtime.DefaultHub.Disconnect(client)
+
+	server := websocket.Server{Handler: func(ws *websocket.Conn) {
+		ctx, cancel := context.WithCancel(c.Request.Context())
+		defer cancel()
+
+		// 客户端不需要发送消息，读循环只用来发现连接关闭
+		go func() {
+			defer cancel()
+			var discard string
+			for websocket.Message.Receive(ws, &discard) == nil {
+			}
+		}()
+
+		send := func(msg realtime.Message) error {
+			return websocket.JSON.Send(ws, msg)
+		}
+		ping := func() error {
+			return websocket.JSON.Send(ws, realtime.Message{Type: "ping"})
+		}
+		pumpEvents(ctx, client, lastEventID, replay, reset, send, ping)
+	}}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// pumpEvents 先补发断线期间的事件，再持续推送新事件，直到连接关闭或被 Hub 断开
+func pumpEvents(ctx context.Context, client *realtime.Client, lastEventID uint64, replay []realtime.Message, reset bool,
+	send func(realtime.Message) error, ping func() error) {
+	if reset {
+		if err := send(realtime.Message{Type: realtime.TypeReset}); err != nil {
+			return
+		}
+	}
+	for _, msg := range replay {
+		if err := send(msg); err != nil {
+			return
+		}
+		lastEventID = msg.ID
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-client.Messages():
+			if !ok {
+				return
+			}
+			// 跳过补发阶段已经发送过的事件
+			if msg.ID <= lastEventID {
+				continue
+			}
+			if err := send(msg); err != nil {
+				return
+			}
+			lastEventID = msg.ID
+		case <-heartbeat.C:
+			if err := ping(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// parseLastEventID 读取 Last-Event-ID 请求头或 last_event_id 查询参数
+func parseLastEventID(c *gin.Context) uint64 {
+	raw := c.GetHeader("Last-Event-ID")
+	if raw == "" {
+		raw = c.Query("last_event_id")
+	}
+	id, _ := strconv.ParseUint(raw, 10, 64)
+	return id
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"fmt"
+	models "myproject/internal/model"
+	"myproject/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestDataExport 申请导出个人数据
+// @Summary      导出个人数据
+// @Description  在后台生成包含个人资料、任务及全部相关记录的 ZIP 文件（JSON 和 HTML 摘要），通过状态接口查询进度和下载链接。已有生成中的任务时返回该任务
+// @Tags         用户
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string]interface{}  "已有生成中的任务"
+// @Success      202  {object}  map[string]interface{}  "已开始生成"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Router       /api/me/export [post]
+func RequestDataExport(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	export, created, err := service.RequestDataExport(currentUser)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建导出任务失败"})
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusAccepted
+	}
+	c.Header("Location", fmt.Sprintf("/me/export/%d", export.ID))
+	c.JSON(status, gin.H{"export": export})
+}
+
+// GetDataExport 查询个人数据导出状态
+// @Summary      查询数据导出
+// @Description  status 为 ready 时返回下载链接，链接与文件同时过期
+// @Tags         用户
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "导出任务ID"
+// @Success      200  {object}  map[string]interface{}  "导出任务"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Failure      404  {object}  map[string]interface{}  "导出任务不存在"
+// @Router       /api/me/export/{id} [get]
+func GetDataExport(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	export, err := service.GetDataExport(currentUser, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "导出任务不存在"})
+		return
+	}
+
+	resp := gin.H{"export": export}
+	url, err := service.DataExportDownloadURL(currentUser, export)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成下载链接失败"})
+		return
+	}
+	if url != "" {
+		resp["download_url"] = url
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// DownloadDataExport 下载个人数据导出文件
+// @Summary      下载数据导出
+// @Description  使用状态接口返回的下载链接下载 ZIP 文件，链接过期后需要重新申请导出
+// @Tags         用户
+// @Produce      application/zip
+// @Param        token  query  string  true  "下载令牌"
+// @Success      200  {file}    file                    "ZIP 文件"
+// @Failure      400  {object}  map[string]interface{}  "链接无效或已过期"
+// @Failure      404  {object}  map[string]interface{}  "文件不存在或已过期"
+// @Router       /exports/download [get]
+func DownloadDataExport(c *gin.Context) {
+	export, err := service.OpenDataExport(c.Query("token"))
+	if err != nil {
+		switch err {
+		case service.ErrInvalidActionToken:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "链接无效或已过期"})
+		case service.ErrExportNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "文件不存在或已过期"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		}
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.FileAttachment(export.FilePath, fmt.Sprintf("taskflow-export-%s.zip", export.CreatedAt.Format("20060102")))
+}
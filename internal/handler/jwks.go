@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"myproject/internal/oidc"
+	"myproject/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKS 公开令牌签名公钥
+// @Summary      令牌签名公钥
+// @Description  以 JWKS 格式返回验证本服务签发令牌所需的公钥，包括轮换重叠期内的旧密钥和即将生效的新密钥
+// @Tags         用户
+// @Produce      json
+// @Success      200  {object}  oidc.JWKS  "公钥集合"
+// @Router       /.well-known/jwks.json [get]
+func JWKS(c *gin.Context) {
+	set := oidc.JWKS{Keys: []oidc.JWK{}}
+	for _, key := range utils.PublishedKeys() {
+		jwk, err := oidc.NewJWK(key.ID, key.Algorithm, key.PublicKey())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+			return
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, set)
+}
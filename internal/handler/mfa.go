@@ -0,0 +1,207 @@
+package handler
+
+import (
+	models "myproject/internal/model"
+	"myproject/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MFALoginInput struct {
+	MFAToken   string `json:"mfa_token" binding:"required"`
+	Code       string `json:"code" binding:"required"` // 6 位验证码或恢复码
+	DeviceName string `json:"device_name" binding:"max=100"`
+}
+
+type MFACodeInput struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type DisableTOTPInput struct {
+	Password string `json:"password"` // 没有设置密码的用户可以不填
+	Code     string `json:"code" binding:"required"`
+}
+
+// LoginMFA 两步验证登录
+// @Summary      两步验证登录
+// @Description  使用登录返回的 mfa_token 和验证器中的 6 位验证码（或恢复码）完成登录。连续输错 5 次后 mfa_token 作废
+// @Tags         用户
+// @Accept       json
+// @Produce      json
+// @Param        request body MFALoginInput true "挑战令牌和验证码"
+// @Success      200  {object}  map[string]interface{}  "登录成功返回token"
+// @Failure      400  {object}  map[string]interface{}  "请求参数错误"
+// @Failure      401  {object}  map[string]interface{}  "验证码错误或挑战令牌失效"
+// @Failure      429  {object}  map[string]interface{}  "失败次数过多，Retry-After 秒后重试"
+// @Router       /login/mfa [post]
+func LoginMFA(c *gin.Context) {
+	var input MFALoginInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := service.CompleteMFALogin(input.MFAToken, input.Code, service.ClientInfo{
+		DeviceName: input.DeviceName,
+		UserAgent:  c.Request.UserAgent(),
+		IP:         c.ClientIP(),
+	})
+	if err != nil {
+		if respondLoginThrottled(c, err) {
+			return
+		}
+		switch err {
+		case service.ErrInvalidMFAToken:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "验证已过期，请重新登录"})
+		case service.ErrInvalidMFACode:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "验证码错误"})
+		case service.ErrAccountDisabled:
+			c.JSON(http.StatusForbidden, gin.H{"error": "账号已被禁用"})
+		case service.ErrGenerateTokenFailed:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "生成token失败"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		}
+		return
+	}
+
+	respondLogin(c, result)
+}
+
+// SetupTOTP 开始设置两步验证
+// @Summary      设置两步验证
+// @Description  生成 TOTP 密钥和 otpauth:// 链接，用验证器应用扫码后调用确认接口启用
+// @Tags         两步验证
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string]interface{}  "密钥和 otpauth 链接"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Failure      409  {object}  map[string]interface{}  "已开启两步验证"
+// @Router       /api/2fa/setup [post]
+func SetupTOTP(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	setup, err := service.SetupTOTP(currentUser)
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, setup)
+}
+
+// ConfirmTOTP 确认并启用两步验证
+// @Summary      启用两步验证
+// @Description  提交验证器应用中的第一个验证码，成功后启用两步验证并返回恢复码。恢复码只显示这一次
+// @Tags         两步验证
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body MFACodeInput true "验证码"
+// @Success      200  {object}  map[string]interface{}  "恢复码"
+// @Failure      400  {object}  map[string]interface{}  "验证码错误"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Failure      409  {object}  map[string]interface{}  "已开启两步验证"
+// @Router       /api/2fa/confirm [post]
+func ConfirmTOTP(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	var input MFACodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := service.ConfirmTOTP(currentUser, input.Code)
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "两步验证已开启，请妥善保存恢复码",
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTOTP 关闭两步验证
+// @Summary      关闭两步验证
+// @Description  需要验证码（或恢复码）；设置过密码的用户还需要当前密码
+// @Tags         两步验证
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body DisableTOTPInput true "密码和验证码"
+// @Success      200  {object}  map[string]interface{}  "已关闭"
+// @Failure      400  {object}  map[string]interface{}  "密码或验证码错误"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Router       /api/2fa/disable [post]
+func DisableTOTP(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	var input DisableTOTPInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := service.DisableTOTP(currentUser, input.Password, input.Code); err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "两步验证已关闭"})
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码
+// @Summary      重新生成恢复码
+// @Description  需要验证码（或恢复码），旧的恢复码全部作废
+// @Tags         两步验证
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body MFACodeInput true "验证码"
+// @Success      200  {object}  map[string]interface{}  "新的恢复码"
+// @Failure      400  {object}  map[string]interface{}  "验证码错误"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Router       /api/2fa/recovery-codes [post]
+func RegenerateRecoveryCodes(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	var input MFACodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := service.RegenerateRecoveryCodes(currentUser, input.Code)
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// respondMFAError 把两步验证相关的错误转换为响应
+func respondMFAError(c *gin.Context, err error) {
+	switch err {
+	case service.ErrMFAAlreadyEnabled:
+		c.JSON(http.StatusConflict, gin.H{"error": "已开启两步验证"})
+	case service.ErrMFANotEnabled:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "未开启两步验证"})
+	case service.ErrMFASetupRequired:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请先获取两步验证密钥"})
+	case service.ErrInvalidMFACode:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "验证码错误"})
+	case service.ErrInvalidCredentials:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "密码错误"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+	}
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	models "myproject/internal/model"
+	"myproject/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetNotifications 获取通知列表
+// @Summary      获取通知
+// @Description  获取当前用户的站内通知，默认不含已关闭的通知
+// @Tags         通知
+// @Produce      json
+// @Security     BearerAuth
+// @Param        all  query     bool  false  "包含已关闭的通知"
+// @Success      200  {object}  map[string]interface{}  "通知列表"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Router       /api/notifications [get]
+func GetNotifications(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	includeDismissed, _ := strconv.ParseBool(c.DefaultQuery("all", "false"))
+
+	notifications, err := service.GetNotifications(currentUser, includeDismissed)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"notifications": notifications})
+}
+
+// DismissNotification 关闭通知
+// @Summary      关闭通知
+// @Description  关闭指定通知
+// @Tags         通知
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "通知ID"
+// @Success      200  {object}  map[string]interface{}  "已关闭"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Failure      404  {object}  map[string]interface{}  "通知不存在"
+// @Router       /api/notifications/{id} [delete]
+func DismissNotification(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	if err := service.DismissNotification(currentUser, c.Param("id")); err != nil {
+		switch err {
+		case service.ErrNotificationNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "通知不存在"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已关闭"})
+}
+
+// DismissAllNotifications 关闭全部通知
+// @Summary      关闭全部通知
+// @Description  关闭当前用户的全部通知
+// @Tags         通知
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string]interface{}  "已关闭"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Router       /api/notifications [delete]
+func DismissAllNotifications(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	if err := service.DismissAllNotifications(currentUser); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已关闭"})
+}
@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"myproject/internal/service"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetOIDCProviders 获取可用的外部登录方式
+// @Summary      外部登录方式
+// @Description  获取已配置的 OpenID Connect 身份提供方
+// @Tags         用户
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "身份提供方列表"
+// @Router       /auth/providers [get]
+func GetOIDCProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": service.OIDCProviders()})
+}
+
+// oauthStateCookie 保存发起外部登录的浏览器的 state，回调时必须与查询参数中的 state 一致
+const oauthStateCookie = "oauth_state"
+
+// OIDCLogin 跳转到身份提供方登录
+// @Summary      外部登录
+// @Description  生成 state、nonce 和 PKCE 参数后重定向到身份提供方的授权页面，state 同时写入 HttpOnly cookie，回调时校验
+// @Tags         用户
+// @Param        provider  path  string  true  "身份提供方"
+// @Success      302  "重定向到身份提供方"
+// @Failure      404  {object}  map[string]interface{}  "身份提供方不存在"
+// @Failure      502  {object}  map[string]interface{}  "身份提供方不可用"
+// @Router       /auth/{provider}/login [get]
+func OIDCLogin(c *gin.Context) {
+	authURL, state, err := service.BeginOIDCLogin(c.Param("provider"))
+	if err != nil {
+		switch err {
+		case service.ErrUnknownProvider:
+			c.JSON(http.StatusNotFound, gin.H{"error": "身份提供方不存在"})
+		case service.ErrProviderUnavailable:
+			c.JSON(http.StatusBadGateway, gin.H{"error": "身份提供方暂时不可用"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		}
+		return
+	}
+
+	setOAuthStateCookie(c, state, int(service.OAuthStateTTL.Seconds()))
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback 外部登录回调
+// @Summary      外部登录回调
+// @Description  身份提供方授权后回调，校验 state（须与发起登录时写入的 cookie 一致）和 ID Token，然后重定向到前端 APP_URL/auth/callback。
+// @Description  成功时 fragment 中带一次性登录码 code，前端用它调用 /auth/exchange 换取令牌；失败时带 error。
+// @Description  未关联的账号按已验证的邮箱关联邮箱已验证的用户，或在配置允许时自动创建
+// @Tags         用户
+// @Param        provider  path   string  true   "身份提供方"
+// @Param        code      query  string  true   "授权码"
+// @Param        state     query  string  true   "登录时生成的 state"
+// @Success      302  "重定向到前端，fragment 为 code=<登录码> 或 error=<原因>"
+// @Router       /auth/{provider}/callback [get]
+func OIDCCallback(c *gin.Context) {
+	browserState, _ := c.Cookie(oauthStateCookie)
+	setOAuthStateCookie(c, "", -1)
+
+	result := url.Values{}
+	if errCode := c.Query("error"); errCode != "" {
+		result.Set("error", "access_denied")
+		c.Redirect(http.StatusFound, service.OIDCResultURL(result))
+		return
+	}
+	code, state := c.Query("code"), c.Query("state")
+	if code == "" || state == "" {
+		result.Set("error", "invalid_request")
+		c.Redirect(http.StatusFound, service.OIDCResultURL(result))
+		return
+	}
+
+	loginCode, err := service.CompleteOIDCLogin(c.Param("provider"), code, state, browserState, service.ClientInfo{
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	})
+	if err != nil {
+		switch err {
+		case service.ErrUnknownProvider:
+			result.Set("error", "unknown_provider")
+		case service.ErrInvalidOAuthState:
+			result.Set("error", "invalid_state")
+		case service.ErrProviderEmailUnverified:
+			result.Set("error", "email_not_verified")
+		case service.ErrOIDCEmailUnverifiedAccount:
+			result.Set("error", "email_taken_unverified")
+		case service.ErrOIDCUserNotFound:
+			result.Set("error", "no_user")
+		case service.ErrAccountDisabled:
+			result.Set("error", "account_disabled")
+		default:
+			result.Set("error", "login_failed")
+		}
+		c.Redirect(http.StatusFound, service.OIDCResultURL(result))
+		return
+	}
+
+	result.Set("code", loginCode)
+	c.Redirect(http.StatusFound, service.OIDCResultURL(result))
+}
+
+type OIDCExchangeInput struct {
+	Code       string `json:"code" binding:"required"`
+	DeviceName string `json:"device_name" binding:"max=100"`
+}
+
+// OIDCExchange 用外部登录的一次性登录码换取令牌
+// @Summary      外部登录换取令牌
+// @Description  使用外部登录回调跳转到前端时带的一次性登录码完成登录，返回与普通登录相同的结果。登录码 1 分钟内有效，只能使用一次
+// @Tags         用户
+// @Accept       json
+// @Produce      json
+// @Param        request body OIDCExchangeInput true "登录码"
+// @Success      200  {object}  map[string]interface{}  "登录成功返回token，或需要两步验证"
+// @Failure      400  {object}  map[string]interface{}  "请求参数错误"
+// @Failure      401  {object}  map[string]interface{}  "登录码无效或已过期"
+// @Failure      403  {object}  map[string]interface{}  "账号已被禁用"
+// @Router       /auth/exchange [post]
+func OIDCExchange(c *gin.Context) {
+	var input OIDCExchangeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := service.ExchangeOIDCLoginCode(input.Code, service.ClientInfo{
+		DeviceName: input.DeviceName,
+		UserAgent:  c.Request.UserAgent(),
+		IP:         c.ClientIP(),
+	})
+	if err != nil {
+		switch err {
+		case service.ErrInvalidActionToken:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "登录已过期，请重新发起"})
+		case service.ErrAccountDisabled:
+			c.JSON(http.StatusForbidden, gin.H{"error": "账号已被禁用"})
+		case service.ErrGenerateTokenFailed:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "生成token失败"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		}
+		return
+	}
+
+	respondLoginResult(c, result)
+}
+
+// setOAuthStateCookie 写入或清除（maxAge 为负）state cookie。回调是从身份提供方跳转回来的顶层 GET 请求，
+// SameSite=Lax 下 cookie 会随请求发送
+func setOAuthStateCookie(c *gin.Context, state string, maxAge int) {
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oauthStateCookie, state, maxAge, "/auth/", "", secure, true)
+}
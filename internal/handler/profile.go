@@ -0,0 +1,206 @@
+package handler
+
+import (
+	models "myproject/internal/model"
+	"myproject/internal/service"
+	"myproject/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UpdateProfileInput struct {
+	Username        *string `json:"username" binding:"omitempty,min=3"`
+	Email           *string `json:"email" binding:"omitempty,email"`
+	TimeZone        *string `json:"time_zone" binding:"omitempty,max=64"`
+	CurrentPassword string  `json:"current_password"` // 修改邮箱时需要
+}
+
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"current_password"` // 没有设置过密码时可以为空
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+type DeleteAccountInput struct {
+	Password string `json:"password"`
+	Confirm  string `json:"confirm"` // 没有设置密码的用户填写用户名确认
+}
+
+// GetProfile 获取个人资料
+// @Summary      获取个人资料
+// @Description  获取当前用户的资料和权限，pending_email 为等待确认的新邮箱
+// @Tags         用户
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string]interface{}  "个人资料"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Router       /api/me [get]
+func GetProfile(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	c.JSON(http.StatusOK, gin.H{
+		"user":         currentUser,
+		"has_password": currentUser.HasPassword(),
+		"permissions":  service.UserPermissions(currentUser),
+	})
+}
+
+// UpdateProfile 修改个人资料
+// @Summary      修改个人资料
+// @Description  修改用户名、时区或邮箱。修改邮箱需要当前密码，新邮箱收到的链接确认后才生效
+// @Tags         用户
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body UpdateProfileInput true "要修改的字段"
+// @Success      200  {object}  map[string]interface{}  "修改后的资料"
+// @Failure      400  {object}  map[string]interface{}  "请求参数错误或密码错误"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Failure      409  {object}  map[string]interface{}  "用户名或邮箱已被使用"
+// @Router       /api/me [patch]
+func (h *UserHandler) UpdateProfile(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	var input UpdateProfileInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := h.users.UpdateProfile(currentUser, service.ProfileUpdate{
+		Username:        input.Username,
+		Email:           input.Email,
+		TimeZone:        input.TimeZone,
+		CurrentPassword: input.CurrentPassword,
+	})
+	if err != nil {
+		respondProfileError(c, err)
+		return
+	}
+
+	message := "资料已更新"
+	if input.Email != nil && updated.PendingEmail != "" {
+		message = "资料已更新，请打开发送到新邮箱的链接完成修改"
+	}
+	c.JSON(http.StatusOK, gin.H{"message": message, "user": updated})
+}
+
+// ConfirmEmailChange 确认修改邮箱
+// @Summary      确认修改邮箱
+// @Description  使用发送到新邮箱的令牌确认修改，令牌只能使用一次
+// @Tags         用户
+// @Accept       json
+// @Produce      json
+// @Param        request body VerifyEmailInput true "确认令牌"
+// @Success      200  {object}  map[string]interface{}  "邮箱已修改"
+// @Failure      400  {object}  map[string]interface{}  "令牌无效或已过期"
+// @Failure      409  {object}  map[string]interface{}  "邮箱已被使用"
+// @Router       /email/change/confirm [post]
+func (h *UserHandler) ConfirmEmailChange(c *gin.Context) {
+	var input VerifyEmailInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.users.ConfirmEmailChange(input.Token)
+	if err != nil {
+		respondProfileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "邮箱已修改", "email": user.Email})
+}
+
+// ChangePassword 修改密码
+// @Summary      修改密码
+// @Description  需要当前密码；修改后其它设备全部退出登录，当前设备保持登录
+// @Tags         用户
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body ChangePasswordInput true "当前密码和新密码"
+// @Success      200  {object}  map[string]interface{}  "密码已修改"
+// @Failure      400  {object}  map[string]interface{}  "请求参数错误或密码错误"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Router       /api/me/password [post]
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+	claims, _ := c.Get("claims")
+
+	var input ChangePasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.users.ChangePassword(currentUser, input.CurrentPassword, input.NewPassword, claims.(*utils.Claims).SessionID, service.ClientInfo{
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	})
+	if err != nil {
+		respondProfileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "密码已修改，其它设备已退出登录"})
+}
+
+// DeleteAccount 注销账号
+// @Summary      注销账号
+// @Description  删除当前用户及其任务、模板、通知、webhook 等全部数据，所有令牌立即失效，操作不可恢复。需要密码，没有设置密码的用户填写用户名确认
+// @Tags         用户
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body DeleteAccountInput true "确认信息"
+// @Success      200  {object}  map[string]interface{}  "账号已注销"
+// @Failure      400  {object}  map[string]interface{}  "密码错误或确认信息不符"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Router       /api/me [delete]
+func DeleteAccount(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	var input DeleteAccountInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := service.DeleteAccount(currentUser, input.Password, input.Confirm); err != nil {
+		respondProfileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "账号已注销"})
+}
+
+// respondProfileError 把个人资料相关的错误转换为响应
+func respondProfileError(c *gin.Context, err error) {
+	switch err {
+	case service.ErrInvalidCredentials:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "密码错误"})
+	case service.ErrUsernameTaken:
+		c.JSON(http.StatusConflict, gin.H{"error": "用户名已被使用"})
+	case service.ErrEmailTaken:
+		c.JSON(http.StatusConflict, gin.H{"error": "邮箱已被使用"})
+	case service.ErrInvalidTimeZone:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的时区"})
+	case service.ErrDeleteConfirmation:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请输入用户名确认注销"})
+	case service.ErrInvalidActionToken:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "链接无效或已过期"})
+	case service.ErrHashPasswordFailed:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "密码加密失败"})
+	case service.ErrUpdatePasswordFail:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "修改密码失败"})
+	case service.ErrDeleteUserFail:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "注销账号失败"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+	}
+}
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	models "myproject/internal/model"
+	"myproject/internal/service"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreateReminderInput struct {
+	RemindAt      *time.Time `json:"remind_at"`      // 绝对提醒时间
+	OffsetMinutes *int       `json:"offset_minutes"` // 截止时间前多少分钟提醒
+	Channel       string     `json:"channel" binding:"omitempty,oneof=in_app email"`
+}
+
+// CreateReminder 创建提醒
+// @Summary      创建任务提醒
+// @Description  为任务创建提醒，remind_at 与 offset_minutes 二选一
+// @Tags         提醒
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id      path    string               true  "任务ID"
+// @Param        request body    CreateReminderInput  true  "提醒信息"
+// @Success      200     {object} map[string]interface{} "创建成功"
+// @Failure      400     {object} map[string]interface{} "请求参数错误"
+// @Failure      401     {object} map[string]interface{} "未认证"
+// @Failure      404     {object} map[string]interface{} "任务不存在"
+// @Router       /api/tasks/{id}/reminders [post]
+func CreateReminder(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	var input CreateReminderInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reminder, err := service.CreateReminder(currentUser, c.Param("id"), input.RemindAt, input.OffsetMinutes, input.Channel)
+	if err != nil {
+		switch err {
+		case service.ErrTaskNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		case service.ErrInvalidReminder:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "remind_at 和 offset_minutes 必须且只能指定一个"})
+		case service.ErrReminderNeedsDueDate:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "任务没有截止时间，无法按偏移提醒"})
+		case service.ErrCreateReminderFail:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "创建失败"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "创建成功",
+		"reminder": reminder,
+	})
+}
+
+// GetReminders 获取提醒列表
+// @Summary      获取任务提醒
+// @Description  获取任务的全部提醒
+// @Tags         提醒
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "任务ID"
+// @Success      200  {object}  map[string]interface{}  "提醒列表"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Failure      404  {object}  map[string]interface{}  "任务不存在"
+// @Router       /api/tasks/{id}/reminders [get]
+func GetReminders(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	reminders, err := service.GetReminders(currentUser, c.Param("id"))
+	if err != nil {
+		switch err {
+		case service.ErrTaskNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reminders": reminders})
+}
+
+// DeleteReminder 删除提醒
+// @Summary      删除任务提醒
+// @Description  删除任务的指定提醒
+// @Tags         提醒
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id           path      string  true  "任务ID"
+// @Param        reminder_id  path      string  true  "提醒ID"
+// @Success      200  {object}  map[string]interface{}  "删除成功"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Failure      404  {object}  map[string]interface{}  "提醒不存在"
+// @Router       /api/tasks/{id}/reminders/{reminder_id} [delete]
+func DeleteReminder(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	if err := service.DeleteReminder(currentUser, c.Param("id"), c.Param("reminder_id")); err != nil {
+		switch err {
+		case service.ErrReminderNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "提醒不存在"})
+		case service.ErrDeleteReminderFail:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "删除失败"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	models "myproject/internal/model"
+	"myproject/internal/service"
+	"myproject/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSessions 获取登录会话列表
+// @Summary      获取登录会话
+// @Description  获取当前用户已登录的设备，current 标记发起请求的会话
+// @Tags         会话
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string]interface{}  "会话列表"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Router       /api/sessions [get]
+func GetSessions(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+	claims, _ := c.Get("claims")
+
+	sessions, err := service.GetSessions(currentUser, claims.(*utils.Claims).SessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+// RevokeSession 结束登录会话
+// @Summary      结束登录会话
+// @Description  吊销指定会话，该设备上的访问令牌和刷新令牌立即失效
+// @Tags         会话
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "会话ID"
+// @Success      200  {object}  map[string]interface{}  "已结束会话"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Failure      404  {object}  map[string]interface{}  "会话不存在"
+// @Router       /api/sessions/{id} [delete]
+func RevokeSession(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	if err := service.RevokeSession(currentUser, c.Param("id")); err != nil {
+		switch err {
+		case service.ErrSessionNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "会话不存在"})
+		case service.ErrRevokeSessionFail:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "结束会话失败"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已结束会话"})
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	models "myproject/internal/model"
+	"myproject/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SyncInput struct {
+	SyncToken string               `json:"sync_token"` // 上次同步返回的令牌，首次同步为空
+	Changes   []service.SyncChange `json:"changes"`    // 客户端离线期间的修改
+}
+
+// Sync 离线同步
+// @Summary      离线增量同步
+// @Description  提交客户端离线期间的修改，返回上次同步之后服务端的全部变更（删除的任务以墓碑表示）。
+// @Description  每个字段按最后写入者胜出合并：服务端在 base_revision 之后改过的字段视为冲突，modified_at 较新的一方保留。
+// @Description  has_more 为 true 时应带返回的 sync_token 继续同步
+// @Tags         同步
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body SyncInput true "同步令牌和本地修改"
+// @Success      200  {object}  service.SyncResponse  "同步结果"
+// @Failure      400  {object}  map[string]interface{}  "请求参数错误"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Router       /api/sync [post]
+func Sync(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	var input SyncInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := service.Sync(currentUser, input.SyncToken, input.Changes)
+	if err != nil {
+		switch err {
+		case service.ErrInvalidSyncToken:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的同步令牌"})
+		case service.ErrTooManySyncChanges:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "单次同步的修改过多"})
+		case service.ErrSyncFail:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "同步失败"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"errors"
+	"io"
 	models "myproject/internal/model"
 	"myproject/internal/service"
 	"net/http"
@@ -14,13 +16,31 @@ type CreateTaskInput struct {
     Description string `json:"description"`
 }
 
+// UpdateTaskInput PUT 时的完整任务，字段与 service.TaskDocument 一致
 type UpdateTaskInput struct {
     // Title       string `json:"title"`
-    Description string `json:"description"`
-    Status      string `json:"status" binding:"omitempty,oneof=pending done"`
+    Description string     `json:"description"`
+    Status      string     `json:"status" binding:"omitempty,oneof=pending done"`
+    Priority    string     `json:"priority" binding:"omitempty,oneof=high medium low"`
+    DueDate     *time.Time `json:"due_date"`
+    Recurrence  string     `json:"recurrence"`
+    Tags        []string   `json:"tags"`
+}
+
+// 修补文档的最大字节数
+const maxPatchSize = 64 << 10
+
+// TaskHandler 任务接口
+type TaskHandler struct {
+	tasks *service.TaskService
+}
+
+// NewTaskHandler 创建任务接口
+func NewTaskHandler(tasks *service.TaskService) *TaskHandler {
+	return &TaskHandler{tasks: tasks}
 }
 
-// CreateTask 创建任务
+// Create 创建任务
 // @Summary      创建新任务
 // @Description  为当前用户创建新任务
 // @Tags         任务
@@ -32,7 +52,7 @@ type UpdateTaskInput struct {
 // @Failure      400  {object}  map[string]interface{}  "请求参数错误"
 // @Failure      401  {object}  map[string]interface{}  "未认证"
 // @Router       /api/tasks [post]
-func CreateTask(c *gin.Context) {
+func (h *TaskHandler) Create(c *gin.Context) {
 	user, _ := c.Get("user")
 	currentUser := user.(models.User)
 
@@ -42,7 +62,7 @@ func CreateTask(c *gin.Context) {
 		return
 	}
 
-	task, err := service.CreateTask(currentUser, input.Description)
+	task, err := h.tasks.Create(currentUser, input.Description)
 	if err != nil {
 		switch err {
 		case service.ErrCreateTaskFail:
@@ -53,22 +73,27 @@ func CreateTask(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", taskETag(task))
 	c.JSON(http.StatusOK, gin.H{
 		"message": "创建成功",
 		"task":    task,
 	})
 }
 
-// GetTasks 获取任务列表
+// List 获取任务列表
 // @Summary      获取所有任务
-// @Description  获取当前用户的所有任务
+// @Description  获取当前用户的所有任务，响应带 ETag；If-None-Match 命中时返回 304
 // @Tags         任务
 // @Produce      json
 // @Security     BearerAuth
+// @Param        date           query   string  false  "创建日期 YYYY-MM-DD，默认今天"
+// @Param        If-None-Match  header  string  false  "上次获取的 ETag"
 // @Success      200  {object}  map[string]interface{}  "任务列表"
+// @Success      304  {string}  string  "未修改"
+// @Failure      400  {object}  map[string]interface{}  "日期格式错误"
 // @Failure      401  {object}  map[string]interface{}  "未认证"
 // @Router       /api/tasks [get]
-func GetTasks(c *gin.Context) {
+func (h *TaskHandler) List(c *gin.Context) {
 	user, _ := c.Get("user")
 	currentUser := user.(models.User)
 
@@ -77,9 +102,11 @@ func GetTasks(c *gin.Context) {
 		date = time.Now().Format("2006-01-02")
 	}
 
-	tasks, err := service.GetTasks(currentUser, date)
+	tasks, err := h.tasks.List(currentUser, date)
 	if err != nil {
 		switch err {
+		case service.ErrInvalidDate:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "日期格式错误，应为 YYYY-MM-DD"})
 		case service.ErrQueryTaskFail:
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
 		default:
@@ -88,29 +115,35 @@ func GetTasks(c *gin.Context) {
 		return
 	}
 
+	if notModified(c, tasksETag(tasks)) {
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"tasks": tasks,
 	})
 }
 
-// GetTask 获取单个任务
+// Get 获取单个任务
 // @Summary      获取任务详情
-// @Description  根据ID获取任务详情
+// @Description  根据ID获取任务详情，响应带 ETag；If-None-Match 命中时返回 304
 // @Tags         任务
 // @Produce      json
 // @Security     BearerAuth
-// @Param        id   path      string  true  "任务ID"
+// @Param        id             path    string  true   "任务ID"
+// @Param        If-None-Match  header  string  false  "上次获取的 ETag"
 // @Success      200  {object}  map[string]interface{}  "任务详情"
+// @Success      304  {string}  string  "未修改"
 // @Failure      401  {object}  map[string]interface{}  "未认证"
 // @Failure      404  {object}  map[string]interface{}  "任务不存在"
 // @Router       /api/tasks/{id} [get]
-func GetTask(c *gin.Context) {
+func (h *TaskHandler) Get(c *gin.Context) {
 	user, _ := c.Get("user")
 	currentUser := user.(models.User)
 
 	id := c.Param("id")
 
-	task, err := service.GetTask(currentUser, id)
+	task, err := h.tasks.Get(currentUser, id)
 	if err != nil {
 		switch err {
 		case service.ErrTaskNotFound:
@@ -121,24 +154,32 @@ func GetTask(c *gin.Context) {
 		return
 	}
 
+	if notModified(c, taskETag(task)) {
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{"task": task})
 }
 
-// UpdateTask 更新任务
-// @Summary      更新任务
-// @Description  更新任务信息
+// Update 替换任务
+// @Summary      替换任务
+// @Description  以请求体整体替换任务的可编辑字段，未给出的字段会被清空；只修改部分字段请使用 PATCH。
+// @Description  带 If-Match 时只有任务仍是该版本才更新
 // @Tags         任务
 // @Accept       json
 // @Produce      json
 // @Security     BearerAuth
 // @Param        id      path    string           true  "任务ID"
-// @Param        request body UpdateTaskInput true "更新信息"
+// @Param        If-Match header string           false "任务的 ETag"
+// @Param        request body UpdateTaskInput true "任务的全部可编辑字段"
 // @Success      200     {object} map[string]interface{} "更新成功"
 // @Failure      400     {object} map[string]interface{} "请求参数错误"
 // @Failure      401     {object} map[string]interface{} "未认证"
 // @Failure      404     {object} map[string]interface{} "任务不存在"
+// @Failure      412     {object} map[string]interface{} "任务已被修改"
+// @Failure      422     {object} map[string]interface{} "任务字段不合法"
 // @Router       /api/tasks/{id} [put]
-func UpdateTask(c *gin.Context) {
+func (h *TaskHandler) Update(c *gin.Context) {
 	user, _ := c.Get("user")
 	currentUser := user.(models.User)
 
@@ -150,54 +191,124 @@ func UpdateTask(c *gin.Context) {
 		return
 	}
 
-	updates := make(map[string]interface{})
-	if input.Description != "" {
-		updates["description"] = input.Description
+	task, err := h.tasks.Replace(currentUser, id, service.TaskDocument(input), ifMatchRevisions(c))
+	if err != nil {
+		respondTaskWriteError(c, err)
+		return
+	}
+
+	c.Header("ETag", taskETag(task))
+	c.JSON(http.StatusOK, gin.H{
+		"message": "更新成功",
+		"task":    task,
+	})
+}
+
+// Patch 修补任务
+// @Summary      修补任务
+// @Description  Content-Type 为 application/merge-patch+json（或 application/json）时按 RFC 7396 合并，null 清空字段；
+// @Description  为 application/json-patch+json 时按 RFC 6902 执行操作。只能修改 description、status、priority、due_date、recurrence、tags，
+// @Description  修补结果按与创建任务相同的规则校验。带 If-Match 时只有任务仍是该版本才更新
+// @Tags         任务
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path    string  true   "任务ID"
+// @Param        If-Match header  string  false  "任务的 ETag"
+// @Param        request  body    object  true   "Merge Patch 文档或 JSON Patch 操作数组"
+// @Success      200     {object} map[string]interface{} "更新成功"
+// @Failure      400     {object} map[string]interface{} "修补文档格式错误"
+// @Failure      401     {object} map[string]interface{} "未认证"
+// @Failure      404     {object} map[string]interface{} "任务不存在"
+// @Failure      409     {object} map[string]interface{} "test 操作未通过"
+// @Failure      412     {object} map[string]interface{} "任务已被修改"
+// @Failure      415     {object} map[string]interface{} "不支持的 Content-Type"
+// @Failure      422     {object} map[string]interface{} "修补结果不合法"
+// @Router       /api/tasks/{id} [patch]
+func (h *TaskHandler) Patch(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	var patchType string
+	switch c.ContentType() {
+	case "application/merge-patch+json", "application/json":
+		patchType = service.PatchMerge
+	case "application/json-patch+json":
+		patchType = service.PatchJSON
+	default:
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type 必须是 application/merge-patch+json 或 application/json-patch+json"})
+		return
 	}
-	if input.Status != "" {
-		updates["status"] = input.Status
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxPatchSize+1))
+	if err != nil || len(body) > maxPatchSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "修补文档过大"})
+		return
 	}
 
-	task, err := service.UpdateTask(currentUser, id, updates)
+	task, err := h.tasks.Patch(currentUser, c.Param("id"), patchType, body, ifMatchRevisions(c))
 	if err != nil {
-		switch err {
-		case service.ErrTaskNotFound:
-			c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
-		case service.ErrUpdateTaskFail:
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新失败"})
-		default:
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
-		}
+		respondTaskWriteError(c, err)
 		return
 	}
 
+	c.Header("ETag", taskETag(task))
 	c.JSON(http.StatusOK, gin.H{
 		"message": "更新成功",
 		"task":    task,
 	})
 }
 
-// DeleteTask 删除任务
+// respondTaskWriteError 把替换、修补任务时的错误转换为响应
+func respondTaskWriteError(c *gin.Context, err error) {
+	var validationErr *service.TaskValidationError
+	switch {
+	case errors.Is(err, service.ErrTaskNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+	case errors.Is(err, service.ErrPreconditionFailed):
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "任务已被修改，请刷新后重试"})
+	case errors.Is(err, service.ErrPatchTestFailed):
+		c.JSON(http.StatusConflict, gin.H{"error": "test 操作未通过"})
+	case errors.Is(err, service.ErrFieldNotPatchable):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "不允许修改的字段", "detail": err.Error()})
+	case errors.As(err, &validationErr):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "任务字段不合法", "field": validationErr.Field, "detail": validationErr.Reason})
+	case errors.Is(err, service.ErrInvalidTask):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "任务字段不合法", "detail": err.Error()})
+	case errors.Is(err, service.ErrInvalidPatch):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "修补文档格式错误", "detail": err.Error()})
+	case errors.Is(err, service.ErrUpdateTaskFail):
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新失败"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+	}
+}
+
+// Delete 删除任务
 // @Summary      删除任务
-// @Description  删除指定任务
+// @Description  删除指定任务；带 If-Match 时只有任务仍是该版本才删除
 // @Tags         任务
 // @Produce      json
 // @Security     BearerAuth
-// @Param        id   path      string  true  "任务ID"
+// @Param        id        path    string  true   "任务ID"
+// @Param        If-Match  header  string  false  "任务的 ETag"
 // @Success      200  {object}  map[string]interface{}  "删除成功"
 // @Failure      401  {object}  map[string]interface{}  "未认证"
 // @Failure      404  {object}  map[string]interface{}  "任务不存在"
+// @Failure      412  {object}  map[string]interface{}  "任务已被修改"
 // @Router       /api/tasks/{id} [delete]
-func DeleteTask(c *gin.Context) {
+func (h *TaskHandler) Delete(c *gin.Context) {
 	user, _ := c.Get("user")
 	currentUser := user.(models.User)
 
 	id := c.Param("id")
 
-	if err := service.DeleteTask(currentUser, id); err != nil {
+	if err := h.tasks.Delete(currentUser, id, ifMatchRevisions(c)); err != nil {
 		switch err {
 		case service.ErrTaskNotFound:
 			c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		case service.ErrPreconditionFailed:
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": "任务已被修改，请刷新后重试"})
 		case service.ErrDeleteTaskFail:
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "删除失败"})
 		default:
@@ -209,4 +320,49 @@ func DeleteTask(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"message": "删除成功",
 	})
-}
\ No newline at end of file
+}
+type QuickAddTaskInput struct {
+	Text string `json:"text" binding:"required"`
+}
+
+// QuickAdd 快速创建任务
+// @Summary      自然语言快速创建任务
+// @Description  解析一句话（如 "Send report tomorrow 5pm #work !high every friday" 或 "明天下午5点开会 #工作"）为标题、截止时间、标签、优先级和重复规则并创建任务
+// @Tags         任务
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body QuickAddTaskInput true "任务描述"
+// @Success      200  {object}  map[string]interface{}  "创建成功，返回任务和解析结果"
+// @Failure      400  {object}  map[string]interface{}  "请求参数错误"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Router       /api/tasks/quick [post]
+func (h *TaskHandler) QuickAdd(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	var input QuickAddTaskInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	task, parsed, err := h.tasks.QuickAdd(currentUser, input.Text)
+	if err != nil {
+		switch err {
+		case service.ErrEmptyTaskTitle:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "任务标题不能为空", "parsed": parsed})
+		case service.ErrCreateTaskFail:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "创建失败"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "创建成功",
+		"task":    task,
+		"parsed":  parsed,
+	})
+}
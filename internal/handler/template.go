@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"errors"
+	models "myproject/internal/model"
+	"myproject/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreateTemplateInput struct {
+	TaskID      uint                  `json:"task_id"` // 以已有任务及其子任务为蓝本
+	Name        string                `json:"name" binding:"max=128"`
+	Description string                `json:"description"`
+	Items       []models.TemplateItem `json:"items"` // 不指定 task_id 时直接给出任务树
+}
+
+type InstantiateTemplateInput struct {
+	Anchor    string            `json:"anchor"` // 锚定日期，RFC3339 或 YYYY-MM-DD，默认现在
+	Variables map[string]string `json:"variables"`
+}
+
+// CreateTemplate 创建模板
+// @Summary      创建任务模板
+// @Description  以已有任务（含子任务）为蓝本创建模板，或直接提交任务树
+// @Tags         模板
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body CreateTemplateInput true "模板信息"
+// @Success      200  {object}  map[string]interface{}  "创建成功"
+// @Failure      400  {object}  map[string]interface{}  "请求参数错误"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Failure      404  {object}  map[string]interface{}  "任务不存在"
+// @Failure      422  {object}  map[string]interface{}  "模板任务不合法"
+// @Router       /api/templates [post]
+func CreateTemplate(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	var input CreateTemplateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var template *models.Template
+	var err error
+	if input.TaskID != 0 {
+		taskID := strconv.FormatUint(uint64(input.TaskID), 10)
+		template, err = service.CreateTemplateFromTask(currentUser, taskID, input.Name, input.Description)
+	} else {
+		if input.Name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "模板名称不能为空"})
+			return
+		}
+		template, err = service.CreateTemplate(currentUser, input.Name, input.Description, input.Items)
+	}
+	if err != nil {
+		var validationErr *service.TaskValidationError
+		switch {
+		case errors.Is(err, service.ErrTaskNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		case errors.Is(err, service.ErrEmptyTemplate):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "模板不能为空"})
+		case errors.As(err, &validationErr):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "模板任务不合法", "field": validationErr.Field, "detail": validationErr.Reason})
+		case errors.Is(err, service.ErrCreateTemplateFail):
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "创建失败"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "创建成功",
+		"template": template,
+	})
+}
+
+// GetTemplates 获取模板列表
+// @Summary      获取所有模板
+// @Description  获取当前用户的所有任务模板
+// @Tags         模板
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string]interface{}  "模板列表"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Router       /api/templates [get]
+func GetTemplates(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	templates, err := service.GetTemplates(currentUser)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"templates": templates})
+}
+
+// GetTemplate 获取单个模板
+// @Summary      获取模板详情
+// @Description  根据ID获取任务模板
+// @Tags         模板
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "模板ID"
+// @Success      200  {object}  map[string]interface{}  "模板详情"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Failure      404  {object}  map[string]interface{}  "模板不存在"
+// @Router       /api/templates/{id} [get]
+func GetTemplate(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	template, err := service.GetTemplate(currentUser, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "模板不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"template": template})
+}
+
+// DeleteTemplate 删除模板
+// @Summary      删除模板
+// @Description  删除指定任务模板
+// @Tags         模板
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "模板ID"
+// @Success      200  {object}  map[string]interface{}  "删除成功"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Failure      404  {object}  map[string]interface{}  "模板不存在"
+// @Router       /api/templates/{id} [delete]
+func DeleteTemplate(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	if err := service.DeleteTemplate(currentUser, c.Param("id")); err != nil {
+		switch err {
+		case service.ErrTemplateNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "模板不存在"})
+		case service.ErrDeleteTemplateFail:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "删除失败"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+}
+
+// InstantiateTemplate 按模板创建任务
+// @Summary      实例化模板
+// @Description  以锚定日期和变量（替换 {{name}} 等占位符）按模板在一个事务中创建任务树
+// @Tags         模板
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id      path    string                    true  "模板ID"
+// @Param        request body    InstantiateTemplateInput  true  "锚定日期和变量"
+// @Success      200     {object} map[string]interface{} "创建成功"
+// @Failure      400     {object} map[string]interface{} "请求参数错误"
+// @Failure      401     {object} map[string]interface{} "未认证"
+// @Failure      404     {object} map[string]interface{} "模板不存在"
+// @Failure      422     {object} map[string]interface{} "替换变量后的任务不合法"
+// @Router       /api/templates/{id}/instantiate [post]
+func InstantiateTemplate(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	var input InstantiateTemplateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tasks, err := service.InstantiateTemplate(currentUser, c.Param("id"), input.Anchor, input.Variables)
+	if err != nil {
+		var missingErr *service.MissingVariablesError
+		var validationErr *service.TaskValidationError
+		switch {
+		case errors.Is(err, service.ErrTemplateNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "模板不存在"})
+		case errors.As(err, &missingErr):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "缺少模板变量", "missing": missingErr.Names})
+		case errors.Is(err, service.ErrInvalidAnchorDate):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "锚定日期格式错误"})
+		case errors.As(err, &validationErr):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "替换变量后的任务不合法", "field": validationErr.Field, "detail": validationErr.Reason})
+		case errors.Is(err, service.ErrInstantiateTemplateFail):
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "创建失败"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "创建成功",
+		"tasks":   tasks,
+	})
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	models "myproject/internal/model"
+	"myproject/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportTasks 导出任务
+// @Summary      导出任务
+// @Description  以 CSV、JSON 或 NDJSON 格式流式导出当前用户的全部任务
+// @Tags         导入导出
+// @Produce      text/csv
+// @Produce      json
+// @Produce      application/x-ndjson
+// @Security     BearerAuth
+// @Param        format  query     string  false  "导出格式 csv|json|ndjson，默认 json"
+// @Success      200     {file}    file    "任务数据"
+// @Failure      400     {object}  map[string]interface{}  "不支持的格式"
+// @Failure      401     {object}  map[string]interface{}  "未认证"
+// @Router       /export [get]
+func ExportTasks(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	format := c.DefaultQuery("format", service.FormatJSON)
+	contentType, err := service.ContentTypeForFormat(format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的格式"})
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="tasks.%s"`, format))
+	c.Status(http.StatusOK)
+
+	// 响应头已经发出，中途失败只能中断连接并记录错误
+	if err := service.ExportTasks(currentUser, format, c.Writer); err != nil {
+		c.Error(err)
+		c.Abort()
+	}
+}
+
+// ImportTasks 导入任务
+// @Summary      导入任务
+// @Description  以流的方式导入 CSV、JSON 或 NDJSON 格式的任务，带 external_id 的记录重复导入时更新而不是新建
+// @Tags         导入导出
+// @Accept       text/csv
+// @Accept       json
+// @Accept       application/x-ndjson
+// @Produce      json
+// @Security     BearerAuth
+// @Param        format   query     string  false  "导入格式 csv|json|ndjson，默认根据 Content-Type 判断"
+// @Param        dry_run  query     bool    false  "只校验不写入"
+// @Success      200      {object}  map[string]interface{}  "导入结果"
+// @Failure      400      {object}  map[string]interface{}  "请求参数错误"
+// @Failure      401      {object}  map[string]interface{}  "未认证"
+// @Router       /import [post]
+func ImportTasks(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	format := c.Query("format")
+	if format == "" {
+		format = service.FormatFromContentType(c.ContentType())
+	}
+
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+
+	result, err := service.ImportTasks(currentUser, format, c.Request.Body, dryRun)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUnsupportedFormat):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的格式"})
+		case errors.Is(err, service.ErrMalformedImport):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  "文件格式错误: " + err.Error(),
+				"result": result,
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		}
+		return
+	}
+
+	message := "导入完成"
+	if dryRun {
+		message = "校验完成"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"result":  result,
+	})
+}
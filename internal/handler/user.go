@@ -1,8 +1,13 @@
 package handler
 
 import (
+	"errors"
+	"math"
+	models "myproject/internal/model"
 	"myproject/internal/service"
+	"myproject/utils"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,13 +19,28 @@ type RegisterInput struct {
 }
 
 type LoginInput struct {
-    Username string `json:"username" binding:"required"`
-    Password string `json:"password" binding:"required"`
+    Username   string `json:"username" binding:"required"`
+    Password   string `json:"password" binding:"required"`
+    DeviceName string `json:"device_name" binding:"max=100"` // 可选，显示在会话列表中
+}
+
+type RefreshTokenInput struct {
+    RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// UserHandler 注册、登录和个人资料接口
+type UserHandler struct {
+	users *service.UserService
+}
+
+// NewUserHandler 创建注册、登录和个人资料接口
+func NewUserHandler(users *service.UserService) *UserHandler {
+	return &UserHandler{users: users}
 }
 
 // Register 用户注册
 // @Summary      用户注册
-// @Description  创建新用户账号
+// @Description  创建新用户账号，并向邮箱发送验证链接
 // @Tags         用户
 // @Accept       json
 // @Produce      json
@@ -29,14 +49,14 @@ type LoginInput struct {
 // @Failure      400  {object}  map[string]interface{}  "请求参数错误"
 // @Failure      500  {object}  map[string]interface{}  "服务器错误"
 // @Router       /register [post]
-func Register(c *gin.Context) {
+func (h *UserHandler) Register(c *gin.Context) {
 	var input RegisterInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	user, err := service.Register(input.Username, input.Password, input.Email)
+	user, err := h.users.Register(input.Username, input.Password, input.Email)
 	if err != nil {
 		switch err {
 		case service.ErrHashPasswordFailed:
@@ -50,18 +70,19 @@ func Register(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "注册成功",
+		"message": "注册成功，请查收验证邮件",
 		"user": gin.H{
-			"id":       user.ID,
-			"username": user.Username,
-			"email":    user.Email,
+			"id":             user.ID,
+			"username":       user.Username,
+			"email":          user.Email,
+			"email_verified": user.EmailVerified,
 		},
 	})
 }
 
 // Login 用户登录
 // @Summary      用户登录
-// @Description  用户登录获取JWT令牌
+// @Description  用户登录获取短期有效的JWT访问令牌和用于续期的刷新令牌；开启两步验证时返回 mfa_token，需要再调用 /login/mfa
 // @Tags         用户
 // @Accept       json
 // @Produce      json
@@ -69,19 +90,32 @@ func Register(c *gin.Context) {
 // @Success      200  {object}  map[string]interface{}  "登录成功返回token"
 // @Failure      400  {object}  map[string]interface{}  "请求参数错误"
 // @Failure      401  {object}  map[string]interface{}  "用户名或密码错误"
+// @Failure      403  {object}  map[string]interface{}  "邮箱未验证"
+// @Failure      429  {object}  map[string]interface{}  "失败次数过多，Retry-After 秒后重试"
 // @Router       /login [post]
-func Login(c *gin.Context) {
+func (h *UserHandler) Login(c *gin.Context) {
 	var input LoginInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	token, user, err := service.Login(input.Username, input.Password)
+	result, err := h.users.Login(input.Username, input.Password, service.ClientInfo{
+		DeviceName: input.DeviceName,
+		UserAgent:  c.Request.UserAgent(),
+		IP:         c.ClientIP(),
+	})
 	if err != nil {
+		if respondLoginThrottled(c, err) {
+			return
+		}
 		switch err {
 		case service.ErrInvalidCredentials:
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "用户名或密码错误"})
+		case service.ErrEmailNotVerified:
+			c.JSON(http.StatusForbidden, gin.H{"error": "邮箱未验证，请先完成验证"})
+		case service.ErrAccountDisabled:
+			c.JSON(http.StatusForbidden, gin.H{"error": "账号已被禁用"})
 		case service.ErrGenerateTokenFailed:
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "生成token失败"})
 		default:
@@ -90,13 +124,139 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	respondLoginResult(c, result)
+}
+
+// respondLoginResult 开启了两步验证时返回挑战令牌，需要调用 /login/mfa 完成登录；否则直接返回令牌
+func respondLoginResult(c *gin.Context, result *service.LoginResult) {
+	if result.MFAToken != "" {
+		c.JSON(http.StatusOK, gin.H{
+			"message":      "请输入两步验证码",
+			"mfa_required": true,
+			"mfa_token":    result.MFAToken,
+		})
+		return
+	}
+
+	respondLogin(c, result)
+}
+
+// respondLoginThrottled 登录被限流时返回 429 和 Retry-After
+func respondLoginThrottled(c *gin.Context, err error) bool {
+	var throttled *service.LoginThrottledError
+	if !errors.As(err, &throttled) {
+		return false
+	}
+	seconds := int(math.Ceil(throttled.RetryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "登录失败次数过多，请稍后再试",
+		"retry_after": seconds,
+	})
+	return true
+}
+
+// respondLogin 返回登录成功后的令牌和用户信息
+func respondLogin(c *gin.Context, result *service.LoginResult) {
 	c.JSON(http.StatusOK, gin.H{
-		"message": "登录成功",
-		"token":   token,
+		"message":       "登录成功",
+		"token":         result.Tokens.AccessToken,
+		"refresh_token": result.Tokens.RefreshToken,
+		"expires_in":    result.Tokens.ExpiresIn,
 		"user": gin.H{
-			"id":       user.ID,
-			"username": user.Username,
-			"email":    user.Email,
+			"id":             result.User.ID,
+			"username":       result.User.Username,
+			"email":          result.User.Email,
+			"email_verified": result.User.EmailVerified,
+			"totp_enabled":   result.User.TOTPEnabled,
 		},
 	})
+}
+
+// RefreshToken 刷新令牌
+// @Summary      刷新令牌
+// @Description  用刷新令牌换取新的访问令牌和刷新令牌，旧的刷新令牌随即失效；重复使用已换发的刷新令牌会吊销所在会话
+// @Tags         用户
+// @Accept       json
+// @Produce      json
+// @Param        request body RefreshTokenInput true "刷新令牌"
+// @Success      200  {object}  map[string]interface{}  "新的令牌"
+// @Failure      400  {object}  map[string]interface{}  "请求参数错误"
+// @Failure      401  {object}  map[string]interface{}  "刷新令牌无效或已被重复使用"
+// @Router       /token/refresh [post]
+func RefreshToken(c *gin.Context) {
+	var input RefreshTokenInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, _, err := service.RefreshTokens(input.RefreshToken, service.ClientInfo{
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	})
+	if err != nil {
+		switch err {
+		case service.ErrInvalidRefreshToken:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "刷新令牌无效或已过期"})
+		case service.ErrRefreshTokenReused:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "刷新令牌已被使用，请重新登录"})
+		case service.ErrGenerateTokenFailed:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "生成token失败"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+	})
+}
+
+// Logout 退出登录
+// @Summary      退出登录
+// @Description  注销当前访问令牌并结束所在会话，会话的刷新令牌一并失效
+// @Tags         用户
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string]interface{}  "已退出登录"
+// @Failure      401  {object}  map[string]interface{}  "未授权"
+// @Failure      500  {object}  map[string]interface{}  "服务器错误"
+// @Router       /logout [post]
+func Logout(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+	claims, _ := c.Get("claims")
+
+	if err := service.Logout(currentUser, claims.(*utils.Claims)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "退出登录失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已退出登录"})
+}
+
+// LogoutAll 退出全部设备
+// @Summary      退出全部设备
+// @Description  吊销当前用户的全部会话，此前签发的访问令牌和刷新令牌全部失效
+// @Tags         用户
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string]interface{}  "已退出全部设备"
+// @Failure      401  {object}  map[string]interface{}  "未授权"
+// @Failure      500  {object}  map[string]interface{}  "服务器错误"
+// @Router       /logout-all [post]
+func LogoutAll(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	if err := service.LogoutAll(currentUser); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "退出登录失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已退出全部设备"})
 }
\ No newline at end of file
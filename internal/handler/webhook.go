@@ -0,0 +1,200 @@
+package handler
+
+import (
+	models "myproject/internal/model"
+	"myproject/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreateWebhookInput struct {
+	URL    string   `json:"url" binding:"required"`
+	Events []string `json:"events"` // 为空表示订阅全部事件
+}
+
+// CreateWebhook 注册 webhook
+// @Summary      注册 webhook
+// @Description  任务创建、更新、删除、完成时向 url 发送签名的 JSON 请求；签名密钥只在创建时返回一次
+// @Tags         Webhook
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body CreateWebhookInput true "webhook 信息"
+// @Success      200  {object}  map[string]interface{}  "创建成功"
+// @Failure      400  {object}  map[string]interface{}  "请求参数错误"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Router       /api/webhooks [post]
+func CreateWebhook(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	var input CreateWebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	webhook, secret, err := service.CreateWebhook(currentUser, input.URL, input.Events)
+	if err != nil {
+		switch err {
+		case service.ErrInvalidWebhookURL:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "url 必须是 http 或 https 地址"})
+		case service.ErrInvalidEventType:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的事件类型"})
+		case service.ErrCreateWebhookFail:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "创建失败"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "创建成功",
+		"webhook": webhook,
+		"secret":  secret,
+	})
+}
+
+// GetWebhooks 获取 webhook 列表
+// @Summary      获取所有 webhook
+// @Description  获取当前用户注册的全部 webhook
+// @Tags         Webhook
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string]interface{}  "webhook 列表"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Router       /api/webhooks [get]
+func GetWebhooks(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	webhooks, err := service.GetWebhooks(currentUser)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"webhooks": webhooks})
+}
+
+// GetWebhook 获取单个 webhook
+// @Summary      获取 webhook 详情
+// @Description  根据ID获取 webhook
+// @Tags         Webhook
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "webhook ID"
+// @Success      200  {object}  map[string]interface{}  "webhook 详情"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Failure      404  {object}  map[string]interface{}  "webhook 不存在"
+// @Router       /api/webhooks/{id} [get]
+func GetWebhook(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	webhook, err := service.GetWebhook(currentUser, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "webhook 不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"webhook": webhook})
+}
+
+// DeleteWebhook 删除 webhook
+// @Summary      删除 webhook
+// @Description  删除 webhook 及其投递记录
+// @Tags         Webhook
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "webhook ID"
+// @Success      200  {object}  map[string]interface{}  "删除成功"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Failure      404  {object}  map[string]interface{}  "webhook 不存在"
+// @Router       /api/webhooks/{id} [delete]
+func DeleteWebhook(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	if err := service.DeleteWebhook(currentUser, c.Param("id")); err != nil {
+		switch err {
+		case service.ErrWebhookNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "webhook 不存在"})
+		case service.ErrDeleteWebhookFail:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "删除失败"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+}
+
+// GetWebhookDeliveries 获取投递记录
+// @Summary      获取 webhook 投递记录
+// @Description  获取 webhook 最近的投递记录，包括响应状态码、响应内容和错误信息
+// @Tags         Webhook
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "webhook ID"
+// @Success      200  {object}  map[string]interface{}  "投递记录"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Failure      404  {object}  map[string]interface{}  "webhook 不存在"
+// @Router       /api/webhooks/{id}/deliveries [get]
+func GetWebhookDeliveries(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	deliveries, err := service.GetWebhookDeliveries(currentUser, c.Param("id"))
+	if err != nil {
+		switch err {
+		case service.ErrWebhookNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "webhook 不存在"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
+
+// RedeliverWebhook 重新投递
+// @Summary      重新投递 webhook
+// @Description  以原始请求体重新投递一次，生成新的投递记录
+// @Tags         Webhook
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id           path      string  true  "webhook ID"
+// @Param        delivery_id  path      string  true  "投递ID"
+// @Success      200  {object}  map[string]interface{}  "已加入投递队列"
+// @Failure      401  {object}  map[string]interface{}  "未认证"
+// @Failure      404  {object}  map[string]interface{}  "webhook 或投递记录不存在"
+// @Router       /api/webhooks/{id}/deliveries/{delivery_id}/redeliver [post]
+func RedeliverWebhook(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	delivery, err := service.RedeliverWebhook(currentUser, c.Param("id"), c.Param("delivery_id"))
+	if err != nil {
+		switch err {
+		case service.ErrWebhookNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "webhook 不存在"})
+		case service.ErrDeliveryNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "投递记录不存在"})
+		case service.ErrRedeliverWebhookFail:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "重新投递失败"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "已加入投递队列",
+		"delivery": delivery,
+	})
+}
@@ -0,0 +1,251 @@
+package jsonpatch
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func decode(t *testing.T, s string) interface{} {
+	t.Helper()
+	v, err := Decode([]byte(s))
+	if err != nil {
+		t.Fatalf("decode %s: %v", s, err)
+	}
+	return v
+}
+
+// RFC 6902 附录 A 的示例，以及数组下标、移动到子节点、数值比较等边界情况
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string // 为空表示应当失败
+		err   error
+	}{
+		// A.1 - A.16
+		{"A.1 add object member", `{"foo":"bar"}`,
+			`[{"op":"add","path":"/baz","value":"qux"}]`,
+			`{"baz":"qux","foo":"bar"}`, nil},
+		{"A.2 add array element", `{"foo":["bar","baz"]}`,
+			`[{"op":"add","path":"/foo/1","value":"qux"}]`,
+			`{"foo":["bar","qux","baz"]}`, nil},
+		{"A.3 remove object member", `{"baz":"qux","foo":"bar"}`,
+			`[{"op":"remove","path":"/baz"}]`,
+			`{"foo":"bar"}`, nil},
+		{"A.4 remove array element", `{"foo":["bar","qux","baz"]}`,
+			`[{"op":"remove","path":"/foo/1"}]`,
+			`{"foo":["bar","baz"]}`, nil},
+		{"A.5 replace value", `{"baz":"qux","foo":"bar"}`,
+			`[{"op":"replace","path":"/baz","value":"boo"}]`,
+			`{"baz":"boo","foo":"bar"}`, nil},
+		{"A.6 move value", `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			`[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`, nil},
+		{"A.7 move array element", `{"foo":["all","grass","cows","eats"]}`,
+			`[{"op":"move","from":"/foo/1","path":"/foo/3"}]`,
+			`{"foo":["all","cows","eats","grass"]}`, nil},
+		{"A.8 test success", `{"baz":"qux","foo":["a",2,"c"]}`,
+			`[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`,
+			`{"baz":"qux","foo":["a",2,"c"]}`, nil},
+		{"A.9 test failure", `{"baz":"qux"}`,
+			`[{"op":"test","path":"/baz","value":"bar"}]`,
+			``, ErrTestFailed},
+		{"A.10 add nested member", `{"foo":"bar"}`,
+			`[{"op":"add","path":"/child","value":{"grandchild":{}}}]`,
+			`{"foo":"bar","child":{"grandchild":{}}}`, nil},
+		{"A.11 ignore unrecognized elements", `{"foo":"bar"}`,
+			`[{"op":"add","path":"/baz","value":"qux","xyz":123}]`,
+			`{"foo":"bar","baz":"qux"}`, nil},
+		{"A.12 add to nonexistent target", `{"foo":"bar"}`,
+			`[{"op":"add","path":"/baz/bat","value":"qux"}]`,
+			``, ErrInvalidPatch},
+		{"A.13 invalid patch document", `{"foo":"bar"}`,
+			`[{"op":"add","path":"/baz","value":"qux","op":"remove"}]`,
+			``, ErrInvalidPatch},
+		{"A.14 escape ordering", `{"/":9,"~1":10}`,
+			`[{"op":"test","path":"/~01","value":10}]`,
+			`{"/":9,"~1":10}`, nil},
+		{"A.15 compare strings and numbers", `{"/":9,"~1":10}`,
+			`[{"op":"test","path":"/~01","value":"10"}]`,
+			``, ErrTestFailed},
+		{"A.16 add array value", `{"foo":["bar"]}`,
+			`[{"op":"add","path":"/foo/-","value":["abc","def"]}]`,
+			`{"foo":["bar",["abc","def"]]}`, nil},
+
+		// 数组
+		{"append with dash", `{"foo":[1,2]}`,
+			`[{"op":"add","path":"/foo/-","value":3}]`,
+			`{"foo":[1,2,3]}`, nil},
+		{"add at end index", `{"foo":[1,2]}`,
+			`[{"op":"add","path":"/foo/2","value":3}]`,
+			`{"foo":[1,2,3]}`, nil},
+		{"add past end", `{"foo":[1,2]}`,
+			`[{"op":"add","path":"/foo/3","value":3}]`,
+			``, ErrInvalidPatch},
+		{"remove dash", `{"foo":[1,2]}`,
+			`[{"op":"remove","path":"/foo/-"}]`,
+			``, ErrInvalidPatch},
+		{"index with leading zero", `{"foo":[1,2]}`,
+			`[{"op":"remove","path":"/foo/01"}]`,
+			``, ErrInvalidPatch},
+		{"index with sign", `{"foo":[1,2]}`,
+			`[{"op":"remove","path":"/foo/+1"}]`,
+			``, ErrInvalidPatch},
+		{"negative index", `{"foo":[1,2]}`,
+			`[{"op":"remove","path":"/foo/-1"}]`,
+			``, ErrInvalidPatch},
+		{"add into nested array", `{"a":[[1],[2]]}`,
+			`[{"op":"add","path":"/a/1/0","value":0}]`,
+			`{"a":[[1],[0,2]]}`, nil},
+		{"remove from nested array", `{"a":[[1,2],[3]]}`,
+			`[{"op":"remove","path":"/a/0/0"}]`,
+			`{"a":[[2],[3]]}`, nil},
+		{"insert into root array", `[1,2]`,
+			`[{"op":"add","path":"/0","value":0}]`,
+			`[0,1,2]`, nil},
+		{"replace array element", `{"foo":[1,2,3]}`,
+			`[{"op":"replace","path":"/foo/1","value":9}]`,
+			`{"foo":[1,9,3]}`, nil},
+		{"dash on object is a key", `{"foo":{}}`,
+			`[{"op":"add","path":"/foo/-","value":1}]`,
+			`{"foo":{"-":1}}`, nil},
+
+		// replace / remove / move / copy
+		{"replace missing member", `{"foo":"bar"}`,
+			`[{"op":"replace","path":"/baz","value":1}]`,
+			``, ErrInvalidPatch},
+		{"remove missing member", `{"foo":"bar"}`,
+			`[{"op":"remove","path":"/baz"}]`,
+			``, ErrInvalidPatch},
+		{"replace whole document", `{"foo":"bar"}`,
+			`[{"op":"replace","path":"","value":[1]}]`,
+			`[1]`, nil},
+		{"move into own child", `{"a":{"b":{}}}`,
+			`[{"op":"move","from":"/a","path":"/a/b/c"}]`,
+			``, ErrInvalidPatch},
+		{"move to sibling with shared prefix", `{"a":1}`,
+			`[{"op":"move","from":"/a","path":"/ab"}]`,
+			`{"ab":1}`, nil},
+		{"move to itself", `{"a":1}`,
+			`[{"op":"move","from":"/a","path":"/a"}]`,
+			`{"a":1}`, nil},
+		{"move between arrays", `{"a":[1,2],"b":[3]}`,
+			`[{"op":"move","from":"/a/0","path":"/b/-"}]`,
+			`{"a":[2],"b":[3,1]}`, nil},
+		{"copy is deep", `{"a":{"x":1}}`,
+			`[{"op":"copy","from":"/a","path":"/b"},{"op":"replace","path":"/b/x","value":2}]`,
+			`{"a":{"x":1},"b":{"x":2}}`, nil},
+		{"missing value", `{}`,
+			`[{"op":"add","path":"/a"}]`,
+			``, ErrInvalidPatch},
+		{"unknown op", `{}`,
+			`[{"op":"frobnicate","path":"/a"}]`,
+			``, ErrInvalidPatch},
+		{"pointer without slash", `{"a":1}`,
+			`[{"op":"remove","path":"a"}]`,
+			``, ErrInvalidPatch},
+		{"ops apply in order", `{}`,
+			`[{"op":"add","path":"/a","value":[]},{"op":"add","path":"/a/-","value":1},{"op":"test","path":"/a/0","value":1}]`,
+			`{"a":[1]}`, nil},
+
+		// test 的数值比较
+		{"test int against float", `{"n":1}`,
+			`[{"op":"test","path":"/n","value":1.0}]`,
+			`{"n":1}`, nil},
+		{"test exponent", `{"n":100}`,
+			`[{"op":"test","path":"/n","value":1e2}]`,
+			`{"n":100}`, nil},
+		{"test nested numbers", `{"a":[{"n":2.50}]}`,
+			`[{"op":"test","path":"/a","value":[{"n":2.5}]}]`,
+			`{"a":[{"n":2.50}]}`, nil},
+		{"test large integers exactly", `{"n":9007199254740993}`,
+			`[{"op":"test","path":"/n","value":9007199254740992}]`,
+			``, ErrTestFailed},
+		{"test null", `{"n":null}`,
+			`[{"op":"test","path":"/n","value":null}]`,
+			`{"n":null}`, nil},
+		{"test missing member", `{}`,
+			`[{"op":"test","path":"/n","value":null}]`,
+			``, ErrInvalidPatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ops, err := ParsePatch([]byte(tt.patch))
+			if err != nil {
+				t.Fatalf("parse patch: %v", err)
+			}
+			got, err := Apply(decode(t, tt.doc), ops)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if want := decode(t, tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %#v, want %#v", got, want)
+			}
+		})
+	}
+}
+
+// test 失败不应被包装成 ErrInvalidPatch，调用方据此返回 409 而不是 400
+func TestApplyTestFailureIsNotInvalidPatch(t *testing.T) {
+	ops, err := ParsePatch([]byte(`[{"op":"test","path":"/a","value":2}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Apply(decode(t, `{"a":1}`), ops)
+	if !errors.Is(err, ErrTestFailed) || errors.Is(err, ErrInvalidPatch) {
+		t.Errorf("error = %v, want only ErrTestFailed", err)
+	}
+}
+
+func TestParsePatchRejectsNonArray(t *testing.T) {
+	if _, err := ParsePatch([]byte(`{"op":"add"}`)); !errors.Is(err, ErrInvalidPatch) {
+		t.Errorf("error = %v, want ErrInvalidPatch", err)
+	}
+}
+
+// RFC 7396 附录 A 的示例
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		target, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target+" + "+tt.patch, func(t *testing.T) {
+			got := MergePatch(decode(t, tt.target), decode(t, tt.patch))
+			if want := decode(t, tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %#v, want %#v", got, want)
+			}
+		})
+	}
+}
+
+func TestDecodeRejectsTrailingData(t *testing.T) {
+	if _, err := Decode([]byte(`{} {}`)); err == nil {
+		t.Error("expected an error for trailing data")
+	}
+}
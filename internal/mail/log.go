@@ -0,0 +1,49 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LogMailer 本地开发用，不真正发送邮件：设置了目录时把邮件写成 .eml 文件，否则直接输出到日志
+type LogMailer struct {
+	dir  string
+	from string
+}
+
+func NewLogMailer(dir, from string) *LogMailer {
+	return &LogMailer{dir: dir, from: from}
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	if msg.To == "" {
+		return ErrNoRecipient
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if m.dir == "" {
+		log.Printf("邮件 -> %s\n主题: %s\n%s", msg.To, msg.Subject, msg.Text)
+		return nil
+	}
+
+	body, err := buildMessage(m.from, msg)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102-150405.000000"), msg.To)
+	path := filepath.Join(m.dir, filepath.Base(name))
+	if err := os.WriteFile(path, body, 0o644); err != nil {
+		return err
+	}
+	log.Printf("邮件 -> %s 已写入 %s", msg.To, path)
+	return nil
+}
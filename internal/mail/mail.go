@@ -0,0 +1,41 @@
+// Package mail 定义发送邮件的接口及其实现。
+package mail
+
+import (
+	"context"
+	"errors"
+	"os"
+)
+
+var ErrNoRecipient = errors.New("mail has no recipient")
+
+// Message 一封邮件，HTML 为空时只发送纯文本
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer 邮件发送器
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// FromEnv 根据 MAIL_DRIVER 选择发送方式：smtp（默认）通过 SMTP 发送，
+// file 写入 MAIL_DIR 目录（默认 tmp/mail），log 只输出到日志
+func FromEnv() Mailer {
+	cfg := SMTPConfigFromEnv()
+	switch os.Getenv("MAIL_DRIVER") {
+	case "file":
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = "tmp/mail"
+		}
+		return NewLogMailer(dir, cfg.From)
+	case "log":
+		return NewLogMailer("", cfg.From)
+	default:
+		return NewSMTPMailer(cfg)
+	}
+}
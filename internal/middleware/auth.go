@@ -3,9 +3,12 @@ package middleware
 import (
 	"myproject/config"
 	models "myproject/internal/model"
+	"myproject/internal/repository"
+	"myproject/internal/service"
 	"myproject/utils"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -28,24 +31,109 @@ func AuthMiddleware() gin.HandlerFunc {
             return
         }
         
-        // 解析 token
-        claims, err := utils.ParseToken(parts[1])
-        if err != nil {
-            c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的token"})
-            c.Abort()
-            return
+        authenticate(c, parts[1])
+    }
+}
+
+// StreamAuthMiddleware 用于 SSE 和 WebSocket 连接。浏览器的 EventSource 和 WebSocket 无法设置请求头，
+// 没有 Authorization 时改从 access_token 查询参数读取 token，校验方式与 AuthMiddleware 相同。
+// 查询参数可能出现在代理和浏览器历史中，只接受短期的登录 token；长期有效的个人访问令牌必须放在请求头中
+func StreamAuthMiddleware() gin.HandlerFunc {
+    auth := AuthMiddleware()
+    return func(c *gin.Context) {
+        if c.GetHeader("Authorization") == "" {
+            if token := c.Query("access_token"); token != "" {
+                if strings.HasPrefix(token, service.AccessTokenPrefix) {
+                    c.JSON(http.StatusUnauthorized, gin.H{"error": "个人访问令牌只能通过 Authorization 请求头提供"})
+                    c.Abort()
+                    return
+                }
+                authenticate(c, token)
+                return
+            }
         }
-        
-        // 获取用户信息
-        var user models.User
-        if err := config.DB.First(&user, claims.UserID).Error; err != nil {
-            c.JSON(http.StatusUnauthorized, gin.H{"error": "用户不存在"})
-            c.Abort()
-            return
+        auth(c)
+    }
+}
+
+// authenticate 校验 token 并加载用户，失败时中止请求
+func authenticate(c *gin.Context, token string) {
+    // 个人访问令牌
+    if strings.HasPrefix(token, service.AccessTokenPrefix) {
+        authenticateAccessToken(c, token)
+        return
+    }
+    
+    // 解析 token
+    claims, err := utils.ParseToken(token)
+    if err != nil {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的token"})
+        c.Abort()
+        return
+    }
+    
+    // 已注销的 token 不再接受
+    if revoked, err := repository.IsAccessTokenRevoked(claims.ID); err != nil || revoked {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "token已失效"})
+        c.Abort()
+        return
+    }
+    
+    // 令牌所属的会话被吊销后不再接受
+    if err := service.CheckSession(claims); err != nil {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "会话已失效"})
+        c.Abort()
+        return
+    }
+    
+    // 获取用户信息
+    var user models.User
+    if err := config.DB.First(&user, claims.UserID).Error; err != nil {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "用户不存在"})
+        c.Abort()
+        return
+    }
+    
+    // 被禁用的用户不能继续使用已签发的 token
+    if user.DisabledAt != nil {
+        c.JSON(http.StatusForbidden, gin.H{"error": "账号已被禁用"})
+        c.Abort()
+        return
+    }
+    
+    // 退出全部设备之前签发的 token 一律失效。iat 只精确到秒，同一秒内更早签发的 token 由会话吊销兜底
+    if user.TokensRevokedAt != nil && (claims.IssuedAt == nil || claims.IssuedAt.Before(user.TokensRevokedAt.Truncate(time.Second))) {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "token已失效"})
+        c.Abort()
+        return
+    }
+    
+    // 将用户信息存入上下文
+    c.Set("user", user)
+    c.Set("claims", claims)
+    c.Next()
+}
+
+// authenticateAccessToken 校验个人访问令牌，令牌的权限存入上下文，由 RequireScope 检查
+func authenticateAccessToken(c *gin.Context, raw string) {
+    token, user, err := service.AuthenticateAccessToken(raw)
+    if err != nil {
+        switch err {
+        case service.ErrAccessTokenExpired:
+            c.JSON(http.StatusUnauthorized, gin.H{"error": "访问令牌已过期"})
+        default:
+            c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的访问令牌"})
         }
-        
-        // 将用户信息存入上下文
-        c.Set("user", user)
-        c.Next()
+        c.Abort()
+        return
+    }
+    if user.DisabledAt != nil {
+        c.JSON(http.StatusForbidden, gin.H{"error": "账号已被禁用"})
+        c.Abort()
+        return
     }
-}
\ No newline at end of file
+    
+    c.Set("user", *user)
+    c.Set("scopes", token.Scopes)
+    c.Next()
+}
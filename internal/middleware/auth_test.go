@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"myproject/internal/service"
+	"myproject/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 个人访问令牌长期有效，不能放在可能被记录的查询参数中
+func TestStreamAuthRejectsAccessTokensInQuery(t *testing.T) {
+	testutil.OpenDB(t)
+	user := testutil.CreateUser(t, "alice")
+	_, token, err := service.CreateAccessToken(user, "stream", service.Scopes, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/events", StreamAuthMiddleware(), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	req := httptest.NewRequest(http.MethodGet, "/events?access_token="+token, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("token in query: status %d, want 401", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("token in header: status %d, want 204", w.Code)
+	}
+}
+
+func TestLoggerRedactsTokens(t *testing.T) {
+	var buf bytes.Buffer
+	previous := gin.DefaultWriter
+	gin.DefaultWriter = &buf
+	defer func() { gin.DefaultWriter = previous }()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Logger())
+	r.GET("/events", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	req := httptest.NewRequest(http.MethodGet, "/events?after=5&access_token=eyJhbGciOi.secret&token=abc", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	line := buf.String()
+	if strings.Contains(line, "secret") || strings.Contains(line, "abc") {
+		t.Errorf("log contains a token: %s", line)
+	}
+	if !strings.Contains(line, "/events?after=5&access_token=REDACTED&token=REDACTED") {
+		t.Errorf("log line = %s", line)
+	}
+}
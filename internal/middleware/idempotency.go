@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	models "myproject/internal/model"
+	"myproject/internal/service"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+    maxIdempotencyKeyLen = 191
+    // 请求体不超过该大小时保存在内存中，更大的（例如导入文件）暂存到临时文件
+    maxInMemoryBody = 1 << 20
+)
+
+// 回放时一并恢复的响应头
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// Idempotency 处理写请求上的 Idempotency-Key 请求头，需放在 AuthMiddleware 之后。
+// 同一用户、同一个键的重试会回放首次请求的响应；键相同但请求不同时返回 422
+func Idempotency() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        key := c.GetHeader("Idempotency-Key")
+        switch c.Request.Method {
+        case http.MethodGet, http.MethodHead, http.MethodOptions:
+            key = ""
+        }
+        if key == "" {
+            c.Next()
+            return
+        }
+        if len(key) > maxIdempotencyKeyLen {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key 过长"})
+            c.Abort()
+            return
+        }
+
+        body, fingerprint, err := bufferRequestBody(c)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "读取请求失败"})
+            c.Abort()
+            return
+        }
+        defer body.Close()
+        c.Request.Body = body
+
+        user, _ := c.Get("user")
+        currentUser := user.(models.User)
+
+        record, replay, err := service.BeginIdempotentRequest(c.Request.Context(), currentUser, key, fingerprint)
+        if err != nil {
+            switch err {
+            case service.ErrIdempotencyKeyMismatch:
+                c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key 已用于不同的请求"})
+            case service.ErrIdempotencyKeyInUse:
+                c.JSON(http.StatusConflict, gin.H{"error": "相同 Idempotency-Key 的请求正在处理"})
+            default:
+                c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+            }
+            c.Abort()
+            return
+        }
+
+        if replay != nil {
+            for name, value := range replay.ResponseHeaders {
+                c.Header(name, value)
+            }
+            c.Header("Idempotent-Replayed", "true")
+            c.Status(replay.ResponseStatus)
+            c.Writer.Write(replay.ResponseBody)
+            c.Abort()
+            return
+        }
+
+        // 处理期间一直持有锁，重试的请求等待本次请求完成后回放，不会重复执行
+        hold, release := context.WithCancel(context.Background())
+        go service.HoldIdempotentRequest(hold, record)
+        defer release()
+
+        recorder := &responseRecorder{ResponseWriter: c.Writer}
+        c.Writer = recorder
+        c.Next()
+        release()
+
+        headers := make(map[string]string)
+        for _, name := range replayedHeaders {
+            if value := recorder.Header().Get(name); value != "" {
+                headers[name] = value
+            }
+        }
+        if err := service.FinishIdempotentRequest(record, recorder.Status(), headers, recorder.body.Bytes()); err != nil {
+            log.Printf("保存幂等响应失败: %v", err)
+        }
+    }
+}
+
+// bufferRequestBody 读出请求体并计算请求方法、路径、查询参数和请求体的摘要，用于识别同一个键被用于不同的请求。
+// 返回可重新读取的请求体，超过 maxInMemoryBody 的部分暂存到临时文件，关闭时删除
+func bufferRequestBody(c *gin.Context) (io.ReadCloser, string, error) {
+    h := sha256.New()
+    io.WriteString(h, c.Request.Method+" "+c.Request.URL.Path+"?"+c.Request.URL.RawQuery+"\n")
+
+    var buf bytes.Buffer
+    n, err := io.CopyN(io.MultiWriter(&buf, h), c.Request.Body, maxInMemoryBody+1)
+    if err != nil && err != io.EOF {
+        return nil, "", err
+    }
+    if n <= maxInMemoryBody {
+        return io.NopCloser(&buf), hex.EncodeToString(h.Sum(nil)), nil
+    }
+
+    f, err := os.CreateTemp("", "idempotent-body-*")
+    if err != nil {
+        return nil, "", err
+    }
+    spooled := &tempFileBody{File: f}
+    if _, err := buf.WriteTo(f); err != nil {
+        spooled.Close()
+        return nil, "", err
+    }
+    if _, err := io.Copy(io.MultiWriter(f, h), c.Request.Body); err != nil {
+        spooled.Close()
+        return nil, "", err
+    }
+    if _, err := f.Seek(0, io.SeekStart); err != nil {
+        spooled.Close()
+        return nil, "", err
+    }
+    return spooled, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// tempFileBody 暂存到临时文件的请求体，关闭时删除文件。可以重复关闭
+type tempFileBody struct {
+    *os.File
+    once sync.Once
+}
+
+func (b *tempFileBody) Close() error {
+    b.once.Do(func() {
+        b.File.Close()
+        os.Remove(b.File.Name())
+    })
+    return nil
+}
+
+// responseRecorder 在写出响应的同时保留一份响应体
+type responseRecorder struct {
+    gin.ResponseWriter
+    body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+    w.body.Write(b)
+    return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+    w.body.WriteString(s)
+    return w.ResponseWriter.WriteString(s)
+}
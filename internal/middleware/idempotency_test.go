@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"myproject/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 超过内存上限的请求体（例如导入文件）暂存到临时文件，同样可以使用 Idempotency-Key
+func TestIdempotencyAcceptsLargeBodies(t *testing.T) {
+	testutil.OpenDB(t)
+	user := testutil.CreateUser(t, "alice")
+
+	calls := 0
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/upload", func(c *gin.Context) { c.Set("user", user) }, Idempotency(), func(c *gin.Context) {
+		calls++
+		body, _ := io.ReadAll(c.Request.Body)
+		sum := sha256.Sum256(body)
+		c.JSON(http.StatusCreated, gin.H{"size": len(body), "sum": sum[:]})
+	})
+
+	body := bytes.Repeat([]byte("0123456789abcdef"), maxInMemoryBody/8)
+	post := func(body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/upload", bytes.NewReader(body))
+		req.Header.Set("Idempotency-Key", "upload")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := post(body)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first request: status %d: %s", first.Code, first.Body)
+	}
+	retry := post(body)
+	if retry.Header().Get("Idempotent-Replayed") != "true" || retry.Body.String() != first.Body.String() {
+		t.Errorf("retry was not replayed: %d %s", retry.Code, retry.Body)
+	}
+	if calls != 1 {
+		t.Errorf("handler called %d times, want 1", calls)
+	}
+
+	// 同一个键用于内容不同的大请求体
+	changed := append([]byte(nil), body...)
+	changed[len(changed)-1] = 'x'
+	if w := post(changed); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("different body with the same key: status %d, want 422", w.Code)
+	}
+}
+
+// 查询参数不同（例如 dry_run）的请求不能回放彼此的响应
+func TestIdempotencyKeyRejectsDifferentQuery(t *testing.T) {
+	testutil.OpenDB(t)
+	user := testutil.CreateUser(t, "alice")
+
+	var dryRuns []string
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/import", func(c *gin.Context) { c.Set("user", user) }, Idempotency(), func(c *gin.Context) {
+		dryRuns = append(dryRuns, c.Query("dry_run"))
+		c.JSON(http.StatusOK, gin.H{"dry_run": c.Query("dry_run")})
+	})
+
+	post := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, bytes.NewReader([]byte("[]")))
+		req.Header.Set("Idempotency-Key", "import")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := post("/import?dry_run=true"); w.Code != http.StatusOK {
+		t.Fatalf("dry run: status %d: %s", w.Code, w.Body)
+	}
+	if w := post("/import?dry_run=false"); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("same key with a different query: status %d, want 422", w.Code)
+	}
+	if w := post("/import?dry_run=true"); w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry with the same query was not replayed: %d %s", w.Code, w.Body)
+	}
+	if len(dryRuns) != 1 {
+		t.Errorf("handler ran for %v, want only the first dry run", dryRuns)
+	}
+}
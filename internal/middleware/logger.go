@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 日志中需要隐去取值的查询参数
+var sensitiveQuery = regexp.MustCompile(`([?&](?:access_token|token|code)=)[^&]*`)
+
+// Logger 与 gin 默认的访问日志格式相同，但隐去查询参数中的 token，避免凭据以明文写入日志
+func Logger() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+		return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+			param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			param.StatusCode,
+			param.Latency,
+			param.ClientIP,
+			param.Method,
+			redactQuery(param.Path),
+			param.ErrorMessage,
+		)
+	})
+}
+
+// redactQuery 把路径中敏感查询参数的值替换为 REDACTED
+func redactQuery(path string) string {
+	return sensitiveQuery.ReplaceAllString(path, "${1}REDACTED")
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	models "myproject/internal/model"
+	"myproject/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission 当前用户的角色或单独授予的权限中必须包含 perm，需放在认证中间件之后
+func RequirePermission(perm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !hasPermission(c, perm) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "没有权限", "required_permission": perm})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequirePermissions 按请求方法检查权限：GET、HEAD 需要 read，其余需要 write
+func RequirePermissions(read, write string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		perm := write
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			perm = read
+		}
+		if !hasPermission(c, perm) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "没有权限", "required_permission": perm})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func hasPermission(c *gin.Context, perm string) bool {
+	user, ok := c.Get("user")
+	if !ok {
+		return false
+	}
+	return service.HasPermission(user.(models.User), perm)
+}
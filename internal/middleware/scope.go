@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"myproject/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireScope 个人访问令牌必须带有 scope 权限才能访问；登录会话的 JWT 拥有全部权限
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !hasScope(c, scope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "访问令牌权限不足", "required_scope": scope})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireScopes 按请求方法检查权限：GET、HEAD 需要 read，其余需要 write
+func RequireScopes(read, write string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scope := write
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			scope = read
+		}
+		if !hasScope(c, scope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "访问令牌权限不足", "required_scope": scope})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// SessionOnly 只允许登录会话访问，个人访问令牌不能管理账号、会话和令牌
+func SessionOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("scopes"); ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": "个人访问令牌不能访问该接口"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func hasScope(c *gin.Context, scope string) bool {
+	scopes, ok := c.Get("scopes")
+	if !ok {
+		return true
+	}
+	return service.HasScope(scopes.([]string), scope)
+}
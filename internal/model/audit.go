@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// LoginAttempt 登录失败计数，键为用户名或 IP
+type LoginAttempt struct {
+    Key          string    `json:"key" gorm:"column:throttle_key;primaryKey;size:191"`
+    Failures     int       `json:"failures"`
+    LastFailedAt time.Time `json:"last_failed_at"`
+    ExpiresAt    time.Time `json:"expires_at" gorm:"index"`
+}
+
+// AuditLog 安全相关操作的审计记录
+type AuditLog struct {
+    ID        uint64    `json:"id" gorm:"primaryKey"`
+    UserID    *uint     `json:"user_id,omitempty" gorm:"index"` // 操作者，未登录时为空
+    Action    string    `json:"action" gorm:"size:64;index"`
+    Target    string    `json:"target" gorm:"size:255"` // 操作对象，例如尝试登录的用户名
+    IP        string    `json:"ip" gorm:"size:64"`
+    UserAgent string    `json:"user_agent" gorm:"size:255"`
+    Detail    string    `json:"detail,omitempty" gorm:"type:text"`
+    CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// SendTimeLayout 摘要发送时间的格式
+const SendTimeLayout = "15:04"
+
+// DigestSetting 每日摘要邮件设置，SendTime 为用户时区下的 HH:MM
+type DigestSetting struct {
+    ID        uint      `json:"id" gorm:"primaryKey"`
+    UserID    uint      `json:"user_id" gorm:"uniqueIndex"`
+    User      User      `json:"-" gorm:"foreignKey:UserID"`
+    Enabled   bool      `json:"enabled"`
+    SendTime  string    `json:"send_time" gorm:"size:5;default:08:00"`
+    CreatedAt time.Time `json:"created_at"`
+    UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SendMinute 返回发送时间是一天中的第几分钟，格式错误时 ok 为 false
+func (s *DigestSetting) SendMinute() (minute int, ok bool) {
+    t, err := time.Parse(SendTimeLayout, s.SendTime)
+    if err != nil {
+        return 0, false
+    }
+    return t.Hour()*60 + t.Minute(), true
+}
+
+// DigestLog 摘要发送记录，每个用户每天（用户时区）只有一条
+type DigestLog struct {
+    ID        uint       `json:"id" gorm:"primaryKey"`
+    UserID    uint       `json:"user_id" gorm:"uniqueIndex:idx_digest_user_date"`
+    Date      string     `json:"date" gorm:"size:10;uniqueIndex:idx_digest_user_date"` // YYYY-MM-DD
+    Status    string     `json:"status" gorm:"size:16"`                                // sending, sent
+    SentAt    *time.Time `json:"sent_at,omitempty"`
+    // 发送中的记录由 LeaseOwner 持有到 LeaseUntil，进程在发送途中退出时租约过期，其他实例可以接手重试
+    LeaseOwner string     `json:"-" gorm:"size:128"`
+    LeaseUntil *time.Time `json:"-"`
+    CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// EventLog 推送给客户端的任务事件，ID 即 SSE 的事件 ID，用于断线后按 Last-Event-ID 续传。
+// 只保留最近一段时间的记录。
+type EventLog struct {
+    ID        uint64    `json:"id" gorm:"primaryKey"`
+    UserID    uint      `json:"user_id" gorm:"index"`
+    Type      string    `json:"type" gorm:"size:32"`
+    Payload   string    `json:"payload" gorm:"type:text"`
+    CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
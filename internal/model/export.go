@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// DataExport 个人数据导出任务，由后台生成 ZIP 文件，过期后文件和记录一并删除
+type DataExport struct {
+    ID          uint       `json:"id" gorm:"primaryKey"`
+    UserID      uint       `json:"-" gorm:"index"`
+    Status      string     `json:"status" gorm:"size:16;default:pending;index"` // pending, ready, failed
+    FilePath    string     `json:"-" gorm:"size:512"`
+    Size        int64      `json:"size,omitempty"`
+    LastError   string     `json:"-" gorm:"size:512"`
+    LeaseOwner  string     `json:"-" gorm:"size:128"`
+    LeaseUntil  *time.Time `json:"-"`
+    CompletedAt *time.Time `json:"completed_at,omitempty"`
+    ExpiresAt   *time.Time `json:"expires_at,omitempty" gorm:"index"` // 生成完成后开始计时
+    CreatedAt   time.Time  `json:"created_at"`
+    UpdatedAt   time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// IdempotencyKey 客户端通过 Idempotency-Key 请求头提交的幂等键及首次请求的响应
+type IdempotencyKey struct {
+    ID              uint              `json:"id" gorm:"primaryKey"`
+    UserID          uint              `json:"user_id" gorm:"uniqueIndex:idx_idempotency_user_key"`
+    Key             string            `json:"key" gorm:"column:request_key;size:191;uniqueIndex:idx_idempotency_user_key"`
+    Fingerprint     string            `json:"fingerprint" gorm:"size:64"` // 请求方法、路径和请求体的 SHA-256
+    Status          string            `json:"status" gorm:"size:16"`      // processing, completed
+    ResponseStatus  int               `json:"response_status"`
+    ResponseHeaders map[string]string `json:"response_headers" gorm:"type:text;serializer:json"`
+    ResponseBody    []byte            `json:"-"` // 不指定列类型，MySQL 为 longblob，PostgreSQL 为 bytea
+    LockedUntil     time.Time         `json:"locked_until"` // 处理中的请求超过该时间仍未完成，视为已中断
+    ExpiresAt       time.Time         `json:"expires_at" gorm:"index"`
+    CreatedAt       time.Time         `json:"created_at"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// UserIdentity 用户在外部身份提供方的账号，按 provider + subject 唯一
+type UserIdentity struct {
+    ID        uint      `json:"id" gorm:"primaryKey"`
+    UserID    uint      `json:"user_id" gorm:"index"`
+    Provider  string    `json:"provider" gorm:"size:64;uniqueIndex:idx_identity_subject,priority:1"`
+    Subject   string    `json:"subject" gorm:"size:191;uniqueIndex:idx_identity_subject,priority:2"`
+    Email     string    `json:"email" gorm:"size:255"`
+    CreatedAt time.Time `json:"created_at"`
+    UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OAuthState 一次进行中的外部登录，保存 state 对应的 nonce 和 PKCE code_verifier，只能使用一次
+type OAuthState struct {
+    State        string    `json:"state" gorm:"primaryKey;size:64"`
+    Provider     string    `json:"provider" gorm:"size:64"`
+    Nonce        string    `json:"-" gorm:"size:64"`
+    CodeVerifier string    `json:"-" gorm:"size:128"`
+    ExpiresAt    time.Time `json:"expires_at" gorm:"index"`
+    CreatedAt    time.Time `json:"created_at"`
+}
@@ -0,0 +1,11 @@
+package models
+
+// All 返回需要自动迁移的全部模型
+func All() []interface{} {
+	return []interface{}{
+		&User{}, &Task{}, &Tag{}, &Template{}, &Reminder{}, &Notification{}, &DigestSetting{}, &DigestLog{},
+		&Webhook{}, &WebhookDelivery{}, &EventLog{}, &TaskChange{}, &SyncCounter{}, &IdempotencyKey{}, &Session{},
+		&RefreshToken{}, &RevokedToken{}, &ActionToken{}, &RecoveryCode{}, &PersonalAccessToken{},
+		&UserIdentity{}, &OAuthState{}, &LoginAttempt{}, &AuditLog{}, &DataExport{},
+	}
+}
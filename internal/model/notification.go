@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Notification 站内通知
+type Notification struct {
+    ID          uint       `json:"id" gorm:"primaryKey"`
+    UserID      uint       `json:"user_id" gorm:"index"`
+    TaskID      *uint      `json:"task_id,omitempty"`
+    ReminderID  *uint      `json:"reminder_id,omitempty" gorm:"uniqueIndex"` // 同一提醒只生成一条通知
+    Title       string     `json:"title" gorm:"size:255"`
+    Body        string     `json:"body"`
+    DismissedAt *time.Time `json:"dismissed_at,omitempty"`
+    CreatedAt   time.Time  `json:"created_at"`
+}
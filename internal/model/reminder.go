@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Reminder 任务提醒，可以是绝对时间，也可以是截止时间前的偏移
+type Reminder struct {
+    ID            uint       `json:"id" gorm:"primaryKey"`
+    TaskID        uint       `json:"task_id" gorm:"index"`
+    Task          Task       `json:"-" gorm:"foreignKey:TaskID"`
+    UserID        uint       `json:"user_id" gorm:"index"`
+    User          User       `json:"-" gorm:"foreignKey:UserID"`
+    RemindAt      *time.Time `json:"remind_at,omitempty"`      // 绝对提醒时间
+    OffsetMinutes *int       `json:"offset_minutes,omitempty"` // 截止时间前多少分钟提醒
+    Channel       string     `json:"channel" gorm:"size:16;default:in_app"` // in_app, email
+    FireAt        time.Time  `json:"fire_at" gorm:"index:idx_reminder_due,priority:2"`
+    Status        string     `json:"status" gorm:"size:16;default:pending;index:idx_reminder_due,priority:1"` // pending, sent, failed
+    Attempts      int        `json:"attempts"`
+    LastError     string     `json:"last_error,omitempty" gorm:"size:512"`
+    LeaseOwner    string     `json:"-" gorm:"size:128"`
+    LeaseUntil    *time.Time `json:"-"`
+    SentAt        *time.Time `json:"sent_at,omitempty"`
+    CreatedAt     time.Time  `json:"created_at"`
+    UpdatedAt     time.Time  `json:"updated_at"`
+}
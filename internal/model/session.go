@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Session 一次登录产生的会话，刷新令牌和访问令牌都归属于会话，吊销会话后它们一并失效
+type Session struct {
+    ID         uint       `json:"id" gorm:"primaryKey"`
+    UserID     uint       `json:"-" gorm:"index"`
+    DeviceName string     `json:"device_name" gorm:"size:100"`
+    UserAgent  string     `json:"user_agent" gorm:"size:255"`
+    IP         string     `json:"ip" gorm:"size:64"`
+    LastSeenAt time.Time  `json:"last_seen_at"`
+    ExpiresAt  time.Time  `json:"expires_at" gorm:"index"` // 随刷新令牌续期
+    RevokedAt  *time.Time `json:"-"`
+    Current    bool       `json:"current" gorm:"-"` // 是否为发起请求的会话
+    CreatedAt  time.Time  `json:"created_at"`
+}
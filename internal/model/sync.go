@@ -0,0 +1,89 @@
+package models
+
+import (
+    "database/sql/driver"
+    "encoding/json"
+    "fmt"
+    "time"
+)
+
+// SyncFields 离线同步时按字段合并的任务字段
+var SyncFields = []string{"description", "status", "priority", "due_date", "recurrence", "tags"}
+
+// FieldStamp 字段最后一次修改时任务的版本号和修改时间
+type FieldStamp struct {
+    Revision  uint64    `json:"rev"`
+    UpdatedAt time.Time `json:"at"`
+}
+
+// FieldStamps 字段名 -> 最后一次修改
+type FieldStamps map[string]FieldStamp
+
+func (s FieldStamps) Value() (driver.Value, error) {
+    if s == nil {
+        return "{}", nil
+    }
+    b, err := json.Marshal(s)
+    return string(b), err
+}
+
+func (s *FieldStamps) Scan(value interface{}) error {
+    var b []byte
+    switch v := value.(type) {
+    case nil:
+        *s = nil
+        return nil
+    case []byte:
+        b = v
+    case string:
+        b = []byte(v)
+    default:
+        return fmt.Errorf("unsupported type %T for FieldStamps", value)
+    }
+    if len(b) == 0 {
+        *s = nil
+        return nil
+    }
+    return json.Unmarshal(b, s)
+}
+
+// TaskChange 任务变更日志。Seq 全局单调递增，作为同步令牌；Deleted 为 true 时是删除墓碑。
+// 仓储层的每条任务写入路径都会在同一事务中追加一条记录，Seq 从 SyncCounter 分配。
+type TaskChange struct {
+    Seq       uint64    `json:"seq" gorm:"primaryKey;autoIncrement:false;index:idx_task_change_user_seq,priority:2"`
+    UserID    uint      `json:"user_id" gorm:"index:idx_task_change_user_seq,priority:1"`
+    TaskID    uint      `json:"task_id"`
+    Deleted   bool      `json:"deleted"`
+    CreatedAt time.Time `json:"created_at"`
+}
+
+// SyncCounter 变更序号计数器。写入事务递增计数器时持有该行的锁直到提交，
+// 因此序号的提交顺序与大小顺序一致，不会出现较小的序号晚于较大的序号提交
+type SyncCounter struct {
+    Name  string `gorm:"primaryKey;size:64"`
+    Value uint64 `gorm:"not null"`
+}
+
+// Clone 返回副本，nil 的副本仍为 nil
+func (s FieldStamps) Clone() FieldStamps {
+    if s == nil {
+        return nil
+    }
+    stamps := make(FieldStamps, len(s))
+    for k, v := range s {
+        stamps[k] = v
+    }
+    return stamps
+}
+
+// Stamp 返回记录了 fields 在 revision 版本、at 时刻被修改后的副本
+func (s FieldStamps) Stamp(fields []string, revision uint64, at time.Time) FieldStamps {
+    stamps := make(FieldStamps, len(s)+len(fields))
+    for k, v := range s {
+        stamps[k] = v
+    }
+    for _, field := range fields {
+        stamps[field] = FieldStamp{Revision: revision, UpdatedAt: at}
+    }
+    return stamps
+}
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+type Tag struct {
+    ID        uint      `json:"id" gorm:"primaryKey"`
+    Name      string    `json:"name" gorm:"size:64;not null;uniqueIndex:idx_tag_user_name"`
+    UserID    uint      `json:"user_id" gorm:"uniqueIndex:idx_tag_user_name"`
+    CreatedAt time.Time `json:"created_at"`
+}
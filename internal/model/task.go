@@ -3,12 +3,21 @@ package models
 import "time"
 
 type Task struct {
-    ID          uint      `json:"id" gorm:"primaryKey"`
+    ID          uint       `json:"id" gorm:"primaryKey"`
     // Title       string    `json:"title" gorm:"not null"`
-    Description string    `json:"description"`
-    Status      string    `json:"status" gorm:"default:pending"` // pending, done
-    UserID      uint      `json:"user_id" gorm:"index"`
-    User        User      `json:"user,omitempty" gorm:"foreignKey:UserID"`
-    CreatedAt   time.Time `json:"created_at"`
-    UpdatedAt   time.Time `json:"updated_at"`
-}
\ No newline at end of file
+    Description string     `json:"description"`
+    Status      string     `json:"status" gorm:"default:pending"` // pending, done
+    Priority    string     `json:"priority" gorm:"size:16"`       // high, medium, low，空表示无
+    DueDate     *time.Time `json:"due_date"`
+    CompletedAt *time.Time `json:"completed_at,omitempty"`
+    Recurrence  string     `json:"recurrence" gorm:"size:255"` // RFC 5545 RRULE，例如 FREQ=WEEKLY;BYDAY=FR
+    Tags        []Tag      `json:"tags,omitempty" gorm:"many2many:task_tags"`
+    ParentID    *uint      `json:"parent_id,omitempty" gorm:"index"` // 父任务，用于模板生成的任务树
+    ExternalID  *string    `json:"external_id,omitempty" gorm:"size:191;uniqueIndex:idx_task_user_external"` // 导入时的外部ID，用于幂等重复导入
+    Revision    uint64     `json:"revision" gorm:"not null;default:0"` // 每次写入加一
+    FieldStamps FieldStamps `json:"-" gorm:"type:text"`               // 各字段最后一次修改的版本和时间，用于同步时按字段合并
+    UserID      uint       `json:"user_id" gorm:"index;uniqueIndex:idx_task_user_external"`
+    User        User       `json:"user,omitempty" gorm:"foreignKey:UserID"`
+    CreatedAt   time.Time  `json:"created_at"`
+    UpdatedAt   time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Template 可复用的任务模板，Items 是一棵任务树，整体以 JSON 保存
+type Template struct {
+    ID          uint           `json:"id" gorm:"primaryKey"`
+    Name        string         `json:"name" gorm:"size:128;not null"`
+    Description string         `json:"description"`
+    Items       []TemplateItem `json:"items" gorm:"type:text;serializer:json"`
+    Variables   []string       `json:"variables" gorm:"type:text;serializer:json"` // 模板中出现的 {{变量}}
+    UserID      uint           `json:"user_id" gorm:"index"`
+    CreatedAt   time.Time      `json:"created_at"`
+    UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// TemplateItem 模板中的一个任务，文本中可以使用 {{name}} 形式的变量
+type TemplateItem struct {
+    Description      string         `json:"description"`
+    Priority         string         `json:"priority,omitempty"`
+    Recurrence       string         `json:"recurrence,omitempty"`
+    Tags             []string       `json:"tags,omitempty"`
+    DueOffsetMinutes *int64         `json:"due_offset_minutes,omitempty"` // 相对锚定日期的截止时间偏移，空表示没有截止时间
+    Children         []TemplateItem `json:"children,omitempty"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// RefreshToken 刷新令牌，只保存 SHA-256 摘要。每次刷新都会换发新令牌，
+// 同一次登录换发出的令牌属于同一个会话，已换发的令牌再次出现时整个会话都会被吊销
+type RefreshToken struct {
+    ID        uint       `json:"id" gorm:"primaryKey"`
+    UserID    uint       `json:"user_id" gorm:"index"`
+    SessionID uint       `json:"session_id" gorm:"index"`
+    TokenHash string     `json:"-" gorm:"size:64;uniqueIndex"`
+    ExpiresAt time.Time  `json:"expires_at" gorm:"index"`
+    RotatedAt *time.Time `json:"rotated_at,omitempty"` // 已换发新令牌的时间
+    RevokedAt *time.Time `json:"revoked_at,omitempty"`
+    CreatedAt time.Time  `json:"created_at"`
+}
+
+// RevokedToken 已注销但尚未过期的访问令牌（jti 黑名单）
+type RevokedToken struct {
+    JTI       string    `json:"jti" gorm:"primaryKey;size:64"`
+    ExpiresAt time.Time `json:"expires_at" gorm:"index"`
+}
+
+// ActionToken 邮件链接中一次性令牌的使用记录，令牌本身是签名的 JWT，这里按 jti 保证只能使用一次
+type ActionToken struct {
+    JTI       string     `json:"jti" gorm:"primaryKey;size:64"`
+    UserID    uint       `json:"user_id" gorm:"index:idx_action_token_user,priority:1"`
+    Purpose   string     `json:"purpose" gorm:"size:32;index:idx_action_token_user,priority:2"` // verify_email, reset_password, mfa_login, oidc_login
+    ExpiresAt time.Time  `json:"expires_at" gorm:"index"`
+    UsedAt    *time.Time `json:"used_at,omitempty"`
+    Attempts  int        `json:"attempts"` // 校验失败次数，达到上限后令牌作废
+    CreatedAt time.Time  `json:"created_at"`
+}
+
+// PersonalAccessToken 用户为脚本创建的个人访问令牌，只保存 SHA-256 摘要，明文只在创建时返回一次
+type PersonalAccessToken struct {
+    ID          uint       `json:"id" gorm:"primaryKey"`
+    UserID      uint       `json:"-" gorm:"index"`
+    Name        string     `json:"name" gorm:"size:100;not null"`
+    TokenPrefix string     `json:"token_prefix" gorm:"size:16"` // 明文的前几位，便于用户辨认
+    TokenHash   string     `json:"-" gorm:"size:64;uniqueIndex"`
+    Scopes      []string   `json:"scopes" gorm:"type:text;serializer:json"`
+    ExpiresAt   *time.Time `json:"expires_at"` // 为空表示永不过期
+    LastUsedAt  *time.Time `json:"last_used_at"`
+    CreatedAt   time.Time  `json:"created_at"`
+}
@@ -7,12 +7,22 @@ import (
 )
 
 type User struct {
-    ID        uint      `json:"id" gorm:"primaryKey"`
-    Username  string    `json:"username" gorm:"unique;not null"`
-    Password  string    `json:"-"`  // 不返回给前端
-    Email     string    `json:"email" gorm:"unique;not null"`
-    CreatedAt time.Time `json:"created_at"`
-    UpdatedAt time.Time `json:"updated_at"`
+    ID              uint       `json:"id" gorm:"primaryKey"`
+    Username        string     `json:"username" gorm:"unique;not null"`
+    Password        string     `json:"-"`  // 不返回给前端；只通过外部身份提供方登录的用户为空
+    Email           string     `json:"email" gorm:"unique;not null"`
+    EmailVerified   bool       `json:"email_verified" gorm:"not null;default:false"`
+    PendingEmail    string     `json:"pending_email,omitempty" gorm:"size:255"` // 待确认的新邮箱，确认前仍使用原邮箱
+    TimeZone        string     `json:"time_zone" gorm:"size:64"` // IANA 时区，例如 Asia/Shanghai，空表示服务器时区
+    Role            string     `json:"role" gorm:"size:32;not null;default:user;index"` // user, admin，对应的权限见 service.RolePermissions
+    Permissions     []string   `json:"permissions,omitempty" gorm:"type:text;serializer:json"` // 角色之外额外授予的权限
+    DisabledAt      *time.Time `json:"disabled_at,omitempty" gorm:"index"` // 被管理员禁用的时间，禁用期间不能登录
+    TokensRevokedAt *time.Time `json:"-"` // 退出全部设备的时间，此前签发的访问令牌全部失效
+    TOTPSecret      string     `json:"-" gorm:"size:64"` // base32 编码的 TOTP 密钥，确认前处于待启用状态
+    TOTPEnabled     bool       `json:"totp_enabled" gorm:"not null;default:false"`
+    TOTPLastStep    int64      `json:"-"` // 最近一次通过校验的时间步，同一时间步的验证码不能再次使用
+    CreatedAt       time.Time  `json:"created_at"`
+    UpdatedAt       time.Time  `json:"updated_at"`
 }
 
 // 密码加密
@@ -25,8 +35,28 @@ func (u *User) HashPassword() error {
     return nil
 }
 
-// 验证密码
+// 验证密码，没有设置密码的用户一律不通过
 func (u *User) CheckPassword(password string) bool {
+    if !u.HasPassword() {
+        return false
+    }
     err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
     return err == nil
-}
\ No newline at end of file
+}
+
+// HasPassword 是否设置了密码
+func (u *User) HasPassword() bool {
+    return u.Password != ""
+}
+
+// Location 返回用户所在时区，未设置或无效时使用服务器时区
+func (u *User) Location() *time.Location {
+    if u.TimeZone == "" {
+        return time.Local
+    }
+    loc, err := time.LoadLocation(u.TimeZone)
+    if err != nil {
+        return time.Local
+    }
+    return loc
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Webhook 用户注册的事件回调地址
+type Webhook struct {
+    ID        uint      `json:"id" gorm:"primaryKey"`
+    UserID    uint      `json:"user_id" gorm:"index"`
+    URL       string    `json:"url" gorm:"size:2048;not null"`
+    Secret    string    `json:"-" gorm:"size:64;not null"` // HMAC-SHA256 签名密钥
+    Events    []string  `json:"events" gorm:"type:text;serializer:json"`
+    Active    bool      `json:"active" gorm:"default:true"`
+    CreatedAt time.Time `json:"created_at"`
+    UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery 一次投递，同时作为持久化的发送队列
+type WebhookDelivery struct {
+    ID             uint       `json:"id" gorm:"primaryKey"`
+    WebhookID      uint       `json:"webhook_id" gorm:"index"`
+    Webhook        Webhook    `json:"-" gorm:"foreignKey:WebhookID"`
+    Event          string     `json:"event" gorm:"size:32"`
+    Payload        string     `json:"payload" gorm:"type:text"`
+    Status         string     `json:"status" gorm:"size:16;default:pending;index:idx_delivery_due,priority:1"` // pending, succeeded, failed
+    Attempts       int        `json:"attempts"`
+    NextAttemptAt  time.Time  `json:"next_attempt_at" gorm:"index:idx_delivery_due,priority:2"`
+    ResponseStatus int        `json:"response_status,omitempty"`
+    LastError      string     `json:"last_error,omitempty" gorm:"size:512"`
+    LeaseOwner     string     `json:"-" gorm:"size:128"`
+    LeaseUntil     *time.Time `json:"-"`
+    DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+    CreatedAt      time.Time  `json:"created_at"`
+    UpdatedAt      time.Time  `json:"updated_at"`
+}
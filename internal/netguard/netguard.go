@@ -0,0 +1,101 @@
+// Package netguard 限制服务端代用户发出的请求（例如 webhook）只能访问公网地址，
+// 防止通过回调地址探测或读取内网、本机和云元数据服务。
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateAddress 目标地址不是公网地址
+var ErrPrivateAddress = errors.New("destination address is not public")
+
+// ErrRedirect 拒绝跟随重定向
+var ErrRedirect = errors.New("redirects are not followed")
+
+// 标准库的判断之外还需要排除的地址段
+var reserved = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // 本网络
+	netip.MustParsePrefix("100.64.0.0/10"), // 运营商级 NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF 协议分配
+	netip.MustParsePrefix("198.18.0.0/15"), // 基准测试
+	netip.MustParsePrefix("240.0.0.0/4"),   // 保留，含广播地址
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64，可映射到任意 IPv4 地址
+}
+
+// IsPublic 判断地址是否可以访问：排除回环、私有、链路本地（含 169.254.169.254 元数据服务）、
+// 组播、未指定和保留地址。IPv4 映射的 IPv6 地址按 IPv4 判断
+func IsPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range reserved {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckHost 在保存地址时做初步检查：拒绝 localhost 和非公网的 IP 字面量。
+// 域名在每次拨号时才解析，由 NewClient 的拨号检查兜底
+func CheckHost(host string) error {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrPrivateAddress
+	}
+	if addr, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil && !IsPublic(addr) {
+		return ErrPrivateAddress
+	}
+	return nil
+}
+
+// NewClient 返回只访问公网地址的 HTTP 客户端。检查放在拨号时对解析后的 IP 进行，
+// DNS 重绑定无法绕过；不使用环境变量中的代理，不跟随重定向
+func NewClient(timeout time.Duration) *http.Client {
+	return newClient(timeout, IsPublic)
+}
+
+func newClient(timeout time.Duration, allowed func(netip.Addr) bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("%w: %s", ErrPrivateAddress, address)
+			}
+			if !allowed(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", ErrPrivateAddress, addrPort.Addr())
+			}
+			return nil
+		},
+	}
+	transport := &http.Transport{
+		Proxy: nil,
+		DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, address)
+		},
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return ErrRedirect
+		},
+	}
+}
@@ -0,0 +1,87 @@
+package netguard
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"100.64.0.1", false},
+		{"224.0.0.1", false},
+		{"255.255.255.255", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:169.254.169.254", false},
+		{"64:ff9b::a9fe:a9fe", false},
+	}
+	for _, tt := range tests {
+		if got := IsPublic(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("IsPublic(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	for _, host := range []string{"localhost", "LOCALHOST.", "api.localhost", "127.0.0.1", "[::1]", "169.254.169.254", "10.0.0.8"} {
+		if err := CheckHost(host); !errors.Is(err, ErrPrivateAddress) {
+			t.Errorf("CheckHost(%q) = %v, want ErrPrivateAddress", host, err)
+		}
+	}
+	// 域名在拨号时检查
+	for _, host := range []string{"example.com", "93.184.216.34", "internal.example"} {
+		if err := CheckHost(host); err != nil {
+			t.Errorf("CheckHost(%q) = %v", host, err)
+		}
+	}
+}
+
+func TestClientRefusesPrivateAddresses(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true }))
+	defer server.Close()
+
+	_, err := NewClient(time.Second).Get(server.URL)
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("request to %s: err = %v, want ErrPrivateAddress", server.URL, err)
+	}
+	if called {
+		t.Error("request reached the loopback server")
+	}
+}
+
+func TestClientDoesNotFollowRedirects(t *testing.T) {
+	internal := false
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { internal = true }))
+	defer target.Close()
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer redirect.Close()
+
+	// 允许回环地址，只验证重定向
+	client := newClient(time.Second, func(netip.Addr) bool { return true })
+	_, err := client.Get(redirect.URL)
+	if !errors.Is(err, ErrRedirect) {
+		t.Errorf("err = %v, want ErrRedirect", err)
+	}
+	if internal {
+		t.Error("redirect was followed")
+	}
+}
@@ -0,0 +1,28 @@
+package notify
+
+import (
+	"context"
+
+	"myproject/internal/mail"
+)
+
+// EmailChannel 通过邮件投递通知
+type EmailChannel struct {
+	mailer mail.Mailer
+}
+
+func NewEmailChannel(mailer mail.Mailer) *EmailChannel {
+	return &EmailChannel{mailer: mailer}
+}
+
+func (c *EmailChannel) Name() string {
+	return ChannelEmail
+}
+
+func (c *EmailChannel) Send(ctx context.Context, msg Message) error {
+	return c.mailer.Send(ctx, mail.Message{
+		To:      msg.Email,
+		Subject: msg.Title,
+		Text:    msg.Body,
+	})
+}
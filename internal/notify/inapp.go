@@ -0,0 +1,37 @@
+package notify
+
+import (
+	"context"
+
+	models "myproject/internal/model"
+	"myproject/internal/repository"
+)
+
+// InAppChannel 写入站内通知，用户通过 /notifications 查看
+type InAppChannel struct{}
+
+func NewInAppChannel() *InAppChannel {
+	return &InAppChannel{}
+}
+
+func (c *InAppChannel) Name() string {
+	return ChannelInApp
+}
+
+// Send 以 ReminderID 去重，同一提醒重复投递只会留下一条通知
+func (c *InAppChannel) Send(ctx context.Context, msg Message) error {
+	notification := models.Notification{
+		UserID: msg.UserID,
+		Title:  msg.Title,
+		Body:   msg.Body,
+	}
+	if msg.TaskID != 0 {
+		taskID := msg.TaskID
+		notification.TaskID = &taskID
+	}
+	if msg.ReminderID != 0 {
+		reminderID := msg.ReminderID
+		notification.ReminderID = &reminderID
+	}
+	return repository.CreateNotification(&notification)
+}
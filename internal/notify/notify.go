@@ -0,0 +1,36 @@
+// Package notify 定义提醒的投递渠道。
+package notify
+
+import (
+	"context"
+	"errors"
+)
+
+// 渠道名称，与 models.Reminder.Channel 对应
+const (
+	ChannelInApp = "in_app"
+	ChannelEmail = "email"
+)
+
+var ErrUnknownChannel = errors.New("unknown notification channel")
+
+// Message 一条待投递的通知
+type Message struct {
+	UserID     uint
+	Email      string
+	TaskID     uint
+	ReminderID uint
+	Title      string
+	Body       string
+}
+
+// Channel 通知渠道，Send 对同一 ReminderID 应尽量幂等
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, msg Message) error
+}
+
+// IsValidChannel 判断渠道名称是否合法
+func IsValidChannel(name string) bool {
+	return name == ChannelInApp || name == ChannelEmail
+}
@@ -0,0 +1,45 @@
+package oidc
+
+import (
+	"os"
+	"strings"
+)
+
+// Config 一个身份提供方的配置
+type Config struct {
+	Name          string // 路由中使用的名字，例如 /auth/google/login
+	Issuer        string // 用于发现 /.well-known/openid-configuration
+	ClientID      string
+	ClientSecret  string
+	RedirectURL   string
+	Scopes        []string
+	AutoProvision bool // 邮箱没有对应用户时是否自动创建
+}
+
+// ConfigsFromEnv 从环境变量读取提供方配置。OIDC_PROVIDERS 为逗号分隔的名字，
+// 每个提供方读取 OIDC_<NAME>_ISSUER、_CLIENT_ID、_CLIENT_SECRET、_REDIRECT_URL、
+// _SCOPES（空格分隔，默认 openid email profile）和 _AUTO_PROVISION
+func ConfigsFromEnv() []Config {
+	var configs []Config
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.TrimSpace(strings.ToLower(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		cfg := Config{
+			Name:          name,
+			Issuer:        os.Getenv(prefix + "ISSUER"),
+			ClientID:      os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret:  os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:   os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:        strings.Fields(os.Getenv(prefix + "SCOPES")),
+			AutoProvision: os.Getenv(prefix+"AUTO_PROVISION") == "true",
+		}
+		if len(cfg.Scopes) == 0 {
+			cfg.Scopes = []string{"openid", "email", "profile"}
+		}
+		configs = append(configs, cfg)
+	}
+	return configs
+}
@@ -0,0 +1,82 @@
+// Package plaintext 解析和生成纯文本任务清单：todo.txt 格式与 GitHub 风格的 Markdown 勾选列表。
+package plaintext
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// Item 纯文本清单中的一条任务
+type Item struct {
+	Line        int        // 所在行号，从 1 开始
+	Done        bool       // 是否已完成
+	Priority    string     // todo.txt 优先级 A-Z，空表示无
+	CompletedAt *time.Time // 完成日期
+	CreatedAt   *time.Time // 创建日期
+	DueDate     *time.Time // due: 截止日期
+	Projects    []string   // +project 标签
+	Contexts    []string   // @context 标签
+	Text        string     // 任务文本，保留 +project 和 @context
+}
+
+// ParseError 带行号的解析错误
+type ParseError struct {
+	Line int
+	Msg  string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// Reader 逐行读取清单，解析错误以 *ParseError 返回，调用方可以继续读取后续行
+type Reader struct {
+	scanner *bufio.Scanner
+	line    int
+	parse   func(line int, text string) (*Item, error)
+}
+
+// Read 返回下一条任务，结束时返回 io.EOF
+func (r *Reader) Read() (*Item, error) {
+	for r.scanner.Scan() {
+		r.line++
+		item, err := r.parse(r.line, r.scanner.Text())
+		if err != nil {
+			return nil, err
+		}
+		if item == nil {
+			continue
+		}
+		return item, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// Line 返回最近读取的行号
+func (r *Reader) Line() int {
+	return r.line
+}
+
+func newReader(r io.Reader, parse func(line int, text string) (*Item, error)) *Reader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	return &Reader{scanner: scanner, parse: parse}
+}
+
+func parseDate(s string) (*time.Time, bool) {
+	if len(s) != len(dateLayout) {
+		return nil, false
+	}
+	t, err := time.ParseInLocation(dateLayout, s, time.Local)
+	if err != nil {
+		return nil, false
+	}
+	return &t, true
+}
@@ -0,0 +1,56 @@
+package plaintext
+
+import (
+	"io"
+	"regexp"
+	"strings"
+)
+
+// 列表项：- / * / + 开头，后面可能跟勾选框
+var (
+	markdownListItem = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	markdownCheckbox = regexp.MustCompile(`^\[(.)\](?:\s+(.*))?$`)
+)
+
+// NewMarkdownReader 创建 Markdown 勾选列表读取器，标题、正文和普通列表项会被跳过
+func NewMarkdownReader(r io.Reader) *Reader {
+	return newReader(r, ParseMarkdownLine)
+}
+
+// ParseMarkdownLine 解析一行 GitHub 风格的勾选列表项（- [ ] / - [x]），非勾选列表行返回 nil。
+// 以链接开头的普通列表项（- [文档](url)）同样不是勾选框
+func ParseMarkdownLine(line int, text string) (*Item, error) {
+	m := markdownListItem.FindStringSubmatch(text)
+	if m == nil {
+		return nil, nil
+	}
+
+	box := markdownCheckbox.FindStringSubmatch(strings.TrimSpace(m[1]))
+	if box == nil {
+		return nil, nil
+	}
+
+	item := &Item{Line: line}
+	switch box[1] {
+	case " ":
+	case "x", "X":
+		item.Done = true
+	default:
+		return nil, &ParseError{Line: line, Msg: "invalid checkbox state " + box[1]}
+	}
+
+	item.Text = strings.TrimSpace(box[2])
+	if item.Text == "" {
+		return nil, &ParseError{Line: line, Msg: "empty task"}
+	}
+	return item, nil
+}
+
+// FormatMarkdown 将任务格式化为一行 Markdown 勾选列表项，不含换行符
+func FormatMarkdown(item Item) string {
+	box := "[ ]"
+	if item.Done {
+		box = "[x]"
+	}
+	return "- " + box + " " + strings.Join(strings.Fields(item.Text), " ")
+}
@@ -0,0 +1,44 @@
+package plaintext
+
+import "testing"
+
+func TestParseMarkdownLine(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    *Item
+		wantErr bool
+	}{
+		{text: "# Heading"},
+		{text: "Some paragraph"},
+		{text: "- plain item"},
+		{text: "- [docs](https://example.com)"},
+		{text: "* [x](https://example.com) link whose text is x"},
+		{text: "- [ ] Buy milk", want: &Item{Text: "Buy milk"}},
+		{text: "  + [X] Walk the dog", want: &Item{Done: true, Text: "Walk the dog"}},
+		{text: "- [ ]", wantErr: true},
+		{text: "- [?] Unknown state", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseMarkdownLine(1, tt.text)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected an error", tt.text)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.text, err)
+			continue
+		}
+		if tt.want == nil {
+			if got != nil {
+				t.Errorf("%q: got %+v, want the line to be skipped", tt.text, got)
+			}
+			continue
+		}
+		if got == nil || got.Done != tt.want.Done || got.Text != tt.want.Text {
+			t.Errorf("%q: got %+v, want %+v", tt.text, got, tt.want)
+		}
+	}
+}
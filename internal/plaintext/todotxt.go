@@ -0,0 +1,133 @@
+package plaintext
+
+import (
+	"io"
+	"strings"
+)
+
+// NewTodoTxtReader 创建 todo.txt 读取器，空行会被跳过
+func NewTodoTxtReader(r io.Reader) *Reader {
+	return newReader(r, ParseTodoTxtLine)
+}
+
+// ParseTodoTxtLine 解析一行 todo.txt，空行返回 nil
+//
+// 支持的语法：
+//
+//	x 2024-05-02 2024-05-01 已完成的任务 +project @context pri:A
+//	(A) 2024-05-01 未完成的任务 +project @context due:2024-05-10
+func ParseTodoTxtLine(line int, text string) (*Item, error) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	item := &Item{Line: line}
+
+	if fields[0] == "x" {
+		item.Done = true
+		fields = fields[1:]
+		if len(fields) > 0 {
+			if t, ok := parseDate(fields[0]); ok {
+				item.CompletedAt = t
+				fields = fields[1:]
+			}
+		}
+	} else if isPriority(fields[0]) {
+		item.Priority = fields[0][1:2]
+		fields = fields[1:]
+	}
+
+	if len(fields) > 0 {
+		if t, ok := parseDate(fields[0]); ok {
+			item.CreatedAt = t
+			fields = fields[1:]
+		}
+	}
+
+	words := make([]string, 0, len(fields))
+	for _, field := range fields {
+		key, value, isTag := strings.Cut(field, ":")
+		switch {
+		case isTag && key == "due":
+			t, ok := parseDate(value)
+			if !ok {
+				return nil, &ParseError{Line: line, Msg: "invalid due date " + value}
+			}
+			item.DueDate = t
+			continue
+		case isTag && key == "pri":
+			// 已完成任务按约定把优先级保存为 pri:A
+			if len(value) != 1 || value[0] < 'A' || value[0] > 'Z' {
+				return nil, &ParseError{Line: line, Msg: "invalid priority " + value}
+			}
+			item.Priority = value
+			continue
+		case len(field) > 1 && field[0] == '+':
+			item.Projects = append(item.Projects, field[1:])
+		case len(field) > 1 && field[0] == '@':
+			item.Contexts = append(item.Contexts, field[1:])
+		}
+		words = append(words, field)
+	}
+
+	item.Text = strings.Join(words, " ")
+	if item.Text == "" {
+		return nil, &ParseError{Line: line, Msg: "empty task"}
+	}
+	return item, nil
+}
+
+// FormatTodoTxt 将任务格式化为一行 todo.txt，不含换行符
+func FormatTodoTxt(item Item) string {
+	var parts []string
+	if item.Done {
+		parts = append(parts, "x")
+		if item.CompletedAt != nil {
+			parts = append(parts, item.CompletedAt.Format(dateLayout))
+		}
+	} else if item.Priority != "" {
+		parts = append(parts, "("+item.Priority+")")
+	}
+	// 已完成任务没有完成日期时，创建日期会被误读为完成日期，只能省略
+	if item.CreatedAt != nil && (!item.Done || item.CompletedAt != nil) {
+		parts = append(parts, item.CreatedAt.Format(dateLayout))
+	}
+
+	// todo.txt 一行一条任务，文本中的换行需要压平
+	parts = append(parts, strings.Join(strings.Fields(item.Text), " "))
+
+	// 文本中没有出现的 +project 和 @context 追加在末尾
+	words := strings.Fields(item.Text)
+	for _, project := range item.Projects {
+		if !containsWord(words, "+"+project) {
+			parts = append(parts, "+"+project)
+		}
+	}
+	for _, context := range item.Contexts {
+		if !containsWord(words, "@"+context) {
+			parts = append(parts, "@"+context)
+		}
+	}
+
+	if item.DueDate != nil {
+		parts = append(parts, "due:"+item.DueDate.Format(dateLayout))
+	}
+	if item.Done && item.Priority != "" {
+		parts = append(parts, "pri:"+item.Priority)
+	}
+	return strings.Join(parts, " ")
+}
+
+func containsWord(words []string, word string) bool {
+	for _, w := range words {
+		if w == word {
+			return true
+		}
+	}
+	return false
+}
+
+func isPriority(s string) bool {
+	return len(s) == 3 && s[0] == '(' && s[2] == ')' && s[1] >= 'A' && s[1] <= 'Z'
+}
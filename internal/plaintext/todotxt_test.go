@@ -0,0 +1,100 @@
+package plaintext
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func date(s string) *time.Time {
+	t, _ := time.ParseInLocation(dateLayout, s, time.Local)
+	return &t
+}
+
+func TestParseTodoTxtLine(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want *Item
+	}{
+		{"blank", "   ", nil},
+		{
+			name: "priority and dates",
+			text: "(A) 2024-05-01 Call mom +family @phone due:2024-05-10",
+			want: &Item{
+				Priority:  "A",
+				CreatedAt: date("2024-05-01"),
+				DueDate:   date("2024-05-10"),
+				Projects:  []string{"family"},
+				Contexts:  []string{"phone"},
+				Text:      "Call mom +family @phone",
+			},
+		},
+		{
+			name: "completed with both dates",
+			text: "x 2024-05-02 2024-05-01 Pay rent pri:B",
+			want: &Item{
+				Done:        true,
+				Priority:    "B",
+				CompletedAt: date("2024-05-02"),
+				CreatedAt:   date("2024-05-01"),
+				Text:        "Pay rent",
+			},
+		},
+		{"lone sigils are text", "Use + and @ signs", &Item{Text: "Use + and @ signs"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTodoTxtLine(1, tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want != nil {
+				tt.want.Line = 1
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTodoTxtLineErrors(t *testing.T) {
+	for _, text := range []string{"task due:tomorrow", "task pri:AA", "(A) 2024-05-01"} {
+		if _, err := ParseTodoTxtLine(3, text); err == nil {
+			t.Errorf("%q: expected an error", text)
+		} else if perr, ok := err.(*ParseError); !ok || perr.Line != 3 {
+			t.Errorf("%q: got %v, want a *ParseError for line 3", text, err)
+		}
+	}
+}
+
+func TestFormatTodoTxtRoundTrip(t *testing.T) {
+	item := Item{
+		Done:        true,
+		Priority:    "A",
+		CompletedAt: date("2024-05-02"),
+		CreatedAt:   date("2024-05-01"),
+		DueDate:     date("2024-05-10"),
+		Projects:    []string{"family", "errands"},
+		Contexts:    []string{"phone"},
+		Text:        "Call mom +family",
+	}
+
+	line := FormatTodoTxt(item)
+	if want := "x 2024-05-02 2024-05-01 Call mom +family +errands @phone due:2024-05-10 pri:A"; line != want {
+		t.Fatalf("FormatTodoTxt = %q, want %q", line, want)
+	}
+
+	got, err := ParseTodoTxtLine(1, line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Done || got.Priority != "A" || !got.CompletedAt.Equal(*item.CompletedAt) || !got.CreatedAt.Equal(*item.CreatedAt) {
+		t.Errorf("lost status, priority or dates: %+v", got)
+	}
+	if !reflect.DeepEqual(got.Projects, item.Projects) || !reflect.DeepEqual(got.Contexts, item.Contexts) {
+		t.Errorf("projects = %v, contexts = %v", got.Projects, got.Contexts)
+	}
+}
@@ -0,0 +1,182 @@
+// Package quickadd 把一句自然语言解析成任务字段，例如
+// "Send report tomorrow 5pm #work !high every friday" 或 "明天下午5点开会 #工作 !高"。
+//
+// 解析按规则顺序进行，每条规则命中的文本会从标题中移除，剩余部分作为任务标题。
+// 没有上午、下午等修饰的中文 1–6 点按下午理解，"三点开会" 是 15:00。
+package quickadd
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 解析结果中各片段的类型
+const (
+	KindTag        = "tag"
+	KindPriority   = "priority"
+	KindRecurrence = "recurrence"
+	KindDate       = "date"
+	KindTime       = "time"
+)
+
+// Token 被识别出的一个片段
+type Token struct {
+	Kind  string `json:"kind"`
+	Text  string `json:"text"`
+	Value string `json:"value"`
+}
+
+// Result 解析结果
+type Result struct {
+	Title      string     `json:"title"`
+	DueDate    *time.Time `json:"due_date,omitempty"`
+	AllDay     bool       `json:"all_day"`
+	Tags       []string   `json:"tags"`
+	Priority   string     `json:"priority,omitempty"`   // high, medium, low
+	Recurrence string     `json:"recurrence,omitempty"` // RFC 5545 RRULE，例如 FREQ=WEEKLY;BYDAY=FR
+	Tokens     []Token    `json:"tokens"`
+}
+
+// clock 一天中的时刻
+type clock struct {
+	hour, minute int
+}
+
+type parser struct {
+	now    time.Time
+	input  string
+	masked []byte
+	result Result
+
+	date         *time.Time
+	clock        *clock
+	defaultClock *clock        // "tonight"、"明早" 等隐含的时刻，没有明确时刻时使用
+	evening      bool          // 日期表达式指明了晚上
+	morning      bool          // 日期表达式指明了早上（"明早"）
+	weekday      *time.Weekday // 来自 "every friday" 等重复规则，没有明确日期时用作截止日期
+
+	// 当前匹配前后尚未被占用的文本，供规则判断上下文
+	before, after string
+}
+
+type rule struct {
+	re    *regexp.Regexp
+	apply func(p *parser, m []string) (kind, value string, ok bool)
+}
+
+// Parse 以 now（决定时区和相对日期的基准）解析输入
+func Parse(input string, now time.Time) Result {
+	p := &parser{
+		now:    now,
+		input:  input,
+		masked: []byte(input),
+		result: Result{Tags: []string{}, Tokens: []Token{}},
+	}
+
+	for _, r := range rules {
+		p.applyRule(r)
+	}
+	p.resolveDueDate()
+
+	p.result.Title = strings.Join(strings.Fields(string(p.masked)), " ")
+	return p.result
+}
+
+// applyRule 在尚未被占用的文本上匹配规则，命中的部分用空格覆盖以保持下标不变
+func (p *parser) applyRule(r rule) {
+	for _, loc := range r.re.FindAllStringSubmatchIndex(string(p.masked), -1) {
+		m := make([]string, len(loc)/2)
+		for i := range m {
+			if loc[2*i] >= 0 {
+				m[i] = p.input[loc[2*i]:loc[2*i+1]]
+			}
+		}
+		p.before, p.after = string(p.masked[:loc[0]]), string(p.masked[loc[1]:])
+		kind, value, ok := r.apply(p, m)
+		if !ok {
+			continue
+		}
+		p.result.Tokens = append(p.result.Tokens, Token{
+			Kind:  kind,
+			Text:  strings.TrimSpace(m[0]),
+			Value: value,
+		})
+		for i := loc[0]; i < loc[1]; i++ {
+			p.masked[i] = ' '
+		}
+	}
+}
+
+func (p *parser) resolveDueDate() {
+	date := p.date
+	if p.clock == nil {
+		p.clock = p.defaultClock
+	}
+	if date == nil && p.weekday != nil {
+		d := nextWeekday(p.today(), *p.weekday)
+		date = &d
+	}
+
+	switch {
+	case date != nil && p.clock != nil:
+		due := time.Date(date.Year(), date.Month(), date.Day(), p.clock.hour, p.clock.minute, 0, 0, p.now.Location())
+		p.result.DueDate = &due
+	case date != nil:
+		p.result.DueDate = date
+		p.result.AllDay = true
+	case p.clock != nil:
+		// 只有时刻：今天还没过就是今天，否则是明天
+		today := p.today()
+		due := time.Date(today.Year(), today.Month(), today.Day(), p.clock.hour, p.clock.minute, 0, 0, p.now.Location())
+		if !due.After(p.now) {
+			due = due.AddDate(0, 0, 1)
+		}
+		p.result.DueDate = &due
+	}
+}
+
+func (p *parser) today() time.Time {
+	y, m, d := p.now.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, p.now.Location())
+}
+
+func (p *parser) setDate(t time.Time) (string, string, bool) {
+	if p.date != nil {
+		return "", "", false
+	}
+	p.date = &t
+	return KindDate, t.Format("2006-01-02"), true
+}
+
+func (p *parser) setClock(hour, minute int) (string, string, bool) {
+	if p.clock != nil || hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return "", "", false
+	}
+	p.clock = &clock{hour: hour, minute: minute}
+	return KindTime, time.Date(0, 1, 1, hour, minute, 0, 0, time.UTC).Format("15:04"), true
+}
+
+func (p *parser) setRecurrence(rrule string, weekday *time.Weekday) (string, string, bool) {
+	if p.result.Recurrence != "" {
+		return "", "", false
+	}
+	p.result.Recurrence = rrule
+	p.weekday = weekday
+	return KindRecurrence, rrule, true
+}
+
+// nextWeekday 返回从 from 开始（含当天）的下一个指定星期几
+func nextWeekday(from time.Time, wd time.Weekday) time.Time {
+	return from.AddDate(0, 0, (int(wd)-int(from.Weekday())+7)%7)
+}
+
+// weekStart 返回 t 所在周的周一
+func weekStart(t time.Time) time.Time {
+	return t.AddDate(0, 0, -((int(t.Weekday()) + 6) % 7))
+}
+
+// weekdayInWeek 返回以 monday 开始的那一周中的星期几
+func weekdayInWeek(monday time.Time, wd time.Weekday) time.Time {
+	return monday.AddDate(0, 0, (int(wd)+6)%7)
+}
@@ -0,0 +1,107 @@
+package quickadd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// 2024-05-08 是星期三
+var testNow = time.Date(2024, 5, 8, 10, 0, 0, 0, time.FixedZone("CST", 8*3600))
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input      string
+		title      string
+		due        string // 2006-01-02 15:04，空表示没有截止时间
+		allDay     bool
+		tags       []string
+		priority   string
+		recurrence string
+	}{
+		{input: "Send report tomorrow 5pm #work !high", title: "Send report", due: "2024-05-09 17:00", tags: []string{"work"}, priority: "high"},
+		{input: "Standup every friday", title: "Standup", due: "2024-05-10 00:00", allDay: true, recurrence: "FREQ=WEEKLY;BYDAY=FR"},
+		{input: "Pay rent on 5/10", title: "Pay rent", due: "2024-05-10 00:00", allDay: true},
+		{input: "Dinner tonight at 7:30", title: "Dinner", due: "2024-05-08 19:30"},
+		{input: "Review PR next mon", title: "Review PR", due: "2024-05-13 00:00", allDay: true},
+		{input: "Call mom sat", title: "Call mom", due: "2024-05-11 00:00", allDay: true},
+		{input: "Call mom sat 5pm", title: "Call mom", due: "2024-05-11 17:00"},
+		{input: "Submit form by fri", title: "Submit form", due: "2024-05-10 00:00", allDay: true},
+		{input: "Plan party Sunday", title: "Plan party", due: "2024-05-12 00:00", allDay: true},
+
+		// 普通单词不是星期
+		{input: "walk in the sun", title: "walk in the sun"},
+		{input: "Sat on the couch too long", title: "Sat on the couch too long"},
+		{input: "wed the couple", title: "wed the couple"},
+
+		{input: "明天下午5点开会 #工作 !高", title: "开会", due: "2024-05-09 17:00", tags: []string{"工作"}, priority: "high"},
+		{input: "今晚八点看电影", title: "看电影", due: "2024-05-08 20:00"},
+		{input: "下周五交周报", title: "交周报", due: "2024-05-17 00:00", allDay: true},
+		{input: "每周一例会", title: "例会", due: "2024-05-13 00:00", allDay: true, recurrence: "FREQ=WEEKLY;BYDAY=MO"},
+		{input: "3天后复查", title: "复查", due: "2024-05-11 00:00", allDay: true},
+		{input: "明天三点开会", title: "开会", due: "2024-05-09 15:00"},
+		{input: "下午两点钟接孩子", title: "接孩子", due: "2024-05-08 14:00"},
+		{input: "十一点钟交报告", title: "交报告", due: "2024-05-08 11:00"},
+		{input: "3点开会", title: "开会", due: "2024-05-08 15:00"},
+
+		// 没有时段修饰的 1–6 点按下午，写明凌晨或早上时不变
+		{input: "六点半下班", title: "下班", due: "2024-05-08 18:30"},
+		{input: "凌晨3点发布", title: "发布", due: "2024-05-09 03:00"},
+		{input: "早上6点跑步", title: "跑步", due: "2024-05-09 06:00"},
+		{input: "明早六点出发", title: "出发", due: "2024-05-09 06:00"},
+		{input: "7点吃早饭", title: "吃早饭", due: "2024-05-09 07:00"},
+		{input: "12点吃饭", title: "吃饭", due: "2024-05-08 12:00"},
+		{input: "今晚三点上线", title: "上线", due: "2024-05-08 15:00"},
+
+		// "一点"、"三点" 表示程度或数量时不是时刻
+		{input: "写快一点", title: "写快一点"},
+		{input: "明天早一点出门", title: "早一点出门", due: "2024-05-09 00:00", allDay: true},
+		{input: "注意三点", title: "注意三点"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got := Parse(tt.input, testNow)
+
+			if got.Title != tt.title {
+				t.Errorf("title = %q, want %q", got.Title, tt.title)
+			}
+			due := ""
+			if got.DueDate != nil {
+				due = got.DueDate.Format("2006-01-02 15:04")
+			}
+			if due != tt.due {
+				t.Errorf("due = %q, want %q", due, tt.due)
+			}
+			if got.AllDay != tt.allDay {
+				t.Errorf("all_day = %v, want %v", got.AllDay, tt.allDay)
+			}
+			if tt.tags == nil {
+				tt.tags = []string{}
+			}
+			if !reflect.DeepEqual(got.Tags, tt.tags) {
+				t.Errorf("tags = %v, want %v", got.Tags, tt.tags)
+			}
+			if got.Priority != tt.priority {
+				t.Errorf("priority = %q, want %q", got.Priority, tt.priority)
+			}
+			if got.Recurrence != tt.recurrence {
+				t.Errorf("recurrence = %q, want %q", got.Recurrence, tt.recurrence)
+			}
+		})
+	}
+}
+
+func TestParseChineseNumber(t *testing.T) {
+	tests := map[string]int{"7": 7, "十": 10, "十二": 12, "二十": 20, "二十三": 23, "两": 2}
+	for in, want := range tests {
+		if got, ok := parseChineseNumber(in); !ok || got != want {
+			t.Errorf("parseChineseNumber(%q) = %d, %v, want %d", in, got, ok, want)
+		}
+	}
+	for _, in := range []string{"", "十十", "百"} {
+		if _, ok := parseChineseNumber(in); ok {
+			t.Errorf("parseChineseNumber(%q) should fail", in)
+		}
+	}
+}
@@ -0,0 +1,386 @@
+package quickadd
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var mustCompile = regexp.MustCompile
+
+var englishWeekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+var chineseWeekdays = map[string]time.Weekday{
+	"一": time.Monday, "二": time.Tuesday, "三": time.Wednesday, "四": time.Thursday,
+	"五": time.Friday, "六": time.Saturday, "日": time.Sunday, "天": time.Sunday,
+}
+
+var englishMonths = map[string]time.Month{
+	"jan": time.January, "feb": time.February, "mar": time.March, "apr": time.April,
+	"may": time.May, "jun": time.June, "jul": time.July, "aug": time.August,
+	"sep": time.September, "oct": time.October, "nov": time.November, "dec": time.December,
+}
+
+var rruleDays = map[time.Weekday]string{
+	time.Sunday: "SU", time.Monday: "MO", time.Tuesday: "TU", time.Wednesday: "WE",
+	time.Thursday: "TH", time.Friday: "FR", time.Saturday: "SA",
+}
+
+var priorities = map[string]string{
+	"high": "high", "h": "high", "1": "high", "高": "high",
+	"medium": "medium", "med": "medium", "m": "medium", "2": "medium", "中": "medium",
+	"low": "low", "l": "low", "3": "low", "低": "low",
+}
+
+const (
+	weekdayPattern  = `monday|tuesday|wednesday|thursday|friday|saturday|sunday|mon|tues|tue|wed|thurs|thur|thu|fri|sat|sun`
+	monthPattern    = `jan(?:uary)?|feb(?:ruary)?|mar(?:ch)?|apr(?:il)?|may|june?|july?|aug(?:ust)?|sep(?:t(?:ember)?)?|oct(?:ober)?|nov(?:ember)?|dec(?:ember)?`
+	cnNumberPattern = `[0-9]+|[零一二两三四五六七八九十]+`
+)
+
+// rules 按顺序应用，靠前的规则优先占用文本
+var rules = []rule{
+	// #标签
+	{mustCompile(`(?:^|\s)#([\p{L}\p{N}_\-/]+)`), func(p *parser, m []string) (string, string, bool) {
+		for _, t := range p.result.Tags {
+			if t == m[1] {
+				return KindTag, m[1], true
+			}
+		}
+		p.result.Tags = append(p.result.Tags, m[1])
+		return KindTag, m[1], true
+	}},
+
+	// !优先级
+	{mustCompile(`(?i)(?:^|\s)!(high|medium|med|low|h|m|l|1|2|3|高|中|低)(?:\s|$)`), func(p *parser, m []string) (string, string, bool) {
+		if p.result.Priority != "" {
+			return "", "", false
+		}
+		p.result.Priority = priorities[strings.ToLower(m[1])]
+		return KindPriority, p.result.Priority, true
+	}},
+
+	// 重复规则：every day / every friday / weekly / 每天 / 每周五 / 每个工作日
+	{mustCompile(`(?i)\bevery\s+(day|weekday|week|month|year|` + weekdayPattern + `)\b`), func(p *parser, m []string) (string, string, bool) {
+		switch unit := strings.ToLower(m[1]); unit {
+		case "day":
+			return p.setRecurrence("FREQ=DAILY", nil)
+		case "weekday":
+			return p.setRecurrence("FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR", nil)
+		case "week":
+			return p.setRecurrence("FREQ=WEEKLY", nil)
+		case "month":
+			return p.setRecurrence("FREQ=MONTHLY", nil)
+		case "year":
+			return p.setRecurrence("FREQ=YEARLY", nil)
+		default:
+			wd := englishWeekdays[unit]
+			return p.setRecurrence("FREQ=WEEKLY;BYDAY="+rruleDays[wd], &wd)
+		}
+	}},
+	{mustCompile(`(?i)\b(daily|weekly|monthly|yearly)\b`), func(p *parser, m []string) (string, string, bool) {
+		freq := map[string]string{"daily": "DAILY", "weekly": "WEEKLY", "monthly": "MONTHLY", "yearly": "YEARLY"}
+		return p.setRecurrence("FREQ="+freq[strings.ToLower(m[1])], nil)
+	}},
+	{mustCompile(`每个?工作日`), func(p *parser, m []string) (string, string, bool) {
+		return p.setRecurrence("FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR", nil)
+	}},
+	{mustCompile(`每(?:个)?(天|日|月|年|周|星期|礼拜)([一二三四五六日天])?`), func(p *parser, m []string) (string, string, bool) {
+		switch m[1] {
+		case "天", "日":
+			return p.setRecurrence("FREQ=DAILY", nil)
+		case "月":
+			return p.setRecurrence("FREQ=MONTHLY", nil)
+		case "年":
+			return p.setRecurrence("FREQ=YEARLY", nil)
+		}
+		if m[2] == "" {
+			return p.setRecurrence("FREQ=WEEKLY", nil)
+		}
+		wd := chineseWeekdays[m[2]]
+		return p.setRecurrence("FREQ=WEEKLY;BYDAY="+rruleDays[wd], &wd)
+	}},
+
+	// 绝对日期：2024-05-10 / 5/10 / May 10 / 5月10日
+	{mustCompile(`\b(\d{4})-(\d{1,2})-(\d{1,2})\b`), func(p *parser, m []string) (string, string, bool) {
+		y, _ := strconv.Atoi(m[1])
+		return p.setCalendarDate(y, m[2], m[3])
+	}},
+	{mustCompile(`(?i)\b(?:on\s+)?(\d{1,2})/(\d{1,2})\b`), func(p *parser, m []string) (string, string, bool) {
+		return p.setCalendarDate(0, m[1], m[2])
+	}},
+	{mustCompile(`(?i)\b(?:on\s+)?(` + monthPattern + `)\.?\s+(\d{1,2})(?:st|nd|rd|th)?\b`), func(p *parser, m []string) (string, string, bool) {
+		month := englishMonths[strings.ToLower(m[1])[:3]]
+		return p.setCalendarDate(0, strconv.Itoa(int(month)), m[2])
+	}},
+	{mustCompile(`(` + cnNumberPattern + `)月(` + cnNumberPattern + `)[日号]`), func(p *parser, m []string) (string, string, bool) {
+		month, ok1 := parseChineseNumber(m[1])
+		day, ok2 := parseChineseNumber(m[2])
+		if !ok1 || !ok2 {
+			return "", "", false
+		}
+		return p.setCalendarDate(0, strconv.Itoa(month), strconv.Itoa(day))
+	}},
+
+	// 中文相对日期：今天 / 明晚 / 大后天 / 下周五 / 3天后 / 下个月
+	{mustCompile(`(今天|今日|今晚|明天|明日|明早|明晚|大后天|后天)`), func(p *parser, m []string) (string, string, bool) {
+		offsets := map[string]int{"今天": 0, "今日": 0, "今晚": 0, "明天": 1, "明日": 1, "明早": 1, "明晚": 1, "后天": 2, "大后天": 3}
+		kind, value, ok := p.setDate(p.today().AddDate(0, 0, offsets[m[1]]))
+		if ok && strings.HasSuffix(m[1], "晚") {
+			p.evening = true
+			p.defaultClock = &clock{hour: 20}
+		} else if ok && m[1] == "明早" {
+			p.morning = true
+			p.defaultClock = &clock{hour: 9}
+		}
+		return kind, value, ok
+	}},
+	{mustCompile(`(下下|下个?|这个?|本)?(?:周|星期|礼拜)([一二三四五六日天])`), func(p *parser, m []string) (string, string, bool) {
+		wd := chineseWeekdays[m[2]]
+		monday := weekStart(p.today())
+		switch m[1] {
+		case "":
+			return p.setDate(nextWeekday(p.today(), wd))
+		case "下下":
+			return p.setDate(weekdayInWeek(monday.AddDate(0, 0, 14), wd))
+		case "下", "下个":
+			return p.setDate(weekdayInWeek(monday.AddDate(0, 0, 7), wd))
+		default:
+			return p.setDate(weekdayInWeek(monday, wd))
+		}
+	}},
+	{mustCompile(`(` + cnNumberPattern + `)\s*(天|周|个星期|个礼拜|个月)后`), func(p *parser, m []string) (string, string, bool) {
+		n, ok := parseChineseNumber(m[1])
+		if !ok {
+			return "", "", false
+		}
+		switch m[2] {
+		case "天":
+			return p.setDate(p.today().AddDate(0, 0, n))
+		case "个月":
+			return p.setDate(p.today().AddDate(0, n, 0))
+		default:
+			return p.setDate(p.today().AddDate(0, 0, 7*n))
+		}
+	}},
+	{mustCompile(`下(?:个)?(周|星期|礼拜|月)`), func(p *parser, m []string) (string, string, bool) {
+		if m[1] == "月" {
+			first := time.Date(p.now.Year(), p.now.Month()+1, 1, 0, 0, 0, 0, p.now.Location())
+			return p.setDate(first)
+		}
+		return p.setDate(weekStart(p.today()).AddDate(0, 0, 7))
+	}},
+
+	// 英文相对日期：today / tonight / tomorrow / next friday / in 3 days / next week
+	{mustCompile(`(?i)\b(day after tomorrow|today|tonight|tomorrow|tmrw|tmr)\b`), func(p *parser, m []string) (string, string, bool) {
+		offsets := map[string]int{"today": 0, "tonight": 0, "tomorrow": 1, "tmrw": 1, "tmr": 1, "day after tomorrow": 2}
+		word := strings.ToLower(m[1])
+		kind, value, ok := p.setDate(p.today().AddDate(0, 0, offsets[word]))
+		if ok && word == "tonight" {
+			p.evening = true
+			p.defaultClock = &clock{hour: 20}
+		}
+		return kind, value, ok
+	}},
+	{mustCompile(`(?i)\b(?:(on|by|due|until|before)\s+)?(?:(next|this)\s+)?(` + weekdayPattern + `)\b`), func(p *parser, m []string) (string, string, bool) {
+		word := strings.ToLower(m[3])
+		if m[1] == "" && m[2] == "" && !strings.HasSuffix(word, "day") && !p.weekdayAbbrevInContext() {
+			return "", "", false
+		}
+		wd := englishWeekdays[word]
+		switch strings.ToLower(m[2]) {
+		case "next":
+			return p.setDate(weekdayInWeek(weekStart(p.today()).AddDate(0, 0, 7), wd))
+		case "this":
+			return p.setDate(weekdayInWeek(weekStart(p.today()), wd))
+		default:
+			return p.setDate(nextWeekday(p.today(), wd))
+		}
+	}},
+	{mustCompile(`(?i)\bin\s+(\d+|a|an|one|two|three)\s+(days?|weeks?|months?)\b`), func(p *parser, m []string) (string, string, bool) {
+		words := map[string]int{"a": 1, "an": 1, "one": 1, "two": 2, "three": 3}
+		n, ok := words[strings.ToLower(m[1])]
+		if !ok {
+			n, _ = strconv.Atoi(m[1])
+		}
+		switch unit := strings.ToLower(m[2]); {
+		case strings.HasPrefix(unit, "day"):
+			return p.setDate(p.today().AddDate(0, 0, n))
+		case strings.HasPrefix(unit, "week"):
+			return p.setDate(p.today().AddDate(0, 0, 7*n))
+		default:
+			return p.setDate(p.today().AddDate(0, n, 0))
+		}
+	}},
+	{mustCompile(`(?i)\bnext\s+(week|month)\b`), func(p *parser, m []string) (string, string, bool) {
+		if strings.EqualFold(m[1], "month") {
+			first := time.Date(p.now.Year(), p.now.Month()+1, 1, 0, 0, 0, 0, p.now.Location())
+			return p.setDate(first)
+		}
+		return p.setDate(weekStart(p.today()).AddDate(0, 0, 7))
+	}},
+
+	// 时刻：5pm / 5:30 pm / at 17:00 / noon / 下午5点半 / 晚上8点
+	{mustCompile(`(?i)\b(?:at\s+)?(\d{1,2})(?::(\d{2}))?\s*(am|pm)\b`), func(p *parser, m []string) (string, string, bool) {
+		hour, _ := strconv.Atoi(m[1])
+		minute, _ := strconv.Atoi(m[2])
+		if hour < 1 || hour > 12 {
+			return "", "", false
+		}
+		hour %= 12
+		if strings.EqualFold(m[3], "pm") {
+			hour += 12
+		}
+		return p.setClock(hour, minute)
+	}},
+	{mustCompile(`(早上|早晨|上午|中午|下午|傍晚|晚上|凌晨)?(` + cnNumberPattern + `)\s*[点點时]钟?\s*(?:(半)|(` + cnNumberPattern + `)分?)?`), func(p *parser, m []string) (string, string, bool) {
+		hour, ok := parseChineseNumber(m[2])
+		if !ok {
+			return "", "", false
+		}
+		if m[1] == "" && m[3] == "" && m[4] == "" && !strings.HasSuffix(m[0], "钟") && !p.chineseClockInContext(m[2]) {
+			return "", "", false
+		}
+		minute := 0
+		if m[3] == "半" {
+			minute = 30
+		} else if m[4] != "" {
+			if minute, ok = parseChineseNumber(m[4]); !ok {
+				return "", "", false
+			}
+		}
+		switch m[1] {
+		case "下午", "傍晚", "晚上":
+			if hour < 12 {
+				hour += 12
+			}
+		case "中午":
+			if hour < 6 {
+				hour += 12
+			}
+		case "":
+			hour = p.bareChineseHour(hour)
+		}
+		return p.setClock(hour, minute)
+	}},
+	{mustCompile(`(?i)\b(?:at\s+)?(\d{1,2}):(\d{2})\b`), func(p *parser, m []string) (string, string, bool) {
+		hour, _ := strconv.Atoi(m[1])
+		minute, _ := strconv.Atoi(m[2])
+		return p.setClock(p.eveningHour(hour), minute)
+	}},
+	{mustCompile(`(?i)\b(?:at\s+)?(noon|midnight)\b`), func(p *parser, m []string) (string, string, bool) {
+		if strings.EqualFold(m[1], "noon") {
+			return p.setClock(12, 0)
+		}
+		return p.setClock(0, 0)
+	}},
+}
+
+// 单独出现时不当作星期的词：冠词、所有格之后的 "sun"、"sat"、"wed" 等是普通单词
+var (
+	nonDateDeterminer = mustCompile(`(?i)\b(?:the|a|an|my|your|his|her|its|our|their)\s*$`)
+	trailingClock     = mustCompile(`(?i)^\s*(?:(?:at\s+)?(?:\d{1,2}(?::\d{2})?\s*(?:am|pm)|\d{1,2}:\d{2}|noon|midnight))?\s*$`)
+)
+
+// weekdayAbbrevInContext 判断没有介词引导的星期缩写（sun、sat 等）是否表示日期：
+// 必须位于输入末尾（后面最多跟一个时刻），且前面不是冠词或所有格
+func (p *parser) weekdayAbbrevInContext() bool {
+	return trailingClock.MatchString(p.after) && !nonDateDeterminer.MatchString(p.before)
+}
+
+// 出现在这些字之后的 "一点"、"两点" 表示程度，例如 "快一点"、"早一点"
+const degreeAdverbs = "快慢早晚多少好大小高低轻重远近再更"
+
+// chineseClockInContext 判断没有上午、下午等时段修饰，也没有分钟或 "钟" 的 "N点" 是否表示时刻。
+// 阿拉伯数字或已经识别出日期（"明天三点"）时是时刻；
+// 中文数字前面是程度副词（"快一点"）或单独出现（"注意三点"）时不是
+func (p *parser) chineseClockInContext(number string) bool {
+	if _, err := strconv.Atoi(number); err == nil {
+		return true
+	}
+	before := []rune(strings.TrimRight(p.before, " "))
+	if len(before) > 0 && strings.ContainsRune(degreeAdverbs, before[len(before)-1]) {
+		return false
+	}
+	return p.date != nil
+}
+
+// setCalendarDate 设置月日，year 为 0 时取今天之后最近的一次
+func (p *parser) setCalendarDate(year int, month, day string) (string, string, bool) {
+	mo, err1 := strconv.Atoi(month)
+	d, err2 := strconv.Atoi(day)
+	if err1 != nil || err2 != nil || mo < 1 || mo > 12 || d < 1 || d > 31 {
+		return "", "", false
+	}
+	y := year
+	if y == 0 {
+		y = p.now.Year()
+	}
+	t := time.Date(y, time.Month(mo), d, 0, 0, 0, 0, p.now.Location())
+	if t.Day() != d {
+		return "", "", false
+	}
+	if year == 0 && t.Before(p.today()) {
+		t = t.AddDate(1, 0, 0)
+	}
+	return p.setDate(t)
+}
+
+// bareChineseHour 换算没有上午、下午等时段修饰的中文时刻。"今晚八点" 按晚上；
+// 其余情况下 1–6 点按下午理解（"三点开会" 是 15:00），凌晨或早上需要写明，例如 "凌晨三点"、"明早六点"
+func (p *parser) bareChineseHour(hour int) int {
+	switch {
+	case p.evening:
+		return p.eveningHour(hour)
+	case !p.morning && hour >= 1 && hour <= 6:
+		return hour + 12
+	}
+	return hour
+}
+
+// eveningHour 在 "今晚八点"、"tonight at 8:00" 这类上下文中把 12 点以前的时刻视为晚上
+func (p *parser) eveningHour(hour int) int {
+	if p.evening && hour < 12 {
+		return hour + 12
+	}
+	return hour
+}
+
+// parseChineseNumber 解析阿拉伯数字或 0-99 的中文数字
+func parseChineseNumber(s string) (int, bool) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n, true
+	}
+	digits := map[rune]int{'零': 0, '一': 1, '二': 2, '两': 2, '三': 3, '四': 4, '五': 5, '六': 6, '七': 7, '八': 8, '九': 9}
+	runes := []rune(s)
+	tens, ones, seenTen := 0, 0, false
+	for i, r := range runes {
+		if r == '十' {
+			if seenTen {
+				return 0, false
+			}
+			seenTen = true
+			tens = 1
+			if i > 0 {
+				tens = ones
+			}
+			ones = 0
+			continue
+		}
+		d, ok := digits[r]
+		if !ok {
+			return 0, false
+		}
+		ones = d
+	}
+	return tens*10 + ones, len(runes) > 0
+}
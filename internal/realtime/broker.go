@@ -0,0 +1,82 @@
+// Package realtime 把任务事件实时推送给在线的客户端（SSE / WebSocket）。
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+)
+
+// Message 推送给客户端的一条事件，ID 为事件日志中的序号
+type Message struct {
+	ID     uint64          `json:"id"`
+	UserID uint            `json:"-"`
+	Type   string          `json:"type"`
+	Data   json.RawMessage `json:"data,omitempty"`
+}
+
+// Broker 在 API 实例之间广播事件。每个实例的 Hub 订阅 broker，再把消息分发给本实例上的连接；
+// 多实例部署时可以换成基于 Redis Pub/Sub、NATS 等的实现。
+type Broker interface {
+	Publish(ctx context.Context, msg Message) error
+	// Subscribe 返回接收消息的通道，ctx 结束后通道关闭
+	Subscribe(ctx context.Context) (<-chan Message, error)
+}
+
+// MemoryBroker 进程内的 Broker，只适用于单实例部署。
+// Publish 不会阻塞：订阅者的缓冲写满时直接断开该订阅（关闭其通道），由订阅者重新订阅。
+type MemoryBroker struct {
+	mu   sync.RWMutex
+	subs map[chan Message]struct{}
+}
+
+// subscriberBuffer 每个订阅者的缓冲大小
+const subscriberBuffer = 256
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{subs: make(map[chan Message]struct{})}
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, msg Message) error {
+	var slow []chan Message
+	b.mu.RLock()
+	for ch := range b.subs {
+		select {
+		case ch <- msg:
+		default:
+			slow = append(slow, ch)
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, ch := range slow {
+		log.Printf("推送订阅者处理过慢，已断开")
+		b.unsubscribe(ch)
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(ctx context.Context) (<-chan Message, error) {
+	ch := make(chan Message, subscriberBuffer)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.unsubscribe(ch)
+	}()
+	return ch, nil
+}
+
+// unsubscribe 移除订阅并关闭通道，重复调用是安全的
+func (b *MemoryBroker) unsubscribe(ch chan Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[ch]; !ok {
+		return
+	}
+	delete(b.subs, ch)
+	close(ch)
+}
@@ -0,0 +1,221 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"myproject/internal/events"
+	models "myproject/internal/model"
+	"myproject/internal/repository"
+)
+
+const (
+	// 事件日志保留时长，超过后断线续传改为通知客户端全量刷新
+	eventLogRetention = 24 * time.Hour
+	pruneInterval     = 10 * time.Minute
+	// 续传时最多补发的事件数
+	maxReplay = 500
+	// 每个连接的发送缓冲，写满说明客户端跟不上，直接断开由客户端续传
+	clientBuffer = 64
+)
+
+// TypeReset 通知客户端无法续传，需要重新拉取任务列表
+const TypeReset = "reset"
+
+// DefaultHub 默认使用进程内 broker，多实例部署时在启动前替换为共享 broker 的 Hub
+var DefaultHub = NewHub(NewMemoryBroker())
+
+// Hub 管理本实例上的连接，把 broker 收到的事件分发给对应用户的连接
+type Hub struct {
+	broker  Broker
+	mu      sync.RWMutex
+	clients map[uint]map[*Client]struct{}
+}
+
+// Client 一个在线连接
+type Client struct {
+	userID uint
+	send   chan Message
+}
+
+// Messages 返回推送给该连接的事件，连接被 Hub 断开时通道关闭
+func (c *Client) Messages() <-chan Message {
+	return c.send
+}
+
+func NewHub(broker Broker) *Hub {
+	return &Hub{
+		broker:  broker,
+		clients: make(map[uint]map[*Client]struct{}),
+	}
+}
+
+// HandleTaskEvent 记录任务事件并通过 broker 广播，作为 events 的订阅者使用
+func (h *Hub) HandleTaskEvent(e events.Event) {
+	data, err := json.Marshal(map[string]interface{}{"task": taskPayload{Task: e.Task}})
+	if err != nil {
+		log.Printf("序列化推送事件失败: %v", err)
+		return
+	}
+
+	entry := models.EventLog{UserID: e.UserID, Type: e.Type, Payload: string(data), CreatedAt: e.OccurredAt}
+	if err := repository.AppendEventLog(&entry); err != nil {
+		log.Printf("记录推送事件失败: %v", err)
+		return
+	}
+
+	if err := h.broker.Publish(context.Background(), messageFromLog(entry)); err != nil {
+		log.Printf("广播推送事件失败: %v", err)
+	}
+}
+
+// Run 订阅 broker 并分发事件，阻塞直到 ctx 结束。过期事件日志的清理在单独的 goroutine 中进行，
+// 不会拖慢分发。订阅因处理过慢被 broker 断开时，断开本实例的全部连接让客户端续传，然后重新订阅。
+func (h *Hub) Run(ctx context.Context) {
+	go h.pruneLoop(ctx)
+
+	for {
+		messages, err := h.broker.Subscribe(ctx)
+		if err != nil {
+			log.Printf("订阅推送事件失败: %v", err)
+			return
+		}
+		for msg := range messages {
+			h.dispatch(msg)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("推送订阅被 broker 断开，重新订阅")
+		h.disconnectAll()
+	}
+}
+
+// pruneLoop 定期清理过期的事件日志，直到 ctx 结束
+func (h *Hub) pruneLoop(ctx context.Context) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := repository.PruneEventLogs(now.Add(-eventLogRetention)); err != nil {
+				log.Printf("清理事件日志失败: %v", err)
+			}
+		}
+	}
+}
+
+// Connect 注册一个连接。lastEventID 不为 0 时返回其后需要补发的事件；
+// 这些事件已被清理或数量过多时 reset 为 true，客户端应重新拉取任务列表。
+// 连接先注册再查询日志，补发期间产生的事件会进入缓冲，调用方需跳过 ID 不大于已补发事件的消息。
+func (h *Hub) Connect(userID uint, lastEventID uint64) (client *Client, replay []Message, reset bool, err error) {
+	client = &Client{userID: userID, send: make(chan Message, clientBuffer)}
+	h.mu.Lock()
+	if h.clients[userID] == nil {
+		h.clients[userID] = make(map[*Client]struct{})
+	}
+	h.clients[userID][client] = struct{}{}
+	h.mu.Unlock()
+
+	if lastEventID == 0 {
+		return client, nil, false, nil
+	}
+
+	oldest, err := repository.GetOldestEventLogID()
+	if err != nil {
+		h.Disconnect(client)
+		return nil, nil, false, err
+	}
+	if oldest == 0 || oldest > lastEventID+1 {
+		return client, nil, true, nil
+	}
+
+	entries, err := repository.GetEventLogsAfter(userID, lastEventID, maxReplay+1)
+	if err != nil {
+		h.Disconnect(client)
+		return nil, nil, false, err
+	}
+	if len(entries) > maxReplay {
+		return client, nil, true, nil
+	}
+	for _, entry := range entries {
+		replay = append(replay, messageFromLog(entry))
+	}
+	return client, replay, false, nil
+}
+
+// Disconnect 注销连接
+func (h *Hub) Disconnect(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(client)
+}
+
+func (h *Hub) dispatch(msg Message) {
+	var slow []*Client
+	h.mu.RLock()
+	for client := range h.clients[msg.UserID] {
+		select {
+		case client.send <- msg:
+		default:
+			slow = append(slow, client)
+		}
+	}
+	h.mu.RUnlock()
+
+	if len(slow) > 0 {
+		h.mu.Lock()
+		for _, client := range slow {
+			h.remove(client)
+		}
+		h.mu.Unlock()
+	}
+}
+
+// disconnectAll 断开本实例的全部连接，客户端会带着 Last-Event-ID 重连并从事件日志补发
+func (h *Hub) disconnectAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, conns := range h.clients {
+		for client := range conns {
+			h.remove(client)
+		}
+	}
+}
+
+// remove 需持有写锁，关闭发送通道让连接结束
+func (h *Hub) remove(client *Client) {
+	conns, ok := h.clients[client.userID]
+	if !ok {
+		return
+	}
+	if _, ok := conns[client]; !ok {
+		return
+	}
+	delete(conns, client)
+	close(client.send)
+	if len(conns) == 0 {
+		delete(h.clients, client.userID)
+	}
+}
+
+// taskPayload 推送给客户端的任务，事件中的任务不带关联用户，省略零值的 user 字段
+type taskPayload struct {
+	models.Task
+	User *models.User `json:"user,omitempty"`
+}
+
+func messageFromLog(entry models.EventLog) Message {
+	return Message{
+		ID:     entry.ID,
+		UserID: entry.UserID,
+		Type:   entry.Type,
+		Data:   json.RawMessage(entry.Payload),
+	}
+}
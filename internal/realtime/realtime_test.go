@@ -0,0 +1,136 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"myproject/internal/events"
+	models "myproject/internal/model"
+	"myproject/internal/testutil"
+)
+
+func TestMemoryBrokerPublishDoesNotBlockOnSlowSubscriber(t *testing.T) {
+	b := NewMemoryBroker()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	slow, _ := b.Subscribe(ctx)
+	fast, _ := b.Subscribe(ctx)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < subscriberBuffer+1; i++ {
+			b.Publish(context.Background(), Message{ID: uint64(i + 1)})
+			<-fast
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Publish blocked on a full subscriber")
+	}
+
+	// 慢订阅者收到缓冲内的消息后通道关闭
+	n := 0
+	for range slow {
+		n++
+	}
+	if n != subscriberBuffer {
+		t.Errorf("slow subscriber received %d messages before being disconnected, want %d", n, subscriberBuffer)
+	}
+
+	b.Publish(context.Background(), Message{ID: 1000})
+	if msg := <-fast; msg.ID != 1000 {
+		t.Errorf("fast subscriber received %d, want 1000", msg.ID)
+	}
+}
+
+func TestHubRunDisconnectsClientsAndResubscribesAfterBrokerDrop(t *testing.T) {
+	testutil.OpenDB(t)
+	b := NewMemoryBroker()
+	h := NewHub(b)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go h.Run(ctx)
+
+	client, _, _, err := h.Connect(1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForSubscribers(t, b, 1)
+
+	// 模拟 Hub 的订阅被断开
+	b.mu.RLock()
+	var sub chan Message
+	for ch := range b.subs {
+		sub = ch
+	}
+	b.mu.RUnlock()
+	b.unsubscribe(sub)
+
+	select {
+	case _, ok := <-client.Messages():
+		if ok {
+			t.Fatal("client received a message, want its channel closed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("client was not disconnected after the broker dropped the hub")
+	}
+
+	waitForSubscribers(t, b, 1)
+	client, _, _, _ = h.Connect(1, 0)
+	b.Publish(context.Background(), Message{ID: 7, UserID: 1, Type: events.TaskCreated})
+	select {
+	case msg := <-client.Messages():
+		if msg.ID != 7 {
+			t.Errorf("received %d, want 7", msg.ID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("hub did not resubscribe")
+	}
+}
+
+func waitForSubscribers(t *testing.T, b *MemoryBroker, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		b.mu.RLock()
+		got := len(b.subs)
+		b.mu.RUnlock()
+		if got == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("broker does not have %d subscribers", n)
+}
+
+func TestHandleTaskEventOmitsUser(t *testing.T) {
+	testutil.OpenDB(t)
+	b := NewMemoryBroker()
+	h := NewHub(b)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	messages, _ := b.Subscribe(ctx)
+
+	task := models.Task{ID: 7, Description: "Ship it", UserID: 1}
+	h.HandleTaskEvent(events.Event{Type: events.TaskCreated, UserID: 1, Task: task, OccurredAt: time.Now()})
+
+	msg := <-messages
+	var data struct {
+		Task map[string]json.RawMessage `json:"task"`
+	}
+	if err := json.Unmarshal(msg.Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := data.Task["user"]; ok {
+		t.Errorf("payload %s contains a user object", msg.Data)
+	}
+	if string(data.Task["description"]) != `"Ship it"` {
+		t.Errorf("payload %s lost the task fields", msg.Data)
+	}
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"time"
+
+	"myproject/config"
+	models "myproject/internal/model"
+)
+
+// CreateAccessToken 保存个人访问令牌
+func CreateAccessToken(token *models.PersonalAccessToken) error {
+	return config.DB.Create(token).Error
+}
+
+// GetAccessTokensByUser 获取用户的全部个人访问令牌
+func GetAccessTokensByUser(userID uint) ([]models.PersonalAccessToken, error) {
+	var tokens []models.PersonalAccessToken
+	err := config.DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// GetAccessTokenByHash 根据摘要获取个人访问令牌
+func GetAccessTokenByHash(hash string) (*models.PersonalAccessToken, error) {
+	var token models.PersonalAccessToken
+	if err := config.DB.Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// GetAccessTokenByID 获取用户的某个个人访问令牌
+func GetAccessTokenByID(id string, userID uint) (*models.PersonalAccessToken, error) {
+	var token models.PersonalAccessToken
+	if err := config.DB.Where("id = ? AND user_id = ?", id, userID).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// DeleteAccessToken 删除个人访问令牌
+func DeleteAccessToken(token *models.PersonalAccessToken) error {
+	return config.DB.Delete(token).Error
+}
+
+// TouchAccessToken 更新最后使用时间。只有上次记录早于 before 时才写入
+func TouchAccessToken(id uint, at, before time.Time) error {
+	return config.DB.Model(&models.PersonalAccessToken{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, before).
+		Update("last_used_at", at).Error
+}
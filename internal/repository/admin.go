@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"strings"
+
+	"myproject/config"
+	models "myproject/internal/model"
+)
+
+// SearchUsers 按用户名或邮箱模糊搜索用户，不区分大小写，role 为空表示不限角色，disabled 为 nil 表示不限状态
+func SearchUsers(query, role string, disabled *bool, offset, limit int) ([]models.User, int64, error) {
+	db := config.DB.Model(&models.User{})
+	if query != "" {
+		// PostgreSQL 的 LIKE 区分大小写，统一转成小写比较
+		like := "%" + strings.ToLower(query) + "%"
+		db = db.Where("LOWER(username) LIKE ? OR LOWER(email) LIKE ?", like, like)
+	}
+	if role != "" {
+		db = db.Where("role = ?", role)
+	}
+	if disabled != nil {
+		if *disabled {
+			db = db.Where("disabled_at IS NOT NULL")
+		} else {
+			db = db.Where("disabled_at IS NULL")
+		}
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var users []models.User
+	if err := db.Order("id").Offset(offset).Limit(limit).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// GetAuditLogs 按动作和用户查询审计记录，最新的在前
+func GetAuditLogs(action string, userID *uint, offset, limit int) ([]models.AuditLog, int64, error) {
+	db := config.DB.Model(&models.AuditLog{})
+	if action != "" {
+		db = db.Where("action = ?", action)
+	}
+	if userID != nil {
+		db = db.Where("user_id = ?", *userID)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var logs []models.AuditLog
+	if err := db.Order("id DESC").Offset(offset).Limit(limit).Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+	return logs, total, nil
+}
+
+// Count 统计 model 表中满足条件的记录数，where 为空时统计全部
+func Count(model interface{}, where string, args ...interface{}) (int64, error) {
+	db := config.DB.Model(model)
+	if where != "" {
+		db = db.Where(where, args...)
+	}
+	var n int64
+	err := db.Count(&n).Error
+	return n, err
+}
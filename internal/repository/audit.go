@@ -0,0 +1,11 @@
+package repository
+
+import (
+	"myproject/config"
+	models "myproject/internal/model"
+)
+
+// CreateAuditLog 写入审计记录
+func CreateAuditLog(entry *models.AuditLog) error {
+	return config.DB.Create(entry).Error
+}
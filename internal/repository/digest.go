@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"time"
+
+	"myproject/config"
+	models "myproject/internal/model"
+
+	"gorm.io/gorm/clause"
+)
+
+// GetDigestSetting 获取用户的摘要设置
+func GetDigestSetting(userID uint) (*models.DigestSetting, error) {
+	var setting models.DigestSetting
+	if err := config.DB.Where("user_id = ?", userID).First(&setting).Error; err != nil {
+		return nil, err
+	}
+	return &setting, nil
+}
+
+// SaveDigestSetting 创建或更新用户的摘要设置
+func SaveDigestSetting(setting *models.DigestSetting) error {
+	return config.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled", "send_time", "updated_at"}),
+	}).Create(setting).Error
+}
+
+// GetEnabledDigestSettings 获取所有开启摘要的设置
+func GetEnabledDigestSettings() ([]models.DigestSetting, error) {
+	var settings []models.DigestSetting
+	if err := config.DB.
+		Preload("User").
+		Where("enabled = ?", true).
+		Find(&settings).Error; err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// ClaimDigest 由 owner 占用用户某一天的摘要发送记录，租约到 leaseUntil 为止。
+// 之前的占用者在租约内没有发送完成（例如进程退出）时接手这条记录；
+// 返回 false 表示这一天已经发送过或正在被其他实例发送
+func ClaimDigest(userID uint, date, owner string, now, leaseUntil time.Time) (*models.DigestLog, bool, error) {
+	log := models.DigestLog{UserID: userID, Date: date, Status: "sending", LeaseOwner: owner, LeaseUntil: &leaseUntil}
+	result := config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&log)
+	if result.Error != nil {
+		return nil, false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return &log, true, nil
+	}
+
+	result = config.DB.Model(&models.DigestLog{}).
+		Where("user_id = ? AND date = ? AND status = ?", userID, date, "sending").
+		Where("lease_until IS NULL OR lease_until < ?", now).
+		Updates(map[string]interface{}{"lease_owner": owner, "lease_until": leaseUntil})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, false, result.Error
+	}
+	if err := config.DB.Where("user_id = ? AND date = ?", userID, date).First(&log).Error; err != nil {
+		return nil, false, err
+	}
+	return &log, log.LeaseOwner == owner, nil
+}
+
+// MarkDigestSent 由租约持有者标记摘要已发送
+func MarkDigestSent(log *models.DigestLog, owner string, at time.Time) error {
+	return config.DB.Model(&models.DigestLog{}).
+		Where("id = ? AND lease_owner = ?", log.ID, owner).
+		Updates(map[string]interface{}{"status": "sent", "sent_at": at, "lease_owner": "", "lease_until": nil}).Error
+}
+
+// ReleaseDigest 发送失败时由租约持有者删除占用记录，以便下一轮重试
+func ReleaseDigest(log *models.DigestLog, owner string) error {
+	return config.DB.Where("id = ? AND lease_owner = ? AND status = ?", log.ID, owner, "sending").Delete(&models.DigestLog{}).Error
+}
+
+// GetDigestLogs 获取用户最近的摘要发送记录
+func GetDigestLogs(userID uint, limit int) ([]models.DigestLog, error) {
+	var logs []models.DigestLog
+	if err := config.DB.
+		Where("user_id = ?", userID).
+		Order("date DESC").
+		Limit(limit).
+		Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// GetOpenTasksDueBetween 获取 [from, to) 内到期且未完成的任务
+func GetOpenTasksDueBetween(userID uint, from, to time.Time) ([]models.Task, error) {
+	var tasks []models.Task
+	if err := config.DB.
+		Where("user_id = ? AND status <> ? AND due_date >= ? AND due_date < ?", userID, "done", from, to).
+		Order("due_date").
+		Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// GetTasksCompletedBetween 获取 [from, to) 内完成的任务
+func GetTasksCompletedBetween(userID uint, from, to time.Time) ([]models.Task, error) {
+	var tasks []models.Task
+	if err := config.DB.
+		Where("user_id = ? AND status = ? AND completed_at >= ? AND completed_at < ?", userID, "done", from, to).
+		Order("completed_at").
+		Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"time"
+
+	"myproject/config"
+	models "myproject/internal/model"
+)
+
+// AppendEventLog 记录一条事件，写入后 entry.ID 即事件序号
+func AppendEventLog(entry *models.EventLog) error {
+	return config.DB.Create(entry).Error
+}
+
+// GetEventLogsAfter 获取用户 afterID 之后的事件，按序号升序，最多 limit 条
+func GetEventLogsAfter(userID uint, afterID uint64, limit int) ([]models.EventLog, error) {
+	var entries []models.EventLog
+	if err := config.DB.
+		Where("user_id = ? AND id > ?", userID, afterID).
+		Order("id").
+		Limit(limit).
+		Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// GetOldestEventLogID 获取仍保留的最早事件序号，没有记录时返回 0
+func GetOldestEventLogID() (uint64, error) {
+	var id *uint64
+	if err := config.DB.Model(&models.EventLog{}).Select("MIN(id)").Scan(&id).Error; err != nil {
+		return 0, err
+	}
+	if id == nil {
+		return 0, nil
+	}
+	return *id, nil
+}
+
+// PruneEventLogs 删除 before 之前的事件
+func PruneEventLogs(before time.Time) error {
+	return config.DB.Where("created_at < ?", before).Delete(&models.EventLog{}).Error
+}
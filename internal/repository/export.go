@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"time"
+
+	"myproject/config"
+	models "myproject/internal/model"
+)
+
+// CreateDataExport 创建数据导出任务
+func CreateDataExport(export *models.DataExport) error {
+	return config.DB.Create(export).Error
+}
+
+// GetDataExport 获取用户的某个数据导出任务
+func GetDataExport(id string, userID uint) (*models.DataExport, error) {
+	var export models.DataExport
+	if err := config.DB.Where("id = ? AND user_id = ?", id, userID).First(&export).Error; err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// GetPendingDataExport 获取用户尚未生成完成的导出任务
+func GetPendingDataExport(userID uint) (*models.DataExport, error) {
+	var export models.DataExport
+	if err := config.DB.Where("user_id = ? AND status = ?", userID, "pending").First(&export).Error; err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// GetDataExportsByUser 获取用户的全部导出任务
+func GetDataExportsByUser(userID uint) ([]models.DataExport, error) {
+	var exports []models.DataExport
+	if err := config.DB.Where("user_id = ?", userID).Order("id DESC").Find(&exports).Error; err != nil {
+		return nil, err
+	}
+	return exports, nil
+}
+
+// ClaimDueDataExports 为 owner 租用最多 limit 个待生成的导出任务
+func ClaimDueDataExports(owner string, now, leaseUntil time.Time, limit int) ([]models.DataExport, error) {
+	claimed, err := claimDue(&models.DataExport{}, "created_at", owner, now, leaseUntil, limit)
+	if err != nil || len(claimed) == 0 {
+		return nil, err
+	}
+
+	var exports []models.DataExport
+	if err := config.DB.Where("id IN ?", claimed).Find(&exports).Error; err != nil {
+		return nil, err
+	}
+	return exports, nil
+}
+
+// FinishDataExport 释放租约并写入结果。租约已被其他实例接管或记录已被删除时不更新，返回 false
+func FinishDataExport(export *models.DataExport, owner string, updates map[string]interface{}) (bool, error) {
+	updates["lease_owner"] = ""
+	updates["lease_until"] = nil
+	result := config.DB.Model(&models.DataExport{}).
+		Where("id = ? AND lease_owner = ?", export.ID, owner).
+		Updates(updates)
+	return result.RowsAffected == 1, result.Error
+}
+
+// GetExpiredDataExports 获取已过期的导出任务
+func GetExpiredDataExports(now time.Time) ([]models.DataExport, error) {
+	var exports []models.DataExport
+	if err := config.DB.Where("expires_at < ?", now).Find(&exports).Error; err != nil {
+		return nil, err
+	}
+	return exports, nil
+}
+
+// DeleteDataExport 删除导出任务记录
+func DeleteDataExport(id uint) error {
+	return config.DB.Delete(&models.DataExport{}, id).Error
+}
+
+// FindByUser 按 ID 顺序查询 model 表中属于用户的全部记录，用于导出个人数据
+func FindByUser(dest interface{}, userID uint, preloads ...string) error {
+	query := config.DB.Where("user_id = ?", userID).Order("id")
+	for _, preload := range preloads {
+		query = query.Preload(preload)
+	}
+	return query.Find(dest).Error
+}
+
+// GetDeliveriesByUser 获取用户全部 webhook 的投递记录
+func GetDeliveriesByUser(userID uint) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	webhooks := config.DB.Model(&models.Webhook{}).Select("id").Where("user_id = ?", userID)
+	if err := config.DB.Where("webhook_id IN (?)", webhooks).Order("id").Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
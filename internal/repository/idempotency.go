@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"time"
+
+	"myproject/config"
+	models "myproject/internal/model"
+
+	"gorm.io/gorm/clause"
+)
+
+// ClaimIdempotencyKey 尝试登记幂等键，返回是否由本次请求登记成功
+func ClaimIdempotencyKey(record *models.IdempotencyKey) (bool, error) {
+	result := config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// GetIdempotencyKey 获取用户的幂等键记录
+func GetIdempotencyKey(userID uint, key string) (*models.IdempotencyKey, error) {
+	var record models.IdempotencyKey
+	if err := config.DB.
+		Where("user_id = ? AND request_key = ?", userID, key).
+		First(&record).Error; err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// RenewIdempotencyKey 延长处理中幂等键的锁
+func RenewIdempotencyKey(record *models.IdempotencyKey, lockedUntil time.Time) error {
+	return config.DB.Model(&models.IdempotencyKey{}).
+		Where("id = ? AND status = ?", record.ID, "processing").
+		Update("locked_until", lockedUntil).Error
+}
+
+// CompleteIdempotencyKey 保存首次请求的响应
+func CompleteIdempotencyKey(record *models.IdempotencyKey, status int, headers map[string]string, body []byte) error {
+	return config.DB.Model(record).
+		Select("status", "response_status", "response_headers", "response_body").
+		Updates(models.IdempotencyKey{
+			Status:          "completed",
+			ResponseStatus:  status,
+			ResponseHeaders: headers,
+			ResponseBody:    body,
+		}).Error
+}
+
+// DeleteIdempotencyKey 删除幂等键。onlyIfLockedBefore 不为零时只删除在该时间前就已中断的处理中记录
+func DeleteIdempotencyKey(record *models.IdempotencyKey, onlyIfLockedBefore time.Time) error {
+	db := config.DB.Where("id = ?", record.ID)
+	if !onlyIfLockedBefore.IsZero() {
+		db = db.Where("status = ? AND locked_until < ?", "processing", onlyIfLockedBefore)
+	}
+	return db.Delete(&models.IdempotencyKey{}).Error
+}
+
+// DeleteExpiredIdempotencyKeys 清理过期的幂等键
+func DeleteExpiredIdempotencyKeys(now time.Time) error {
+	return config.DB.Where("expires_at < ?", now).Delete(&models.IdempotencyKey{}).Error
+}
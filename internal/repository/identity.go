@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"time"
+
+	"myproject/config"
+	models "myproject/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// CreateOAuthState 保存外部登录的 state
+func CreateOAuthState(state *models.OAuthState) error {
+	return config.DB.Create(state).Error
+}
+
+// TakeOAuthState 取出并删除 state，保证只能使用一次；不存在或已过期时返回 gorm.ErrRecordNotFound
+func TakeOAuthState(state, provider string, now time.Time) (*models.OAuthState, error) {
+	var record models.OAuthState
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("state = ? AND provider = ?", state, provider).First(&record).Error; err != nil {
+			return err
+		}
+		result := tx.Where("state = ?", state).Delete(&models.OAuthState{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 || !now.Before(record.ExpiresAt) {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// DeleteExpiredOAuthStates 删除过期的 state
+func DeleteExpiredOAuthStates(now time.Time) error {
+	return config.DB.Where("expires_at < ?", now).Delete(&models.OAuthState{}).Error
+}
+
+// GetIdentity 根据提供方和 subject 查找关联的账号
+func GetIdentity(provider, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	if err := config.DB.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error; err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// CreateIdentity 关联外部账号
+func CreateIdentity(identity *models.UserIdentity) error {
+	return config.DB.Create(identity).Error
+}
+
+// CreateUserWithIdentity 创建用户并关联外部账号
+func CreateUserWithIdentity(user *models.User, identity *models.UserIdentity) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		identity.UserID = user.ID
+		return tx.Create(identity).Error
+	})
+}
+
+// UsernameExists 判断用户名是否已被使用
+func UsernameExists(username string) (bool, error) {
+	var count int64
+	err := config.DB.Model(&models.User{}).Where("username = ?", username).Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"time"
+
+	"myproject/config"
+)
+
+// claimDue 在带有 status、lease_owner、lease_until 列的队列表中，为 owner 租用最多 limit 条
+// status 为 pending 且 dueColumn 已到期的记录，返回租到的 ID。
+// 每一条都通过带条件的 UPDATE 抢占，多个实例同时运行时同一条记录只会被一个实例拿到；
+// 持有者崩溃后租约过期，记录会被其他实例重新领取。
+func claimDue(model interface{}, dueColumn, owner string, now, leaseUntil time.Time, limit int) ([]uint, error) {
+	available := "status = ? AND (lease_until IS NULL OR lease_until < ?)"
+
+	var candidates []uint
+	if err := config.DB.Model(model).
+		Where(available, "pending", now).
+		Where(dueColumn+" <= ?", now).
+		Order(dueColumn).
+		Limit(limit).
+		Pluck("id", &candidates).Error; err != nil {
+		return nil, err
+	}
+
+	var claimed []uint
+	for _, id := range candidates {
+		result := config.DB.Model(model).
+			Where("id = ?", id).
+			Where(available, "pending", now).
+			Updates(map[string]interface{}{"lease_owner": owner, "lease_until": leaseUntil})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			claimed = append(claimed, id)
+		}
+	}
+	return claimed, nil
+}
+
+// renewLease 处理一条记录前把 owner 的租约延长到 leaseUntil，返回租约是否仍归 owner 所有。
+// 租约过期后只要没有被其他实例领取（lease_owner 未变）仍可续上；已被领取时返回 false，调用方不能再处理这条记录
+func renewLease(model interface{}, id uint, owner string, leaseUntil time.Time) (bool, error) {
+	result := config.DB.Model(model).
+		Where("id = ? AND status = ? AND lease_owner = ?", id, "pending", owner).
+		Update("lease_until", leaseUntil)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,371 @@
+// Package memory 提供仓库接口的内存实现，用于不依赖数据库的服务层测试。
+// 只实现任务和用户仓库，因此只能测试注入了仓库的 service.TaskService 和 service.UserService
+package memory
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	models "myproject/internal/model"
+	"myproject/internal/repository"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Store 保存在内存中的任务、标签和用户。
+// Do 中的操作串行执行，fn 返回错误时恢复到事务开始前的状态；事务进行期间其它写入也会被一同回滚，
+// 所以只适合测试和单协程使用
+type Store struct {
+	tx sync.Mutex // 串行执行 Do
+
+	mu     sync.Mutex
+	nextID uint
+	tasks  map[uint]models.Task
+	tags   map[uint]models.Tag
+	users  map[uint]models.User
+}
+
+// New 创建空的内存存储
+func New() *Store {
+	return &Store{
+		tasks: make(map[uint]models.Task),
+		tags:  make(map[uint]models.Tag),
+		users: make(map[uint]models.User),
+	}
+}
+
+// Tasks 返回使用该存储的 TaskRepository
+func (s *Store) Tasks() repository.TaskRepository { return taskRepository{s} }
+
+// Users 返回使用该存储的 UserRepository
+func (s *Store) Users() repository.UserRepository { return userRepository{s} }
+
+// Do 实现 repository.UnitOfWork
+func (s *Store) Do(fn func(repos repository.Repositories) error) error {
+	s.tx.Lock()
+	defer s.tx.Unlock()
+
+	s.mu.Lock()
+	saved := s.snapshot()
+	s.mu.Unlock()
+
+	if err := fn(repository.Repositories{Tasks: s.Tasks(), Users: s.Users()}); err != nil {
+		s.mu.Lock()
+		s.restore(saved)
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+type snapshot struct {
+	nextID uint
+	tasks  map[uint]models.Task
+	tags   map[uint]models.Tag
+	users  map[uint]models.User
+}
+
+func (s *Store) snapshot() snapshot {
+	saved := snapshot{
+		nextID: s.nextID,
+		tasks:  make(map[uint]models.Task, len(s.tasks)),
+		tags:   make(map[uint]models.Tag, len(s.tags)),
+		users:  make(map[uint]models.User, len(s.users)),
+	}
+	for id, task := range s.tasks {
+		saved.tasks[id] = cloneTask(task)
+	}
+	for id, tag := range s.tags {
+		saved.tags[id] = tag
+	}
+	for id, user := range s.users {
+		saved.users[id] = cloneUser(user)
+	}
+	return saved
+}
+
+func (s *Store) restore(saved snapshot) {
+	s.nextID = saved.nextID
+	s.tasks = saved.tasks
+	s.tags = saved.tags
+	s.users = saved.users
+}
+
+// newID 分配新的主键，所有表共用一个序列
+func (s *Store) newID() uint {
+	s.nextID++
+	return s.nextID
+}
+
+type taskRepository struct{ s *Store }
+
+func (r taskRepository) Create(task *models.Task) error {
+	s := r.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	task.ID = s.newID()
+	task.Revision = 1
+	task.FieldStamps = task.FieldStamps.Stamp(models.SyncFields, task.Revision, now)
+	if task.Status == "" {
+		task.Status = "pending"
+	}
+	if task.CreatedAt.IsZero() {
+		task.CreatedAt = now
+	}
+	task.UpdatedAt = now
+	s.tasks[task.ID] = cloneTask(*task)
+	return nil
+}
+
+func (r taskRepository) GetByID(id string, userID uint) (*models.Task, error) {
+	s := r.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.findTask(id, userID)
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &task, nil
+}
+
+func (r taskRepository) ListByUserAndDate(userID uint, date string) ([]models.Task, error) {
+	s := r.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := time.ParseInLocation("2006-01-02", date, time.Local); err != nil {
+		return nil, repository.ErrInvalidDate
+	}
+
+	var tasks []models.Task
+	for _, task := range s.tasks {
+		if task.UserID == userID && task.CreatedAt.In(time.Local).Format("2006-01-02") == date {
+			tasks = append(tasks, cloneTask(task))
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+	return tasks, nil
+}
+
+func (r taskRepository) Update(task *models.Task, updates map[string]interface{}) error {
+	return r.update(task, updates, false)
+}
+
+func (r taskRepository) UpdateIfUnchanged(task *models.Task, updates map[string]interface{}) error {
+	return r.update(task, updates, true)
+}
+
+// update 把 updates 写入存储中的任务。内存中的写入是串行的，不需要像数据库那样重试，
+// 直接在最新版本上修改；conditional 为 true 时版本不一致则返回 ErrRevisionMismatch
+func (r taskRepository) update(task *models.Task, updates map[string]interface{}, conditional bool) error {
+	s := r.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.tasks[task.ID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	if conditional && stored.Revision != task.Revision {
+		return repository.ErrRevisionMismatch
+	}
+
+	fields := make([]string, 0, len(updates))
+	for column, value := range updates {
+		fields = append(fields, column)
+		if column == "tags" {
+			tags, _ := value.([]models.Tag)
+			stored.Tags = append([]models.Tag(nil), tags...)
+			continue
+		}
+		if err := setColumn(&stored, column, value); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	stored.Revision++
+	stored.FieldStamps = stored.FieldStamps.Stamp(fields, stored.Revision, now)
+	stored.UpdatedAt = now
+	s.tasks[task.ID] = cloneTask(stored)
+	*task = stored
+	return nil
+}
+
+func (r taskRepository) Delete(task *models.Task) error {
+	return r.delete(task, false)
+}
+
+func (r taskRepository) DeleteIfUnchanged(task *models.Task) error {
+	return r.delete(task, true)
+}
+
+func (r taskRepository) delete(task *models.Task, conditional bool) error {
+	s := r.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.tasks[task.ID]
+	if !ok || (conditional && stored.Revision != task.Revision) {
+		return repository.ErrRevisionMismatch
+	}
+	delete(s.tasks, task.ID)
+	return nil
+}
+
+func (r taskRepository) FindOrCreateTags(userID uint, names []string) ([]models.Tag, error) {
+	s := r.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	byName := make(map[string]models.Tag)
+	for _, tag := range s.tags {
+		if tag.UserID == userID {
+			byName[tag.Name] = tag
+		}
+	}
+
+	var result []models.Tag
+	for _, name := range names {
+		tag, ok := byName[name]
+		if !ok {
+			tag = models.Tag{ID: s.newID(), Name: name, UserID: userID, CreatedAt: time.Now()}
+			s.tags[tag.ID] = tag
+			byName[name] = tag
+		}
+		result = append(result, tag)
+	}
+	return result, nil
+}
+
+// findTask 按字符串形式的ID查找用户的任务，返回副本
+func (s *Store) findTask(id string, userID uint) (models.Task, bool) {
+	taskID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return models.Task{}, false
+	}
+	task, ok := s.tasks[uint(taskID)]
+	if !ok || task.UserID != userID {
+		return models.Task{}, false
+	}
+	return cloneTask(task), true
+}
+
+type userRepository struct{ s *Store }
+
+func (r userRepository) Create(user *models.User) error {
+	s := r.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.users {
+		if existing.Username == user.Username || existing.Email == user.Email {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+
+	now := time.Now()
+	user.ID = s.newID()
+	if user.Role == "" {
+		user.Role = "user"
+	}
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = now
+	}
+	user.UpdatedAt = now
+	s.users[user.ID] = cloneUser(*user)
+	return nil
+}
+
+func (r userRepository) GetByID(id uint) (*models.User, error) {
+	return r.find(func(user models.User) bool { return user.ID == id })
+}
+
+func (r userRepository) GetByUsername(username string) (*models.User, error) {
+	return r.find(func(user models.User) bool { return user.Username == username })
+}
+
+func (r userRepository) GetByEmail(email string) (*models.User, error) {
+	return r.find(func(user models.User) bool { return user.Email == email })
+}
+
+func (r userRepository) find(match func(user models.User) bool) (*models.User, error) {
+	s := r.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		if match(user) {
+			user = cloneUser(user)
+			return &user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r userRepository) Update(user *models.User, updates map[string]interface{}) error {
+	s := r.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[user.ID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	for column, value := range updates {
+		if err := setColumn(&stored, column, value); err != nil {
+			return err
+		}
+	}
+	stored.UpdatedAt = time.Now()
+	s.users[user.ID] = cloneUser(stored)
+	*user = stored
+	return nil
+}
+
+// schemas 缓存模型解析结果，列名到字段的对应关系与 GORM 一致
+var schemas sync.Map
+
+// setColumn 按 GORM 的列名把 value 写入模型的字段
+func setColumn(model interface{}, column string, value interface{}) error {
+	sch, err := schema.Parse(model, &schemas, schema.NamingStrategy{})
+	if err != nil {
+		return err
+	}
+	field := sch.LookUpField(column)
+	if field == nil {
+		return fmt.Errorf("memory: unknown column %q for %s", column, sch.Table)
+	}
+	return field.Set(context.Background(), reflect.ValueOf(model).Elem(), value)
+}
+
+// cloneTask 复制任务，避免调用方修改存储中的切片和映射
+func cloneTask(task models.Task) models.Task {
+	task.Tags = append([]models.Tag(nil), task.Tags...)
+	task.FieldStamps = task.FieldStamps.Clone()
+	return task
+}
+
+func cloneUser(user models.User) models.User {
+	user.Permissions = append([]string(nil), user.Permissions...)
+	return user
+}
+
+var (
+	_ repository.TaskRepository = taskRepository{}
+	_ repository.UserRepository = userRepository{}
+	_ repository.UnitOfWork     = (*Store)(nil)
+)
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"time"
+
+	"myproject/config"
+	models "myproject/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// ConsumeTOTPStep 记录通过校验的时间步，只有比上次更新的时间步才能成功，返回是否成功
+func ConsumeTOTPStep(userID uint, step int64) (bool, error) {
+	result := config.DB.Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", userID, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// EnableTOTP 启用两步验证并替换恢复码
+func EnableTOTP(userID uint, step int64, codes []models.RecoveryCode) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"totp_enabled":   true,
+			"totp_last_step": step,
+		}).Error; err != nil {
+			return err
+		}
+		return replaceRecoveryCodes(tx, userID, codes)
+	})
+}
+
+// DisableTOTP 关闭两步验证，清除密钥和恢复码
+func DisableTOTP(userID uint) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"totp_enabled":   false,
+			"totp_secret":    "",
+			"totp_last_step": 0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+// ReplaceRecoveryCodes 作废旧的恢复码并保存新的
+func ReplaceRecoveryCodes(userID uint, codes []models.RecoveryCode) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, codes)
+	})
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uint, codes []models.RecoveryCode) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	return tx.Create(&codes).Error
+}
+
+// UseRecoveryCode 把未使用的恢复码标记为已使用，返回是否成功
+func UseRecoveryCode(userID uint, hash string, at time.Time) (bool, error) {
+	result := config.DB.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", at)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// CountUnusedRecoveryCodes 统计剩余可用的恢复码
+func CountUnusedRecoveryCodes(userID uint) (int64, error) {
+	var count int64
+	err := config.DB.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"testing"
+
+	"myproject/internal/testutil"
+)
+
+// 同一时间步或更早的时间步只能使用一次，防止验证码被重放
+func TestConsumeTOTPStepRejectsReplay(t *testing.T) {
+	testutil.OpenDB(t)
+	alice := testutil.CreateUser(t, "alice")
+	bob := testutil.CreateUser(t, "bob")
+
+	steps := []struct {
+		userID uint
+		step   int64
+		want   bool
+	}{
+		{alice.ID, 100, true},
+		{alice.ID, 100, false}, // 重放
+		{alice.ID, 99, false},  // 更早的时间步
+		{bob.ID, 100, true},    // 其他用户不受影响
+		{alice.ID, 101, true},
+		{alice.ID, 100, false},
+	}
+	for i, s := range steps {
+		ok, err := ConsumeTOTPStep(s.userID, s.step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != s.want {
+			t.Errorf("#%d user %d step %d: consumed = %v, want %v", i, s.userID, s.step, ok, s.want)
+		}
+	}
+}
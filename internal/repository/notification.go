@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"time"
+
+	"myproject/config"
+	models "myproject/internal/model"
+
+	"gorm.io/gorm/clause"
+)
+
+// CreateNotification 创建站内通知，同一提醒重复创建时忽略
+func CreateNotification(notification *models.Notification) error {
+	return config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(notification).Error
+}
+
+// GetNotificationsByUser 获取用户的通知，最新的在前
+func GetNotificationsByUser(userID uint, includeDismissed bool, limit int) ([]models.Notification, error) {
+	query := config.DB.Where("user_id = ?", userID)
+	if !includeDismissed {
+		query = query.Where("dismissed_at IS NULL")
+	}
+
+	var notifications []models.Notification
+	if err := query.
+		Order("id DESC").
+		Limit(limit).
+		Find(&notifications).Error; err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// GetNotificationByID 获取单条通知
+func GetNotificationByID(id string, userID uint) (*models.Notification, error) {
+	var notification models.Notification
+	if err := config.DB.
+		Where("id = ? AND user_id = ?", id, userID).
+		First(&notification).Error; err != nil {
+		return nil, err
+	}
+	return &notification, nil
+}
+
+// DismissNotification 将通知标记为已关闭
+func DismissNotification(notification *models.Notification, at time.Time) error {
+	return config.DB.Model(notification).Update("dismissed_at", at).Error
+}
+
+// DismissAllNotifications 关闭用户全部未关闭的通知
+func DismissAllNotifications(userID uint, at time.Time) error {
+	return config.DB.Model(&models.Notification{}).
+		Where("user_id = ? AND dismissed_at IS NULL", userID).
+		Update("dismissed_at", at).Error
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"time"
+
+	"myproject/config"
+	models "myproject/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// CreateReminder 创建提醒
+func CreateReminder(reminder *models.Reminder) error {
+	return config.DB.Create(reminder).Error
+}
+
+// GetRemindersByTask 获取任务的全部提醒
+func GetRemindersByTask(taskID, userID uint) ([]models.Reminder, error) {
+	var reminders []models.Reminder
+	if err := config.DB.
+		Where("task_id = ? AND user_id = ?", taskID, userID).
+		Order("fire_at").
+		Find(&reminders).Error; err != nil {
+		return nil, err
+	}
+	return reminders, nil
+}
+
+// GetReminderByID 获取单个提醒
+func GetReminderByID(id string, taskID, userID uint) (*models.Reminder, error) {
+	var reminder models.Reminder
+	if err := config.DB.
+		Where("id = ? AND task_id = ? AND user_id = ?", id, taskID, userID).
+		First(&reminder).Error; err != nil {
+		return nil, err
+	}
+	return &reminder, nil
+}
+
+// DeleteReminder 删除提醒
+func DeleteReminder(reminder *models.Reminder) error {
+	return config.DB.Delete(reminder).Error
+}
+
+// RescheduleOffsetReminders 按任务当前的截止时间重新计算尚未触发、按偏移设置的提醒；
+// 截止时间被清空时这些提醒不再触发
+func RescheduleOffsetReminders(task *models.Task) error {
+	return rescheduleOffsetReminders(config.DB, task)
+}
+
+func rescheduleOffsetReminders(tx *gorm.DB, task *models.Task) error {
+	var reminders []models.Reminder
+	if err := tx.
+		Where("task_id = ? AND status = ? AND offset_minutes IS NOT NULL", task.ID, "pending").
+		Find(&reminders).Error; err != nil {
+		return err
+	}
+
+	for i := range reminders {
+		updates := map[string]interface{}{}
+		if task.DueDate == nil {
+			updates["status"] = "skipped"
+		} else {
+			updates["fire_at"] = task.DueDate.Add(-time.Duration(*reminders[i].OffsetMinutes) * time.Minute)
+		}
+		if err := tx.Model(&reminders[i]).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClaimDueReminders 为 owner 租用最多 limit 条已到期的提醒，租约到 leaseUntil 为止
+func ClaimDueReminders(owner string, now, leaseUntil time.Time, limit int) ([]models.Reminder, error) {
+	claimed, err := claimDue(&models.Reminder{}, "fire_at", owner, now, leaseUntil, limit)
+	if err != nil || len(claimed) == 0 {
+		return nil, err
+	}
+
+	var reminders []models.Reminder
+	if err := config.DB.
+		Preload("Task").
+		Preload("User").
+		Where("id IN ?", claimed).
+		Find(&reminders).Error; err != nil {
+		return nil, err
+	}
+	return reminders, nil
+}
+
+// RenewReminderLease 发送前续租，返回 false 表示提醒已被其他实例领取，不能再发送
+func RenewReminderLease(reminder *models.Reminder, owner string, leaseUntil time.Time) (bool, error) {
+	return renewLease(&models.Reminder{}, reminder.ID, owner, leaseUntil)
+}
+
+// FinishReminder 由租约持有者结束提醒（status 为 sent 或 skipped），租约已被别人拿走时不做任何修改
+func FinishReminder(reminder *models.Reminder, owner, status string, at time.Time) error {
+	return config.DB.Model(&models.Reminder{}).
+		Where("id = ? AND lease_owner = ?", reminder.ID, owner).
+		Updates(map[string]interface{}{
+			"status":      status,
+			"sent_at":     at,
+			"lease_owner": "",
+			"lease_until": nil,
+		}).Error
+}
+
+// RetryReminder 发送失败后释放租约，按 nextFireAt 重试；failed 为 true 时不再重试
+func RetryReminder(reminder *models.Reminder, owner string, nextFireAt time.Time, lastError string, failed bool) error {
+	status := "pending"
+	if failed {
+		status = "failed"
+	}
+	return config.DB.Model(&models.Reminder{}).
+		Where("id = ? AND lease_owner = ?", reminder.ID, owner).
+		Updates(map[string]interface{}{
+			"status":      status,
+			"attempts":    reminder.Attempts + 1,
+			"last_error":  lastError,
+			"fire_at":     nextFireAt,
+			"lease_owner": "",
+			"lease_until": nil,
+		}).Error
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	models "myproject/internal/model"
+)
+
+// 服务层通过 TaskRepository、UserRepository 和 UnitOfWork 访问任务和用户，目前只有 service.TaskService
+// 和 service.UserService 改为注入这些接口。CreateTask、GetUserByID 等包级函数是 GORM 实现在 config.DB 上的包装，
+// 供尚未迁移的服务使用，两条路径共用同样的版本号和变更日志逻辑；新增的 TaskService、UserService 代码只使用注入的接口
+
+// TaskRepository 任务及其标签的存取。查询不到时返回 gorm.ErrRecordNotFound
+type TaskRepository interface {
+	// Create 创建任务，版本号从 1 开始
+	Create(task *models.Task) error
+	// GetByID 获取用户的单个任务，包含标签
+	GetByID(id string, userID uint) (*models.Task, error)
+	// ListByUserAndDate 获取用户在服务器时区某天（YYYY-MM-DD）创建的任务，包含标签。日期格式错误时返回 ErrInvalidDate
+	ListByUserAndDate(userID uint, date string) ([]models.Task, error)
+	// Update 更新任务，被并发写入抢先时重新读取后重试。updates 的键为列名，"tags" 对应 []models.Tag
+	Update(task *models.Task, updates map[string]interface{}) error
+	// UpdateIfUnchanged 仅当存储中的版本仍为 task.Revision 时更新，否则返回 ErrRevisionMismatch
+	UpdateIfUnchanged(task *models.Task, updates map[string]interface{}) error
+	// Delete 删除任务及其提醒
+	Delete(task *models.Task) error
+	// DeleteIfUnchanged 仅当存储中的版本仍为 task.Revision 时删除，否则返回 ErrRevisionMismatch
+	DeleteIfUnchanged(task *models.Task) error
+	// FindOrCreateTags 按名称查找用户的标签，不存在的自动创建
+	FindOrCreateTags(userID uint, names []string) ([]models.Tag, error)
+}
+
+// UserRepository 用户的存取。查询不到时返回 gorm.ErrRecordNotFound
+type UserRepository interface {
+	// Create 创建用户，用户名或邮箱已存在时返回错误
+	Create(user *models.User) error
+	GetByID(id uint) (*models.User, error)
+	GetByUsername(username string) (*models.User, error)
+	GetByEmail(email string) (*models.User, error)
+	// Update 更新用户，updates 的键为列名
+	Update(user *models.User, updates map[string]interface{}) error
+}
+
+// Repositories 共享同一个事务的一组仓库
+type Repositories struct {
+	Tasks TaskRepository
+	Users UserRepository
+}
+
+// UnitOfWork 在一个事务中执行跨仓库的操作，fn 返回错误时全部回滚
+type UnitOfWork interface {
+	Do(fn func(repos Repositories) error) error
+}
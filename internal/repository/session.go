@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"time"
+
+	"myproject/config"
+	models "myproject/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// CreateSession 创建会话
+func CreateSession(session *models.Session) error {
+	return config.DB.Create(session).Error
+}
+
+// GetSession 根据ID获取会话
+func GetSession(id uint) (*models.Session, error) {
+	var session models.Session
+	if err := config.DB.First(&session, id).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetActiveSessionsByUser 获取用户未吊销且未过期的会话，最近活跃的在前
+func GetActiveSessionsByUser(userID uint, now time.Time) ([]models.Session, error) {
+	var sessions []models.Session
+	err := config.DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// GetActiveSessionByID 获取用户的某个有效会话
+func GetActiveSessionByID(id string, userID uint, now time.Time) (*models.Session, error) {
+	var session models.Session
+	err := config.DB.Where("id = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > ?", id, userID, now).
+		First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// ExtendSession 刷新令牌后更新会话的有效期和客户端信息
+func ExtendSession(id uint, updates map[string]interface{}) error {
+	return config.DB.Model(&models.Session{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// TouchSession 更新会话的最后活跃时间。只有上次记录早于 before 时才写入，
+// 并发请求中只有一个会真正落库
+func TouchSession(id uint, at, before time.Time) error {
+	return config.DB.Model(&models.Session{}).
+		Where("id = ? AND last_seen_at < ?", id, before).
+		Update("last_seen_at", at).Error
+}
+
+// RevokeSession 吊销会话及其全部刷新令牌
+func RevokeSession(id uint, at time.Time) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Session{}).
+			Where("id = ? AND revoked_at IS NULL", id).
+			Update("revoked_at", at).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.RefreshToken{}).
+			Where("session_id = ? AND revoked_at IS NULL", id).
+			Update("revoked_at", at).Error
+	})
+}
+
+// RevokeUserSessions 吊销用户的全部会话及刷新令牌
+func RevokeUserSessions(userID uint, at time.Time) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Session{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", at).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", at).Error
+	})
+}
+
+// RevokeOtherSessions 吊销用户除 keepID 以外的全部会话及刷新令牌
+func RevokeOtherSessions(userID, keepID uint, at time.Time) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Session{}).
+			Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, keepID).
+			Update("revoked_at", at).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND session_id <> ? AND revoked_at IS NULL", userID, keepID).
+			Update("revoked_at", at).Error
+	})
+}
+
+// DeleteExpiredSessions 删除已过期的会话
+func DeleteExpiredSessions(now time.Time) error {
+	return config.DB.Where("expires_at < ?", now).Delete(&models.Session{}).Error
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"myproject/config"
+	models "myproject/internal/model"
+)
+
+// ChangedTask 同步区间内发生过变更的任务及其最后一次变更的序号
+type ChangedTask struct {
+	TaskID uint
+	Seq    uint64
+}
+
+// GetLatestChangeSeq 获取已提交的最大变更序号。序号按提交顺序分配，不大于它的变更都已可见
+func GetLatestChangeSeq() (uint64, error) {
+	var seq *uint64
+	if err := config.DB.Model(&models.TaskChange{}).Select("MAX(seq)").Scan(&seq).Error; err != nil {
+		return 0, err
+	}
+	if seq == nil {
+		return 0, nil
+	}
+	return *seq, nil
+}
+
+// GetChangedTasks 获取用户在 (after, upTo] 区间内变更过的任务，按最后一次变更的序号升序，最多 limit 条
+func GetChangedTasks(userID uint, after, upTo uint64, limit int) ([]ChangedTask, error) {
+	var changed []ChangedTask
+	if err := config.DB.Model(&models.TaskChange{}).
+		Select("task_id, MAX(seq) AS seq").
+		Where("user_id = ? AND seq > ? AND seq <= ?", userID, after, upTo).
+		Group("task_id").
+		Order("seq").
+		Limit(limit).
+		Scan(&changed).Error; err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+// GetTasksByIDs 批量获取用户的任务，已删除的任务不会出现在结果中
+func GetTasksByIDs(userID uint, ids []uint) ([]models.Task, error) {
+	var tasks []models.Task
+	if len(ids) == 0 {
+		return tasks, nil
+	}
+	if err := config.DB.
+		Preload("Tags").
+		Where("user_id = ? AND id IN ?", userID, ids).
+		Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"myproject/config"
+	models "myproject/internal/model"
+	"myproject/internal/testutil"
+
+	"gorm.io/gorm"
+)
+
+func TestChangeSeqContinuesFromExistingChangeLog(t *testing.T) {
+	testutil.OpenDB(t)
+	user := testutil.CreateUser(t, "alice")
+	if err := config.DB.Create(&models.TaskChange{Seq: 41, UserID: user.ID, TaskID: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for want := uint64(42); want <= 43; want++ {
+		task := models.Task{Description: "write", UserID: user.ID}
+		if err := CreateTask(&task); err != nil {
+			t.Fatal(err)
+		}
+		if latest, _ := GetLatestChangeSeq(); latest != want {
+			t.Errorf("latest seq = %d, want %d", latest, want)
+		}
+	}
+}
+
+// 写入 A 先分配序号但迟迟不提交，写入 B 随后开始。期间拿到的同步令牌之后必须能取到两者的变更，
+// 不能因为 B 先提交了更大的序号而跳过 A
+func TestSyncTokenDoesNotSkipChangesCommittedOutOfOrder(t *testing.T) {
+	testutil.OpenFileDB(t)
+	user := testutil.CreateUser(t, "alice")
+
+	aInFlight := make(chan struct{})
+	releaseA := make(chan struct{})
+	aDone := make(chan error, 1)
+	var taskA models.Task
+	go func() {
+		aDone <- config.DB.Transaction(func(tx *gorm.DB) error {
+			taskA = models.Task{Description: "A", UserID: user.ID}
+			if err := createTask(tx, &taskA, time.Now()); err != nil {
+				return err
+			}
+			close(aInFlight)
+			<-releaseA
+			return nil
+		})
+	}()
+	<-aInFlight
+
+	bDone := make(chan error, 1)
+	taskB := models.Task{Description: "B", UserID: user.ID}
+	go func() {
+		bDone <- CreateTask(&taskB)
+	}()
+
+	// 给 B 足够的时间抢在 A 之前提交（如果序号不按提交顺序分配的话）
+	time.Sleep(200 * time.Millisecond)
+	token, err := GetLatestChangeSeq()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	close(releaseA)
+	if err := <-aDone; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-bDone; err != nil {
+		t.Fatal(err)
+	}
+
+	latest, err := GetLatestChangeSeq()
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := GetChangedTasks(user.ID, 0, token, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := GetChangedTasks(user.ID, token, latest, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 令牌之前没有任何变更已提交，两次变更都应在令牌之后
+	if len(before) != 0 || len(after) != 2 {
+		t.Fatalf("token %d: %d changes before, %d after, want 0 and 2", token, len(before), len(after))
+	}
+	if after[0].TaskID != taskA.ID || after[1].TaskID != taskB.ID {
+		t.Errorf("changes %+v, want A (%d) before B (%d)", after, taskA.ID, taskB.ID)
+	}
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"myproject/config"
+	models "myproject/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FindOrCreateTags 按名称查找用户的标签，不存在的自动创建
+func FindOrCreateTags(userID uint, names []string) ([]models.Tag, error) {
+	return findOrCreateTags(config.DB, userID, names)
+}
+
+func findOrCreateTags(db *gorm.DB, userID uint, names []string) ([]models.Tag, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	tags := make([]models.Tag, 0, len(names))
+	for _, name := range names {
+		tags = append(tags, models.Tag{Name: name, UserID: userID})
+	}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&tags).Error; err != nil {
+		return nil, err
+	}
+
+	var result []models.Tag
+	if err := db.
+		Where("user_id = ? AND name IN ?", userID, names).
+		Find(&result).Error; err != nil {
+		return nil, err
+	}
+	return result, nil
+}
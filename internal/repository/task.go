@@ -1,43 +1,356 @@
 package repository
 
 import (
+	"errors"
+	"time"
+
 	"myproject/config"
 	models "myproject/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// CreateTask 创建任务
-func CreateTask(task *models.Task) error {
-	return config.DB.Create(task).Error
+// taskChangeCounter 任务变更序号在 SyncCounter 中的名称
+const taskChangeCounter = "task_changes"
+
+var (
+	// ErrRevisionConflict 并发写入同一任务，多次重试后仍未成功
+	ErrRevisionConflict = errors.New("task revision conflict")
+	// ErrRevisionMismatch 条件写入时任务已被修改
+	ErrRevisionMismatch = errors.New("task revision mismatch")
+	// ErrInvalidDate 日期不是 YYYY-MM-DD 格式
+	ErrInvalidDate = errors.New("invalid date")
+)
+
+// 乐观锁冲突时的最大重试次数
+const maxRevisionRetries = 5
+
+// GormTaskRepository 基于 GORM 的 TaskRepository
+type GormTaskRepository struct {
+	db *gorm.DB
 }
 
-// GetTasksByUserAndDate 根据用户和日期获取任务列表
-func GetTasksByUserAndDate(userID uint, date string) ([]models.Task, error) {
+// NewGormTaskRepository 创建使用 db 的任务仓库，db 可以是事务
+func NewGormTaskRepository(db *gorm.DB) *GormTaskRepository {
+	return &GormTaskRepository{db: db}
+}
+
+// Create 创建任务
+func (r *GormTaskRepository) Create(task *models.Task) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return createTask(tx, task, time.Now())
+	})
+}
+
+// GetByID 获取单个任务
+func (r *GormTaskRepository) GetByID(id string, userID uint) (*models.Task, error) {
+	var task models.Task
+	if err := r.db.
+		Preload("Tags").
+		Where("id = ? AND user_id = ?", id, userID).
+		First(&task).Error; err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// ListByUserAndDate 根据用户和日期获取任务列表。按服务器时区当天的起止时间查询，
+// 不依赖数据库的日期函数，也能使用 created_at 上的索引
+func (r *GormTaskRepository) ListByUserAndDate(userID uint, date string) ([]models.Task, error) {
+	start, end, err := dayRange(date)
+	if err != nil {
+		return nil, err
+	}
+
 	var tasks []models.Task
-	if err := config.DB.
-		Where("user_id = ? AND DATE(created_at) = ?", userID, date).
+	if err := r.db.
+		Preload("Tags").
+		Where("user_id = ? AND created_at >= ? AND created_at < ?", userID, start, end).
 		Find(&tasks).Error; err != nil {
 		return nil, err
 	}
 	return tasks, nil
 }
 
+// dayRange 返回 date（YYYY-MM-DD）在服务器时区的开始时间和次日开始时间
+func dayRange(date string) (time.Time, time.Time, error) {
+	start, err := time.ParseInLocation("2006-01-02", date, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, ErrInvalidDate
+	}
+	return start, start.AddDate(0, 0, 1), nil
+}
+
+// Update 更新任务
+func (r *GormTaskRepository) Update(task *models.Task, updates map[string]interface{}) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return updateTask(tx, task, updates, time.Now(), true)
+	})
+}
+
+// UpdateIfUnchanged 仅当数据库中的版本仍为 task.Revision 时更新，否则返回 ErrRevisionMismatch
+func (r *GormTaskRepository) UpdateIfUnchanged(task *models.Task, updates map[string]interface{}) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return updateTask(tx, task, updates, time.Now(), false)
+	})
+}
+
+// Delete 删除任务及其提醒
+func (r *GormTaskRepository) Delete(task *models.Task) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return deleteTask(tx, task, tx)
+	})
+}
+
+// DeleteIfUnchanged 仅当数据库中的版本仍为 task.Revision 时删除，否则返回 ErrRevisionMismatch
+func (r *GormTaskRepository) DeleteIfUnchanged(task *models.Task) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return deleteTask(tx, task, tx.Where("revision = ?", task.Revision))
+	})
+}
+
+// FindOrCreateTags 按名称查找用户的标签，不存在的自动创建
+func (r *GormTaskRepository) FindOrCreateTags(userID uint, names []string) ([]models.Tag, error) {
+	return findOrCreateTags(r.db, userID, names)
+}
+
+// CreateTask 创建任务
+func CreateTask(task *models.Task) error {
+	return NewGormTaskRepository(config.DB).Create(task)
+}
+
+// GetTasksByUserAndDate 根据用户和日期获取任务列表
+func GetTasksByUserAndDate(userID uint, date string) ([]models.Task, error) {
+	return NewGormTaskRepository(config.DB).ListByUserAndDate(userID, date)
+}
+
 // GetTaskByID 获取单个任务
 func GetTaskByID(id string, userID uint) (*models.Task, error) {
+	return NewGormTaskRepository(config.DB).GetByID(id, userID)
+}
+
+// UpdateTask 更新任务。updates 的键为列名，其中 "tags" 对应 []models.Tag，表示替换全部标签
+func UpdateTask(task *models.Task, updates map[string]interface{}) error {
+	return NewGormTaskRepository(config.DB).Update(task, updates)
+}
+
+// UpdateTaskAtIfUnchanged 仅当数据库中的版本仍为 task.Revision 时更新，否则返回 ErrRevisionMismatch。
+// at 记为所改字段的修改时间（离线同步时为客户端的修改时间）
+func UpdateTaskAtIfUnchanged(task *models.Task, updates map[string]interface{}, at time.Time) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		return updateTask(tx, task, updates, at, false)
+	})
+}
+
+// UpdateTaskIfUnchanged 仅当数据库中的版本仍为 task.Revision 时更新，否则返回 ErrRevisionMismatch
+func UpdateTaskIfUnchanged(task *models.Task, updates map[string]interface{}) error {
+	return NewGormTaskRepository(config.DB).UpdateIfUnchanged(task, updates)
+}
+
+// DeleteTask 删除任务及其提醒
+func DeleteTask(task *models.Task) error {
+	return NewGormTaskRepository(config.DB).Delete(task)
+}
+
+// DeleteTaskIfUnchanged 仅当数据库中的版本仍为 task.Revision 时删除，否则返回 ErrRevisionMismatch
+func DeleteTaskIfUnchanged(task *models.Task) error {
+	return NewGormTaskRepository(config.DB).DeleteIfUnchanged(task)
+}
+
+// deleteTask 删除任务，scope 为删除任务本身时附加的条件
+func deleteTask(tx *gorm.DB, task *models.Task, scope *gorm.DB) error {
+	result := scope.Select("Tags").Delete(task)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRevisionMismatch
+	}
+	if err := tx.Where("task_id = ?", task.ID).Delete(&models.Reminder{}).Error; err != nil {
+		return err
+	}
+	return recordTaskChange(tx, task, true)
+}
+
+// createTask 写入新任务，所有同步字段记为 at 时修改
+func createTask(tx *gorm.DB, task *models.Task, at time.Time) error {
+	task.Revision = 1
+	task.FieldStamps = task.FieldStamps.Stamp(models.SyncFields, task.Revision, at)
+	if err := tx.Create(task).Error; err != nil {
+		return err
+	}
+	return recordTaskChange(tx, task, false)
+}
+
+// updateTask 以版本号作乐观锁写入更新。被其他写入抢先时，retry 为 true 则重新读取版本后重试，
+// 否则返回 ErrRevisionMismatch
+func updateTask(tx *gorm.DB, task *models.Task, updates map[string]interface{}, at time.Time, retry bool) error {
+	values := make(map[string]interface{}, len(updates)+2)
+	fields := make([]string, 0, len(updates))
+	var tags []models.Tag
+	replaceTags := false
+	for column, value := range updates {
+		fields = append(fields, column)
+		if column == "tags" {
+			tags, _ = value.([]models.Tag)
+			replaceTags = true
+			continue
+		}
+		values[column] = value
+	}
+
+	for i := 0; i < maxRevisionRetries; i++ {
+		revision := task.Revision
+		values["revision"] = revision + 1
+		values["field_stamps"] = task.FieldStamps.Stamp(fields, revision+1, at)
+
+		// 条件写入失败时不能改动调用方手里的 task，所以不用 Model(task)，成功后再重新读取
+		result := tx.Model(&models.Task{}).
+			Where("id = ? AND revision = ?", task.ID, revision).
+			Updates(values)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 1 {
+			if err := reloadTask(tx, task); err != nil {
+				return err
+			}
+			if replaceTags {
+				if err := tx.Model(task).Association("Tags").Replace(tags); err != nil {
+					return err
+				}
+			}
+			return recordTaskChange(tx, task, false)
+		}
+		if !retry {
+			return ErrRevisionMismatch
+		}
+
+		if err := reloadTask(tx, task); err != nil {
+			return err
+		}
+	}
+	return ErrRevisionConflict
+}
+
+// reloadTask 重新读取任务的列。读入新的结构体再整体替换，避免扫描到已有结构体时 NULL 列保留旧值
+func reloadTask(tx *gorm.DB, task *models.Task) error {
+	var fresh models.Task
+	if err := tx.First(&fresh, task.ID).Error; err != nil {
+		return err
+	}
+	fresh.Tags = task.Tags
+	*task = fresh
+	return nil
+}
+
+// recordTaskChange 追加一条变更日志，分配新的同步序号
+func recordTaskChange(tx *gorm.DB, task *models.Task, deleted bool) error {
+	seq, err := nextChangeSeq(tx)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&models.TaskChange{Seq: seq, UserID: task.UserID, TaskID: task.ID, Deleted: deleted}).Error
+}
+
+// nextChangeSeq 递增变更序号计数器并返回新值。计数器行的锁一直持有到 tx 提交，
+// 并发的写入事务按序号顺序依次提交，同步令牌之前的变更都已经可见
+func nextChangeSeq(tx *gorm.DB) (uint64, error) {
+	for i := 0; i < 2; i++ {
+		result := tx.Model(&models.SyncCounter{}).
+			Where("name = ?", taskChangeCounter).
+			UpdateColumn("value", gorm.Expr("value + 1"))
+		if result.Error != nil {
+			return 0, result.Error
+		}
+		if result.RowsAffected == 1 {
+			var seq uint64
+			err := tx.Model(&models.SyncCounter{}).Where("name = ?", taskChangeCounter).Select("value").Scan(&seq).Error
+			return seq, err
+		}
+
+		// 首次使用时从已有的变更日志初始化计数器，并发初始化时只有一个插入生效
+		var latest *uint64
+		if err := tx.Model(&models.TaskChange{}).Select("MAX(seq)").Scan(&latest).Error; err != nil {
+			return 0, err
+		}
+		counter := models.SyncCounter{Name: taskChangeCounter}
+		if latest != nil {
+			counter.Value = *latest
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&counter).Error; err != nil {
+			return 0, err
+		}
+	}
+	return 0, errors.New("sync counter not initialized")
+}
+
+// GetTaskByExternalID 根据外部ID获取任务
+func GetTaskByExternalID(externalID string, userID uint) (*models.Task, error) {
 	var task models.Task
 	if err := config.DB.
-		Where("id = ? AND user_id = ?", id, userID).
+		Where("external_id = ? AND user_id = ?", externalID, userID).
 		First(&task).Error; err != nil {
 		return nil, err
 	}
 	return &task, nil
 }
 
-// UpdateTask 更新任务
-func UpdateTask(task *models.Task, updates map[string]interface{}) error {
-	return config.DB.Model(task).Updates(updates).Error
+// ImportTask 在一个事务中导入一条任务。task.ExternalID 对应的任务已存在时，用 buildUpdates 返回的更新写入，
+// 标签替换为 tagNames，并按新的截止时间调整提醒，task 替换为更新后的任务；否则创建 task。
+// 不存在的标签在同一事务中创建，任何一步失败都不会留下部分写入。返回是否为新建
+func ImportTask(task *models.Task, tagNames []string, buildUpdates func(existing *models.Task) map[string]interface{}) (bool, error) {
+	created := false
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		tags, err := findOrCreateTags(tx, task.UserID, tagNames)
+		if err != nil {
+			return err
+		}
+
+		var existing models.Task
+		if task.ExternalID != nil {
+			err = tx.Where("external_id = ? AND user_id = ?", *task.ExternalID, task.UserID).First(&existing).Error
+		} else {
+			err = gorm.ErrRecordNotFound
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			created = true
+			task.Tags = tags
+			return createTask(tx, task, time.Now())
+		}
+		if err != nil {
+			return err
+		}
+
+		updates := buildUpdates(&existing)
+		updates["tags"] = tags
+		if err := updateTask(tx, &existing, updates, time.Now(), true); err != nil {
+			return err
+		}
+		existing.Tags = tags
+		if err := rescheduleOffsetReminders(tx, &existing); err != nil {
+			return err
+		}
+		*task = existing
+		return nil
+	})
+	return created, err
 }
 
-// DeleteTask 删除任务
-func DeleteTask(task *models.Task) error {
-	return config.DB.Delete(task).Error
-}
\ No newline at end of file
+// EachTaskByUser 分批遍历用户的全部任务，避免一次性加载到内存
+func EachTaskByUser(userID uint, batchSize int, fn func(task *models.Task) error) error {
+	var batch []models.Task
+	return config.DB.
+		Preload("Tags").
+		Where("user_id = ?", userID).
+		Order("id").
+		FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+			for i := range batch {
+				if err := fn(&batch[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	models "myproject/internal/model"
+	"myproject/internal/testutil"
+)
+
+func TestListByUserAndDateUsesLocalDayBoundaries(t *testing.T) {
+	db := testutil.OpenDB(t)
+	alice := testutil.CreateUser(t, "alice")
+	bob := testutil.CreateUser(t, "bob")
+	repo := NewGormTaskRepository(db)
+
+	midnight := time.Date(2024, 3, 10, 0, 0, 0, 0, time.Local)
+	create := func(user models.User, description string, createdAt time.Time) {
+		t.Helper()
+		task := models.Task{Description: description, UserID: user.ID, CreatedAt: createdAt}
+		if err := repo.Create(&task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	create(alice, "previous day", midnight.Add(-time.Second))
+	create(alice, "at midnight", midnight)
+	create(alice, "last second", midnight.AddDate(0, 0, 1).Add(-time.Second))
+	create(alice, "next day", midnight.AddDate(0, 0, 1))
+	create(bob, "other user", midnight.Add(time.Hour))
+
+	tests := []struct {
+		date string
+		want []string
+	}{
+		{"2024-03-09", []string{"previous day"}},
+		{"2024-03-10", []string{"at midnight", "last second"}},
+		{"2024-03-11", []string{"next day"}},
+		{"2024-03-12", nil},
+	}
+	for _, tt := range tests {
+		tasks, err := repo.ListByUserAndDate(alice.ID, tt.date)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.date, err)
+		}
+		var got []string
+		for _, task := range tasks {
+			got = append(got, task.Description)
+		}
+		sort.Strings(got)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.date, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: got %v, want %v", tt.date, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestListByUserAndDateRejectsInvalidDates(t *testing.T) {
+	db := testutil.OpenDB(t)
+	repo := NewGormTaskRepository(db)
+
+	for _, date := range []string{"", "2024-3-10", "2024-02-30", "10/03/2024", "2024-03-10T00:00:00Z"} {
+		if _, err := repo.ListByUserAndDate(1, date); err != ErrInvalidDate {
+			t.Errorf("%q: err = %v, want ErrInvalidDate", date, err)
+		}
+	}
+}
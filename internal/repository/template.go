@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"time"
+
+	"myproject/config"
+	models "myproject/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// CreateTemplate 创建模板
+func CreateTemplate(template *models.Template) error {
+	return config.DB.Create(template).Error
+}
+
+// GetTemplatesByUser 获取用户的全部模板
+func GetTemplatesByUser(userID uint) ([]models.Template, error) {
+	var templates []models.Template
+	if err := config.DB.
+		Where("user_id = ?", userID).
+		Order("id").
+		Find(&templates).Error; err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// GetTemplateByID 获取单个模板
+func GetTemplateByID(id string, userID uint) (*models.Template, error) {
+	var template models.Template
+	if err := config.DB.
+		Where("id = ? AND user_id = ?", id, userID).
+		First(&template).Error; err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// DeleteTemplate 删除模板
+func DeleteTemplate(template *models.Template) error {
+	return config.DB.Delete(template).Error
+}
+
+// GetSubtasks 获取一组任务的直接子任务
+func GetSubtasks(parentIDs []uint, userID uint) ([]models.Task, error) {
+	var tasks []models.Task
+	if err := config.DB.
+		Preload("Tags").
+		Where("parent_id IN ? AND user_id = ?", parentIDs, userID).
+		Order("id").
+		Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// CreateTaskTree 在一个事务中创建一组任务。
+// parents[i] 是第 i 个任务的父任务在 tasks 中的下标（-1 表示顶层），父任务必须排在子任务之前；
+// 任务的 Tags 只需要填写名称，不存在的标签会在同一事务中创建。
+func CreateTaskTree(tasks []models.Task, parents []int) error {
+	now := time.Now()
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		for i := range tasks {
+			task := &tasks[i]
+			if p := parents[i]; p >= 0 {
+				task.ParentID = &tasks[p].ID
+			}
+
+			names := make([]string, 0, len(task.Tags))
+			for _, tag := range task.Tags {
+				names = append(names, tag.Name)
+			}
+			tags, err := findOrCreateTags(tx, task.UserID, names)
+			if err != nil {
+				return err
+			}
+			task.Tags = tags
+
+			if err := createTask(tx, task, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"time"
+
+	"myproject/config"
+	models "myproject/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateRefreshToken 保存刷新令牌
+func CreateRefreshToken(token *models.RefreshToken) error {
+	return config.DB.Create(token).Error
+}
+
+// GetRefreshTokenByHash 根据摘要获取刷新令牌
+func GetRefreshTokenByHash(hash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	if err := config.DB.Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// RotateRefreshToken 把仍然有效的刷新令牌标记为已换发，返回是否由本次调用标记成功。
+// 并发的两次刷新只有一次能成功，另一次会被视为重复使用
+func RotateRefreshToken(token *models.RefreshToken, at time.Time) (bool, error) {
+	result := config.DB.Model(&models.RefreshToken{}).
+		Where("id = ? AND rotated_at IS NULL AND revoked_at IS NULL", token.ID).
+		Update("rotated_at", at)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RevokeAccessToken 把访问令牌的 jti 加入黑名单直到其过期
+func RevokeAccessToken(jti string, expiresAt time.Time) error {
+	return config.DB.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.RevokedToken{JTI: jti, ExpiresAt: expiresAt}).Error
+}
+
+// IsAccessTokenRevoked 判断 jti 是否在黑名单中
+func IsAccessTokenRevoked(jti string) (bool, error) {
+	var count int64
+	if err := config.DB.Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// CreateActionToken 保存一次性令牌
+func CreateActionToken(token *models.ActionToken) error {
+	return config.DB.Create(token).Error
+}
+
+// GetActionToken 获取一次性令牌记录
+func GetActionToken(jti string) (*models.ActionToken, error) {
+	var token models.ActionToken
+	if err := config.DB.Where("jti = ?", jti).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// UseActionToken 把未使用且未过期的一次性令牌标记为已使用，返回是否由本次调用标记成功
+func UseActionToken(jti string, userID uint, purpose string, at time.Time) (bool, error) {
+	result := config.DB.Model(&models.ActionToken{}).
+		Where("jti = ? AND user_id = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", jti, userID, purpose, at).
+		Update("used_at", at)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// FailActionToken 记录一次校验失败，失败次数达到 maxAttempts 时令牌作废
+func FailActionToken(jti string, maxAttempts int, at time.Time) error {
+	return config.DB.Model(&models.ActionToken{}).
+		Where("jti = ? AND used_at IS NULL", jti).
+		Updates(map[string]interface{}{
+			"attempts": gorm.Expr("attempts + 1"),
+			"used_at":  gorm.Expr("CASE WHEN attempts + 1 >= ? THEN ? ELSE NULL END", maxAttempts, at),
+		}).Error
+}
+
+// InvalidateActionTokens 作废用户某种用途的全部未使用令牌
+func InvalidateActionTokens(userID uint, purpose string, at time.Time) error {
+	return config.DB.Model(&models.ActionToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", at).Error
+}
+
+// DeleteExpiredTokens 清理已过期的刷新令牌、黑名单记录和一次性令牌
+func DeleteExpiredTokens(now time.Time) error {
+	if err := config.DB.Where("expires_at < ?", now).Delete(&models.RefreshToken{}).Error; err != nil {
+		return err
+	}
+	if err := config.DB.Where("expires_at < ?", now).Delete(&models.ActionToken{}).Error; err != nil {
+		return err
+	}
+	return config.DB.Where("expires_at < ?", now).Delete(&models.RevokedToken{}).Error
+}
@@ -0,0 +1,29 @@
+package repository
+
+import "gorm.io/gorm"
+
+// GormUnitOfWork 基于数据库事务的 UnitOfWork
+type GormUnitOfWork struct {
+	db *gorm.DB
+}
+
+// NewGormUnitOfWork 创建在 db 上开启事务的 UnitOfWork
+func NewGormUnitOfWork(db *gorm.DB) *GormUnitOfWork {
+	return &GormUnitOfWork{db: db}
+}
+
+// Do 开启事务，fn 中的仓库都在这个事务中读写。仓库方法自己开启的事务成为嵌套的保存点
+func (u *GormUnitOfWork) Do(fn func(repos Repositories) error) error {
+	return u.db.Transaction(func(tx *gorm.DB) error {
+		return fn(Repositories{
+			Tasks: NewGormTaskRepository(tx),
+			Users: NewGormUserRepository(tx),
+		})
+	})
+}
+
+var (
+	_ TaskRepository = (*GormTaskRepository)(nil)
+	_ UserRepository = (*GormUserRepository)(nil)
+	_ UnitOfWork     = (*GormUnitOfWork)(nil)
+)
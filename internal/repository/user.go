@@ -3,18 +3,115 @@ package repository
 import (
 	"myproject/config"
 	models "myproject/internal/model"
+
+	"gorm.io/gorm"
 )
 
-// CreateUser 创建用户
-func CreateUser(user *models.User) error {
-	return config.DB.Create(user).Error
+// GormUserRepository 基于 GORM 的 UserRepository
+type GormUserRepository struct {
+	db *gorm.DB
 }
 
-// GetUserByUsername 根据用户名查询用户
-func GetUserByUsername(username string) (*models.User, error) {
+// NewGormUserRepository 创建使用 db 的用户仓库，db 可以是事务
+func NewGormUserRepository(db *gorm.DB) *GormUserRepository {
+	return &GormUserRepository{db: db}
+}
+
+// Create 创建用户
+func (r *GormUserRepository) Create(user *models.User) error {
+	return r.db.Create(user).Error
+}
+
+// GetByID 根据ID查询用户
+func (r *GormUserRepository) GetByID(id uint) (*models.User, error) {
+	var user models.User
+	if err := r.db.First(&user, id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetByUsername 根据用户名查询用户
+func (r *GormUserRepository) GetByUsername(username string) (*models.User, error) {
 	var user models.User
-	if err := config.DB.Where("username = ?", username).First(&user).Error; err != nil {
+	if err := r.db.Where("username = ?", username).First(&user).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
+
+// GetByEmail 根据邮箱查询用户
+func (r *GormUserRepository) GetByEmail(email string) (*models.User, error) {
+	var user models.User
+	if err := r.db.Where("email = ?", email).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Update 更新用户
+func (r *GormUserRepository) Update(user *models.User, updates map[string]interface{}) error {
+	return r.db.Model(user).Updates(updates).Error
+}
+
+// CreateUser 创建用户
+func CreateUser(user *models.User) error {
+	return NewGormUserRepository(config.DB).Create(user)
+}
+
+// GetUserByUsername 根据用户名查询用户
+func GetUserByUsername(username string) (*models.User, error) {
+	return NewGormUserRepository(config.DB).GetByUsername(username)
+}
+
+// UpdateUser 更新用户
+func UpdateUser(user *models.User, updates map[string]interface{}) error {
+	return NewGormUserRepository(config.DB).Update(user, updates)
+}
+
+// UpdateUserRole 修改用户的角色和单独授予的权限
+func UpdateUserRole(user *models.User, role string, permissions []string) error {
+	return config.DB.Model(user).Select("role", "permissions").Updates(models.User{Role: role, Permissions: permissions}).Error
+}
+
+// GetUserByID 根据ID查询用户
+func GetUserByID(id uint) (*models.User, error) {
+	return NewGormUserRepository(config.DB).GetByID(id)
+}
+
+// GetUserByEmail 根据邮箱查询用户
+func GetUserByEmail(email string) (*models.User, error) {
+	return NewGormUserRepository(config.DB).GetByEmail(email)
+}
+
+// DeleteUser 在一个事务中删除用户及其全部数据。审计记录保留，但去掉能识别用户的信息
+func DeleteUser(user *models.User) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM task_tags WHERE task_id IN (SELECT id FROM tasks WHERE user_id = ?)", user.ID).Error; err != nil {
+			return err
+		}
+		webhooks := tx.Model(&models.Webhook{}).Select("id").Where("user_id = ?", user.ID)
+		if err := tx.Where("webhook_id IN (?)", webhooks).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+
+		owned := []interface{}{
+			&models.Reminder{}, &models.Notification{}, &models.Task{}, &models.Tag{}, &models.Template{},
+			&models.DigestSetting{}, &models.DigestLog{}, &models.Webhook{}, &models.EventLog{}, &models.TaskChange{},
+			&models.IdempotencyKey{}, &models.RefreshToken{}, &models.Session{}, &models.ActionToken{},
+			&models.RecoveryCode{}, &models.PersonalAccessToken{}, &models.UserIdentity{}, &models.DataExport{},
+		}
+		for _, model := range owned {
+			if err := tx.Where("user_id = ?", user.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&models.AuditLog{}).
+			Where("user_id = ? OR target = ?", user.ID, user.Username).
+			Updates(map[string]interface{}{"user_id": nil, "target": "", "ip": "", "user_agent": "", "detail": ""}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(user).Error
+	})
+}
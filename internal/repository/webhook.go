@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"time"
+
+	"myproject/config"
+	models "myproject/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// CreateWebhook 创建 webhook
+func CreateWebhook(webhook *models.Webhook) error {
+	return config.DB.Create(webhook).Error
+}
+
+// GetWebhooksByUser 获取用户的全部 webhook
+func GetWebhooksByUser(userID uint) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	if err := config.DB.
+		Where("user_id = ?", userID).
+		Order("id").
+		Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// GetActiveWebhooksByUser 获取用户启用中的 webhook
+func GetActiveWebhooksByUser(userID uint) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	if err := config.DB.
+		Where("user_id = ? AND active = ?", userID, true).
+		Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// GetWebhookByID 获取单个 webhook
+func GetWebhookByID(id string, userID uint) (*models.Webhook, error) {
+	var webhook models.Webhook
+	if err := config.DB.
+		Where("id = ? AND user_id = ?", id, userID).
+		First(&webhook).Error; err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// UpdateWebhook 更新 webhook
+func UpdateWebhook(webhook *models.Webhook, updates map[string]interface{}) error {
+	return config.DB.Model(webhook).Updates(updates).Error
+}
+
+// DeleteWebhook 删除 webhook 及其投递记录
+func DeleteWebhook(webhook *models.Webhook) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", webhook.ID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(webhook).Error
+	})
+}
+
+// CreateDeliveries 批量加入投递队列
+func CreateDeliveries(deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return config.DB.Create(&deliveries).Error
+}
+
+// GetDeliveriesByWebhook 获取 webhook 最近的投递记录
+func GetDeliveriesByWebhook(webhookID uint, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	if err := config.DB.
+		Where("webhook_id = ?", webhookID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// GetDeliveryByID 获取单条投递记录
+func GetDeliveryByID(id string, webhookID uint) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	if err := config.DB.
+		Where("id = ? AND webhook_id = ?", id, webhookID).
+		First(&delivery).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// ClaimDueDeliveries 为 owner 租用最多 limit 条到期的投递
+func ClaimDueDeliveries(owner string, now, leaseUntil time.Time, limit int) ([]models.WebhookDelivery, error) {
+	claimed, err := claimDue(&models.WebhookDelivery{}, "next_attempt_at", owner, now, leaseUntil, limit)
+	if err != nil || len(claimed) == 0 {
+		return nil, err
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := config.DB.
+		Preload("Webhook").
+		Where("id IN ?", claimed).
+		Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// RenewDeliveryLease 投递前续租，返回 false 表示投递已被其他实例领取，不能再发送
+func RenewDeliveryLease(delivery *models.WebhookDelivery, owner string, leaseUntil time.Time) (bool, error) {
+	return renewLease(&models.WebhookDelivery{}, delivery.ID, owner, leaseUntil)
+}
+
+// FinishDelivery 由租约持有者记录一次投递的结果并释放租约
+func FinishDelivery(delivery *models.WebhookDelivery, owner string, updates map[string]interface{}) error {
+	updates["lease_owner"] = ""
+	updates["lease_until"] = nil
+	return config.DB.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND lease_owner = ?", delivery.ID, owner).
+		Updates(updates).Error
+}
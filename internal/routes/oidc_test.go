@@ -0,0 +1,108 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"myproject/internal/oidc"
+	"myproject/internal/service"
+	"myproject/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 回调校验 state cookie，成功后跳转到前端并在 fragment 中带一次性登录码，令牌只能通过 /auth/exchange 换取
+func TestOIDCCallbackRedirectsWithLoginCode(t *testing.T) {
+	db := testutil.OpenDB(t)
+	testutil.UseSigningKey(t)
+	t.Setenv("APP_URL", "https://app.example.com")
+	mock := testutil.StartOIDCProvider(t, "taskflow")
+	service.SetOIDCProviders([]oidc.Config{{
+		Name: "mock", Issuer: mock.Issuer, ClientID: "taskflow", RedirectURL: "https://api.example.com/auth/mock/callback",
+		Scopes: []string{"openid", "email"}, AutoProvision: true,
+	}})
+	t.Cleanup(func() { service.SetOIDCProviders(nil) })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	SetupRoutes(r, NewHandlers(db))
+	identity := testutil.OIDCIdentity{Subject: "carol-sub", Email: "carol@example.com", EmailVerified: true}
+
+	// 发起登录
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/mock/login", nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("login: status %d: %s", w.Code, w.Body)
+	}
+	var stateCookie *http.Cookie
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "oauth_state" {
+			stateCookie = cookie
+		}
+	}
+	if stateCookie == nil || !stateCookie.HttpOnly || stateCookie.SameSite != http.SameSiteLaxMode {
+		t.Fatalf("state cookie = %+v, want an HttpOnly SameSite=Lax cookie", stateCookie)
+	}
+	authURL := w.Header().Get("Location")
+	authQuery := mustParseURL(t, authURL).Query()
+
+	callback := func(cookie *http.Cookie) url.Values {
+		code := mock.Authorize(t, authURL, identity)
+		req := httptest.NewRequest(http.MethodGet, "/auth/mock/callback?"+url.Values{"code": {code}, "state": {authQuery.Get("state")}}.Encode(), nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		location := mustParseURL(t, w.Header().Get("Location"))
+		if w.Code != http.StatusFound || location.Host != "app.example.com" || location.Path != "/auth/callback" {
+			t.Fatalf("callback: status %d, location %s", w.Code, location)
+		}
+		if strings.Contains(w.Body.String(), "refresh_token") {
+			t.Fatalf("callback response contains tokens: %s", w.Body)
+		}
+		fragment, _ := url.ParseQuery(location.Fragment)
+		return fragment
+	}
+
+	// 其它浏览器（没有 state cookie）打开回调链接
+	if result := callback(nil); result.Get("error") != "invalid_state" {
+		t.Fatalf("callback without the state cookie: %v, want error=invalid_state", result)
+	}
+
+	result := callback(stateCookie)
+	if result.Get("code") == "" {
+		t.Fatalf("callback: %v, want a login code", result)
+	}
+
+	body := `{"code":"` + result.Get("code") + `"}`
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/auth/exchange", strings.NewReader(body)))
+	var login struct {
+		Token string `json:"token"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &login)
+	if w.Code != http.StatusOK || login.Token == "" {
+		t.Fatalf("exchange: status %d: %s", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/auth/exchange", strings.NewReader(body)))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("reused login code: status %d, want 401", w.Code)
+	}
+}
+
+func mustParseURL(t *testing.T, raw string) *url.URL {
+	t.Helper()
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
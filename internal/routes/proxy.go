@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetTrustedProxies 只采信 proxies（逗号分隔的 IP 或 CIDR）中的反向代理转发的 X-Forwarded-For 和 X-Real-IP。
+// 为空时不信任任何代理，客户端 IP 取连接的对端地址。登录退避和审计日志都按客户端 IP 记录，
+// 采信任意来源的转发头会让客户端每次请求伪造一个新 IP
+func SetTrustedProxies(r *gin.Engine, proxies string) error {
+	var trusted []string
+	for _, proxy := range strings.Split(proxies, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			trusted = append(trusted, proxy)
+		}
+	}
+	return r.SetTrustedProxies(trusted)
+}
@@ -0,0 +1,44 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSetTrustedProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	clientIP := func(proxies, remoteAddr string) string {
+		t.Helper()
+		r := gin.New()
+		if err := SetTrustedProxies(r, proxies); err != nil {
+			t.Fatal(err)
+		}
+		r.GET("/ip", func(c *gin.Context) { c.String(http.StatusOK, c.ClientIP()) })
+
+		req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		req.Header.Set("X-Real-IP", "203.0.113.8")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	// 默认不信任任何代理，转发头被忽略
+	if ip := clientIP("", "198.51.100.1:1234"); ip != "198.51.100.1" {
+		t.Errorf("no trusted proxies: client IP = %s, want the peer address", ip)
+	}
+	if ip := clientIP("10.0.0.0/8, 192.168.1.1", "10.1.2.3:1234"); ip != "203.0.113.7" {
+		t.Errorf("request from a trusted proxy: client IP = %s, want the forwarded address", ip)
+	}
+	if ip := clientIP("10.0.0.0/8, 192.168.1.1", "198.51.100.1:1234"); ip != "198.51.100.1" {
+		t.Errorf("request from an untrusted peer: client IP = %s, want the peer address", ip)
+	}
+
+	if err := SetTrustedProxies(gin.New(), "not-an-ip"); err == nil {
+		t.Error("invalid proxy was accepted")
+	}
+}
@@ -3,15 +3,37 @@ package routes
 import (
 	"myproject/internal/handler"
 	"myproject/internal/middleware"
+	"myproject/internal/repository"
+	"myproject/internal/service"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"gorm.io/gorm"
 )
 
+// Handlers 依赖通过构造函数注入的接口。
+// 目前只有任务的增删改查（TaskHandler）和注册、登录、个人资料（UserHandler）改为注入仓库；
+// 模板、提醒、离线同步、导入导出、摘要、webhook、管理后台、两步验证、令牌和会话的接口仍是包级函数，
+// 通过 repository 的包级函数读写 config.DB。这些服务同时依赖尚无接口的模板、提醒、变更日志、会话等存储，
+// 需要整体迁移，不能只替换其中任务和用户的读写
+type Handlers struct {
+	Users *handler.UserHandler
+	Tasks *handler.TaskHandler
+}
+
+// NewHandlers 使用 db 上的 GORM 仓库组装接口
+func NewHandlers(db *gorm.DB) Handlers {
+	uow := repository.NewGormUnitOfWork(db)
+	return Handlers{
+		Users: handler.NewUserHandler(service.NewUserService(repository.NewGormUserRepository(db))),
+		Tasks: handler.NewTaskHandler(service.NewTaskService(repository.NewGormTaskRepository(db), uow)),
+	}
+}
+
 // SetupRoutes 配置所有路由
-	func SetupRoutes(r *gin.Engine) {
+	func SetupRoutes(r *gin.Engine, h Handlers) {
 		// Swagger 文档
 		r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -19,31 +41,175 @@ import (
 		r.Use(cors.New(cors.Config{
 			AllowOrigins:     []string{"http://localhost:5173", "http://localhost:3000", "http://127.0.0.1:5173"},
 			AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-			AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
+			AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "If-Match", "If-None-Match", "Idempotency-Key"},
+			ExposeHeaders:    []string{"ETag", "Idempotent-Replayed"},
 			AllowCredentials: true,
 		}))
 
 		// 公开路由
-		SetupPublicRoutes(r)
+		SetupPublicRoutes(r, h)
 		
 		// 需要认证的路由
-		SetupPrivateRoutes(r)
+		SetupPrivateRoutes(r, h)
 	}
 
-	func SetupPublicRoutes(r *gin.Engine) {
+	func SetupPublicRoutes(r *gin.Engine, h Handlers) {
 		// 添加路由
-		r.POST("/register", handler.Register)
-		r.POST("/login", handler.Login)
+		r.POST("/register", h.Users.Register)
+		r.POST("/login", h.Users.Login)
+		r.POST("/login/mfa", handler.LoginMFA)
+		r.POST("/token/refresh", handler.RefreshToken)
+
+		// 令牌签名公钥，供其它服务验证本服务签发的令牌
+		r.GET("/.well-known/jwks.json", handler.JWKS)
+
+		// 外部身份提供方登录
+		r.GET("/auth/providers", handler.GetOIDCProviders)
+		r.GET("/auth/:provider/login", handler.OIDCLogin)
+		r.GET("/auth/:provider/callback", handler.OIDCCallback)
+		r.POST("/auth/exchange", handler.OIDCExchange)
+
+		// 邮箱验证和找回密码
+		r.POST("/email/verify", handler.VerifyEmail)
+		r.POST("/email/verify/resend", handler.ResendVerificationEmail)
+		r.POST("/password/forgot", handler.ForgotPassword)
+		r.POST("/password/reset", handler.ResetPassword)
+		r.POST("/email/change/confirm", h.Users.ConfirmEmailChange)
+
+		// 个人数据导出的下载链接自带令牌
+		r.GET("/exports/download", handler.DownloadDataExport)
 	}
 
-	func SetupPrivateRoutes(r *gin.Engine) {
-		// 添加路由
-		tasks := r.Group("/tasks").Use(middleware.AuthMiddleware())
+	func SetupPrivateRoutes(r *gin.Engine, h Handlers) {
+		// 退出登录
+		auth := r.Group("").Use(middleware.AuthMiddleware(), middleware.SessionOnly(), middleware.Idempotency())
+		{
+			auth.POST("/logout", handler.Logout)
+			auth.POST("/logout-all", handler.LogoutAll)
+		}
+
+		// 个人资料和账号
+		me := r.Group("/me").Use(middleware.AuthMiddleware(), middleware.SessionOnly(), middleware.Idempotency())
+		{
+			me.GET("", handler.GetProfile)
+			me.PATCH("", h.Users.UpdateProfile)
+			me.DELETE("", handler.DeleteAccount)
+			me.POST("/password", h.Users.ChangePassword)
+			me.POST("/export", handler.RequestDataExport)
+			me.GET("/export/:id", handler.GetDataExport)
+		}
+
+		// 两步验证。响应中有 TOTP 密钥和恢复码，不能作为幂等响应保存，所以不挂 Idempotency
+		mfa := r.Group("/2fa").Use(middleware.AuthMiddleware(), middleware.SessionOnly())
+		{
+			mfa.POST("/setup", handler.SetupTOTP)
+			mfa.POST("/confirm", handler.ConfirmTOTP)
+			mfa.POST("/disable", handler.DisableTOTP)
+			mfa.POST("/recovery-codes", handler.RegenerateRecoveryCodes)
+		}
+
+		// 登录会话
+		sessions := r.Group("/sessions").Use(middleware.AuthMiddleware(), middleware.SessionOnly(), middleware.Idempotency())
+		{
+			sessions.GET("", handler.GetSessions)
+			sessions.DELETE("/:id", handler.RevokeSession)
+		}
+
+		// 个人访问令牌。创建时的响应是令牌明文，不能作为幂等响应保存，所以不挂 Idempotency
+		accessTokens := r.Group("/access-tokens").Use(middleware.AuthMiddleware(), middleware.SessionOnly())
+		{
+			accessTokens.GET("", handler.GetAccessTokens)
+			accessTokens.POST("", handler.CreateAccessToken)
+			accessTokens.DELETE("/:id", handler.DeleteAccessToken)
+		}
+
+		// 添加路由，用户需要对应模块的权限，个人访问令牌按请求方法需要读或写权限。
+		// 有写操作的分组都挂 Idempotency，客户端重试带相同 Idempotency-Key 的请求时回放首次响应
+		tasks := r.Group("/tasks").Use(middleware.AuthMiddleware(), middleware.RequirePermission(service.PermTasks), middleware.RequireScopes(service.ScopeTasksRead, service.ScopeTasksWrite), middleware.Idempotency())
+		{
+			tasks.GET("", h.Tasks.List)
+			tasks.POST("", h.Tasks.Create)
+			tasks.POST("/quick", h.Tasks.QuickAdd)
+			tasks.GET("/:id", h.Tasks.Get)
+			tasks.PUT("/:id", h.Tasks.Update)
+			tasks.PATCH("/:id", h.Tasks.Patch)
+			tasks.DELETE("/:id", h.Tasks.Delete)
+			tasks.GET("/:id/reminders", handler.GetReminders)
+			tasks.POST("/:id/reminders", handler.CreateReminder)
+			tasks.DELETE("/:id/reminders/:reminder_id", handler.DeleteReminder)
+		}
+
+		// 站内通知
+		notifications := r.Group("/notifications").Use(middleware.AuthMiddleware(), middleware.RequirePermission(service.PermNotifications), middleware.RequireScopes(service.ScopeNotificationsRead, service.ScopeNotificationsWrite), middleware.Idempotency())
+		{
+			notifications.GET("", handler.GetNotifications)
+			notifications.DELETE("", handler.DismissAllNotifications)
+			notifications.DELETE("/:id", handler.DismissNotification)
+		}
+
+		// 任务模板
+		templates := r.Group("/templates").Use(middleware.AuthMiddleware(), middleware.RequirePermission(service.PermTemplates), middleware.RequireScopes(service.ScopeTemplatesRead, service.ScopeTemplatesWrite), middleware.Idempotency())
+		{
+			templates.GET("", handler.GetTemplates)
+			templates.POST("", handler.CreateTemplate)
+			templates.GET("/:id", handler.GetTemplate)
+			templates.DELETE("/:id", handler.DeleteTemplate)
+			templates.POST("/:id/instantiate", middleware.RequirePermission(service.PermTasks), middleware.RequireScope(service.ScopeTasksWrite), handler.InstantiateTemplate)
+		}
+
+		// 每日摘要
+		digest := r.Group("/digest").Use(middleware.AuthMiddleware(), middleware.RequirePermission(service.PermDigest), middleware.RequireScopes(service.ScopeDigestRead, service.ScopeDigestWrite), middleware.Idempotency())
+		{
+			digest.GET("/settings", handler.GetDigestSetting)
+			digest.PUT("/settings", handler.UpdateDigestSetting)
+			digest.GET("/preview", handler.PreviewDigest)
+			digest.GET("/history", handler.GetDigestHistory)
+		}
+
+		// Webhook
+		webhooks := r.Group("/webhooks").Use(middleware.AuthMiddleware(), middleware.RequirePermission(service.PermWebhooks), middleware.RequireScopes(service.ScopeWebhooksRead, service.ScopeWebhooksWrite), middleware.Idempotency())
+		{
+			webhooks.GET("", handler.GetWebhooks)
+			webhooks.POST("", handler.CreateWebhook)
+			webhooks.GET("/:id", handler.GetWebhook)
+			webhooks.DELETE("/:id", handler.DeleteWebhook)
+			webhooks.GET("/:id/deliveries", handler.GetWebhookDeliveries)
+			webhooks.POST("/:id/deliveries/:delivery_id/redeliver", handler.RedeliverWebhook)
+		}
+
+		// 实时推送
+		stream := r.Group("/events").Use(middleware.StreamAuthMiddleware(), middleware.RequirePermission(service.PermTasks), middleware.RequireScope(service.ScopeTasksRead))
+		{
+			stream.GET("", handler.StreamEvents)
+			stream.GET("/ws", handler.TaskEventsWebSocket)
+		}
+
+		// 导入导出和离线同步
+		transfer := r.Group("").Use(middleware.AuthMiddleware(), middleware.RequirePermission(service.PermTasks), middleware.RequireScopes(service.ScopeTasksRead, service.ScopeTasksWrite), middleware.Idempotency())
+		{
+			transfer.GET("/export", handler.ExportTasks)
+			transfer.POST("/import", handler.ImportTasks)
+			transfer.POST("/sync", handler.Sync)
+		}
+
+		// 管理后台，只允许登录会话访问，查询需要读权限，修改需要写权限
+		adminUsers := r.Group("/admin/users").Use(middleware.AuthMiddleware(), middleware.SessionOnly(), middleware.RequirePermissions(service.PermAdminUsersRead, service.PermAdminUsersWrite), middleware.Idempotency())
+		{
+			adminUsers.GET("", handler.AdminListUsers)
+			adminUsers.GET("/:id", handler.AdminGetUser)
+			adminUsers.POST("/:id/disable", handler.AdminDisableUser)
+			adminUsers.POST("/:id/enable", handler.AdminEnableUser)
+			adminUsers.POST("/:id/reset-password", handler.AdminResetPassword)
+			adminUsers.PUT("/:id/role", handler.AdminSetUserRole)
+		}
+
+		adminStats := r.Group("/admin").Use(middleware.AuthMiddleware(), middleware.SessionOnly(), middleware.RequirePermission(service.PermAdminStats))
+		{
+			adminStats.GET("/stats", handler.AdminGetStats)
+		}
+
+		adminAudit := r.Group("/admin").Use(middleware.AuthMiddleware(), middleware.SessionOnly(), middleware.RequirePermission(service.PermAdminAudit))
 		{
-			tasks.GET("", handler.GetTasks)
-			tasks.POST("", handler.CreateTask)
-			// tasks.GET("/:id", handler.GetTask)
-			tasks.PUT("/:id", handler.UpdateTask)
-			tasks.DELETE("/:id", handler.DeleteTask)
+			adminAudit.GET("/audit-logs", handler.AdminGetAuditLogs)
 		}
 	}
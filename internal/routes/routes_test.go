@@ -0,0 +1,82 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"myproject/config"
+	models "myproject/internal/model"
+	"myproject/internal/service"
+	"myproject/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 会创建任务的写接口都要挂 Idempotency：带相同 Idempotency-Key 的重试只执行一次
+func TestTaskCreatingRoutesAreIdempotent(t *testing.T) {
+	db := testutil.OpenDB(t)
+	user := testutil.CreateUser(t, "alice")
+	_, token, err := service.CreateAccessToken(user, "test", service.Scopes, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template, err := service.CreateTemplate(user, "Release", "", []models.TemplateItem{{Description: "Tag release"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	SetupRoutes(r, NewHandlers(db))
+
+	tests := []struct {
+		name string
+		path string
+		body string
+	}{
+		{"import", "/import?format=json", `[{"description":"Imported"}]`},
+		{"sync", "/sync", `{"changes":[{"client_id":"c1","fields":{"description":"Synced"}}]}`},
+		{"instantiate", "/templates/" + strconv.FormatUint(uint64(template.ID), 10) + "/instantiate", `{}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := countTasks(t)
+			var first string
+			for i := 0; i < 2; i++ {
+				req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+				req.Header.Set("Authorization", "Bearer "+token)
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set("Idempotency-Key", tt.name)
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+
+				if w.Code >= 300 {
+					t.Fatalf("request %d: status %d: %s", i+1, w.Code, w.Body)
+				}
+				if i == 0 {
+					first = w.Body.String()
+					continue
+				}
+				if w.Header().Get("Idempotent-Replayed") != "true" || w.Body.String() != first {
+					t.Errorf("retry was not replayed: %s", w.Body)
+				}
+			}
+			if created := countTasks(t) - before; created != 1 {
+				t.Errorf("created %d tasks, want 1", created)
+			}
+		})
+	}
+}
+
+func countTasks(t *testing.T) int64 {
+	t.Helper()
+
+	var n int64
+	if err := config.DB.Model(&models.Task{}).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
@@ -0,0 +1,38 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"myproject/internal/service"
+)
+
+const cleanupInterval = time.Hour
+
+// RunCleanup 定期清理过期的数据，阻塞直到 ctx 结束
+func RunCleanup(ctx context.Context) {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+		if err := service.CleanupIdempotencyKeys(now); err != nil {
+			log.Printf("清理幂等键失败: %v", err)
+		}
+		if err := service.CleanupExpiredTokens(now); err != nil {
+			log.Printf("清理过期令牌失败: %v", err)
+		}
+		if err := service.CleanupLoginAttempts(now); err != nil {
+			log.Printf("清理登录失败计数失败: %v", err)
+		}
+		if err := service.CleanupDataExports(now); err != nil {
+			log.Printf("清理过期的数据导出失败: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"myproject/internal/digest"
+	"myproject/internal/mail"
+	models "myproject/internal/model"
+	"myproject/internal/repository"
+	"myproject/internal/service"
+)
+
+const (
+	digestInterval    = time.Minute
+	digestSendTimeout = 30 * time.Second
+)
+
+// DigestScheduler 每分钟检查开启了每日摘要的用户，到达其时区下的发送时间后发送当天摘要。
+// 发送前先在 digest_logs 中以租约占用（用户, 日期）这条唯一记录，多实例运行不会让同一天的摘要发出两次；
+// 发送超时远小于租约，进程在发送途中退出时租约过期，下一轮由任一实例接手重试。
+type DigestScheduler struct {
+	owner  string
+	lease  time.Duration
+	mailer mail.Mailer
+}
+
+func NewDigestScheduler(mailer mail.Mailer) *DigestScheduler {
+	return &DigestScheduler{owner: instanceID(), lease: defaultLease, mailer: mailer}
+}
+
+// Run 阻塞运行直到 ctx 结束
+func (s *DigestScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(digestInterval)
+	defer ticker.Stop()
+
+	for {
+		s.RunOnce(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce 为所有到达发送时间、当天还没发送过的用户发送摘要
+func (s *DigestScheduler) RunOnce(ctx context.Context, now time.Time) {
+	settings, err := repository.GetEnabledDigestSettings()
+	if err != nil {
+		log.Printf("查询摘要设置失败: %v", err)
+		return
+	}
+
+	for _, setting := range settings {
+		if ctx.Err() != nil {
+			return
+		}
+		sendMinute, ok := setting.SendMinute()
+		if !ok {
+			log.Printf("用户 %d 的摘要发送时间 %q 格式错误", setting.UserID, setting.SendTime)
+			continue
+		}
+		local := now.In(setting.User.Location())
+		if local.Hour()*60+local.Minute() < sendMinute {
+			continue
+		}
+		s.send(ctx, setting.User, local)
+	}
+}
+
+func (s *DigestScheduler) send(ctx context.Context, user models.User, local time.Time) {
+	now := time.Now()
+	claim, ok, err := repository.ClaimDigest(user.ID, local.Format("2006-01-02"), s.owner, now, now.Add(s.lease))
+	if err != nil {
+		log.Printf("占用用户 %d 的摘要记录失败: %v", user.ID, err)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	if err := s.deliver(ctx, user, local); err != nil {
+		log.Printf("发送用户 %d 的摘要失败: %v", user.ID, err)
+		if err := repository.ReleaseDigest(claim, s.owner); err != nil {
+			log.Printf("释放用户 %d 的摘要记录失败: %v", user.ID, err)
+		}
+		return
+	}
+
+	if err := repository.MarkDigestSent(claim, s.owner, time.Now()); err != nil {
+		log.Printf("更新用户 %d 的摘要记录失败: %v", user.ID, err)
+	}
+}
+
+func (s *DigestScheduler) deliver(ctx context.Context, user models.User, local time.Time) error {
+	d, err := service.BuildDigest(user, local)
+	if err != nil {
+		return err
+	}
+	text, html, err := digest.Render(*d)
+	if err != nil {
+		return err
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, digestSendTimeout)
+	defer cancel()
+	return s.mailer.Send(sendCtx, mail.Message{
+		To:      user.Email,
+		Subject: d.Subject(),
+		Text:    text,
+		HTML:    html,
+	})
+}
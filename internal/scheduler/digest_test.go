@@ -0,0 +1,186 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"myproject/config"
+	"myproject/internal/mail"
+	models "myproject/internal/model"
+	"myproject/internal/repository"
+	"myproject/internal/testutil"
+)
+
+// enableDigest 为上海时区的用户开启 08:00 发送的每日摘要
+func enableDigest(t *testing.T, username string) models.User {
+	t.Helper()
+
+	user := testutil.CreateUser(t, username)
+	if err := config.DB.Model(&user).Update("time_zone", "Asia/Shanghai").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := config.DB.Create(&models.DigestSetting{UserID: user.ID, Enabled: true, SendTime: "08:00"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func createDueTask(t *testing.T, user models.User, description string, due time.Time) {
+	t.Helper()
+
+	task := models.Task{Description: description, DueDate: &due, UserID: user.ID}
+	if err := repository.CreateTask(&task); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func digestLogs(t *testing.T, user models.User) []models.DigestLog {
+	t.Helper()
+
+	logs, err := repository.GetDigestLogs(user.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return logs
+}
+
+func TestDigestSchedulerSendsOncePerDayAfterSendTime(t *testing.T) {
+	testutil.OpenDB(t)
+	smtpServer := testutil.StartSMTPServer(t)
+	user := enableDigest(t, "alice")
+
+	shanghai, _ := time.LoadLocation("Asia/Shanghai")
+	createDueTask(t, user, "Write report", time.Date(2024, 5, 9, 10, 0, 0, 0, shanghai))
+	createDueTask(t, user, "Renew passport", time.Date(2024, 5, 7, 9, 0, 0, 0, shanghai))
+
+	mailer := mail.NewSMTPMailer(mail.SMTPConfig{Host: smtpServer.Host, Port: smtpServer.Port, From: "taskflow@test"})
+	s := NewDigestScheduler(mailer)
+
+	// 上海 07:30，还没到发送时间
+	s.RunOnce(context.Background(), time.Date(2024, 5, 8, 23, 30, 0, 0, time.UTC))
+	if n := len(smtpServer.Messages()); n != 0 {
+		t.Fatalf("sent %d emails before the send time", n)
+	}
+
+	// 上海 08:05 和 08:06，只发送一次
+	s.RunOnce(context.Background(), time.Date(2024, 5, 9, 0, 5, 0, 0, time.UTC))
+	s.RunOnce(context.Background(), time.Date(2024, 5, 9, 0, 6, 0, 0, time.UTC))
+
+	messages := smtpServer.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("sent %d emails, want 1", len(messages))
+	}
+	msg := messages[0]
+	if msg.To[0] != user.Email || msg.Subject() != "TaskFlow 每日摘要 2024-05-09" {
+		t.Errorf("to = %v, subject = %q", msg.To, msg.Subject())
+	}
+	for _, want := range []string{"Write report", "Renew passport", "multipart/alternative"} {
+		if !strings.Contains(msg.Data, want) {
+			t.Errorf("email does not contain %q", want)
+		}
+	}
+
+	logs := digestLogs(t, user)
+	if len(logs) != 1 || logs[0].Date != "2024-05-09" || logs[0].Status != "sent" {
+		t.Errorf("digest logs = %+v, want one sent log for 2024-05-09", logs)
+	}
+}
+
+type failingMailer struct{ calls int }
+
+func (m *failingMailer) Send(ctx context.Context, msg mail.Message) error {
+	m.calls++
+	return errors.New("smtp unavailable")
+}
+
+func TestDigestSchedulerRetriesAfterSendFailure(t *testing.T) {
+	testutil.OpenDB(t)
+	smtpServer := testutil.StartSMTPServer(t)
+	user := enableDigest(t, "alice")
+	now := time.Date(2024, 5, 9, 0, 5, 0, 0, time.UTC)
+
+	failing := &failingMailer{}
+	NewDigestScheduler(failing).RunOnce(context.Background(), now)
+	if failing.calls != 1 {
+		t.Fatalf("mailer called %d times, want 1", failing.calls)
+	}
+	if logs := digestLogs(t, user); len(logs) != 0 {
+		t.Fatalf("failed send left digest logs %+v, want the claim released", logs)
+	}
+
+	mailer := mail.NewSMTPMailer(mail.SMTPConfig{Host: smtpServer.Host, Port: smtpServer.Port, From: "taskflow@test"})
+	NewDigestScheduler(mailer).RunOnce(context.Background(), now.Add(time.Minute))
+	if n := len(smtpServer.Messages()); n != 1 {
+		t.Fatalf("sent %d emails on retry, want 1", n)
+	}
+}
+
+// 发送时间按时分比较：一位小时的 "8:00" 与 "08:00" 相同，之后一整天都会发送
+func TestDigestSchedulerComparesSendTimeNumerically(t *testing.T) {
+	testutil.OpenDB(t)
+	user := enableDigest(t, "alice")
+	if err := config.DB.Model(&models.DigestSetting{}).Where("user_id = ?", user.ID).Update("send_time", "8:00").Error; err != nil {
+		t.Fatal(err)
+	}
+	mailer := &countingMailer{}
+	s := NewDigestScheduler(mailer)
+
+	// 上海 07:59
+	s.RunOnce(context.Background(), time.Date(2024, 5, 8, 23, 59, 0, 0, time.UTC))
+	if mailer.calls != 0 {
+		t.Fatalf("sent %d emails before 8:00", mailer.calls)
+	}
+	// 上海 10:30，按字符串比较 "10:30" < "8:00"
+	s.RunOnce(context.Background(), time.Date(2024, 5, 9, 2, 30, 0, 0, time.UTC))
+	if mailer.calls != 1 {
+		t.Errorf("sent %d emails at 10:30, want 1", mailer.calls)
+	}
+}
+
+type countingMailer struct{ calls int }
+
+func (m *countingMailer) Send(ctx context.Context, msg mail.Message) error {
+	m.calls++
+	return nil
+}
+
+// 占用后进程退出留下的 sending 记录在租约过期后由其他实例接手，租约有效期内不会重复发送
+func TestDigestSchedulerTakesOverExpiredClaims(t *testing.T) {
+	testutil.OpenDB(t)
+	user := enableDigest(t, "alice")
+	now := time.Date(2024, 5, 9, 0, 5, 0, 0, time.UTC)
+
+	leaseUntil := time.Now().Add(time.Minute)
+	stale := models.DigestLog{UserID: user.ID, Date: "2024-05-09", Status: "sending", LeaseOwner: "crashed-instance", LeaseUntil: &leaseUntil}
+	if err := config.DB.Create(&stale).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	mailer := &countingMailer{}
+	s := NewDigestScheduler(mailer)
+	s.RunOnce(context.Background(), now)
+	if mailer.calls != 0 {
+		t.Fatalf("sent %d emails while another instance held the claim", mailer.calls)
+	}
+
+	if err := config.DB.Model(&stale).Update("lease_until", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	s.RunOnce(context.Background(), now.Add(time.Minute))
+	if mailer.calls != 1 {
+		t.Fatalf("sent %d emails after the claim expired, want 1", mailer.calls)
+	}
+	logs := digestLogs(t, user)
+	if len(logs) != 1 || logs[0].Status != "sent" || logs[0].LeaseOwner != "" {
+		t.Errorf("digest logs = %+v, want one sent log", logs)
+	}
+
+	s.RunOnce(context.Background(), now.Add(2*time.Minute))
+	if mailer.calls != 1 {
+		t.Errorf("sent %d emails, want the digest sent once", mailer.calls)
+	}
+}
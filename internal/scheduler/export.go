@@ -0,0 +1,61 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"myproject/internal/repository"
+	"myproject/internal/service"
+)
+
+const (
+	exportInterval  = 10 * time.Second
+	exportLease     = 10 * time.Minute
+	exportBatchSize = 5
+)
+
+// DataExportWorker 领取待生成的个人数据导出并在后台生成 ZIP 文件，大账号不会阻塞请求。
+// 生成期间持有较长的租约，进程崩溃后租约过期，任务会被其他实例重新领取
+type DataExportWorker struct {
+	owner string
+	batch int
+}
+
+func NewDataExportWorker() *DataExportWorker {
+	return &DataExportWorker{
+		owner: instanceID(),
+		batch: exportBatchSize,
+	}
+}
+
+// Run 阻塞运行直到 ctx 结束
+func (w *DataExportWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	for {
+		w.RunOnce(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce 领取并生成一批待处理的导出
+func (w *DataExportWorker) RunOnce(ctx context.Context, now time.Time) {
+	exports, err := repository.ClaimDueDataExports(w.owner, now, now.Add(exportLease), w.batch)
+	if err != nil {
+		log.Printf("领取数据导出任务失败: %v", err)
+		return
+	}
+
+	for _, export := range exports {
+		if ctx.Err() != nil {
+			return
+		}
+		service.ProcessDataExport(export, w.owner)
+	}
+}
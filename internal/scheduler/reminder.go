@@ -0,0 +1,157 @@
+// Package scheduler 运行在 API 进程内的后台任务。
+package scheduler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	models "myproject/internal/model"
+	"myproject/internal/notify"
+	"myproject/internal/repository"
+)
+
+const (
+	defaultInterval    = 15 * time.Second
+	defaultLease       = time.Minute
+	defaultBatchSize   = 100
+	maxReminderRetries = 5
+)
+
+// ReminderScheduler 定期领取到期的提醒并通过对应渠道发送。
+// 提醒状态全部保存在数据库中，进程重启后未发送的提醒会在下一轮被领取；
+// 多实例部署时通过数据库租约保证同一条提醒不会被重复发送：一批提醒一起领取，
+// 但每条在发送前单独续租，发送时间不超过租约的一半，所以发送期间租约不会过期。
+type ReminderScheduler struct {
+	owner    string
+	interval time.Duration
+	lease    time.Duration
+	batch    int
+	channels map[string]notify.Channel
+}
+
+func NewReminderScheduler(channels ...notify.Channel) *ReminderScheduler {
+	s := &ReminderScheduler{
+		owner:    instanceID(),
+		interval: defaultInterval,
+		lease:    defaultLease,
+		batch:    defaultBatchSize,
+		channels: make(map[string]notify.Channel, len(channels)),
+	}
+	for _, ch := range channels {
+		s.channels[ch.Name()] = ch
+	}
+	return s
+}
+
+// Run 阻塞运行直到 ctx 结束
+func (s *ReminderScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.RunOnce(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce 领取并发送一批在 now 之前到期的提醒
+func (s *ReminderScheduler) RunOnce(ctx context.Context, now time.Time) {
+	reminders, err := repository.ClaimDueReminders(s.owner, now, now.Add(s.lease), s.batch)
+	if err != nil {
+		log.Printf("领取提醒失败: %v", err)
+		return
+	}
+
+	for i := range reminders {
+		if ctx.Err() != nil {
+			return
+		}
+		s.fire(ctx, &reminders[i], now)
+	}
+}
+
+func (s *ReminderScheduler) fire(ctx context.Context, reminder *models.Reminder, now time.Time) {
+	// 前面的提醒发送较慢时，这一条的租约可能已经过期并被其他实例领取
+	if !s.renew(reminder) {
+		return
+	}
+
+	// 任务已完成或已删除时不再提醒
+	if reminder.Task.ID == 0 || reminder.Task.Status == "done" {
+		if err := repository.FinishReminder(reminder, s.owner, "skipped", now); err != nil {
+			log.Printf("更新提醒 %d 失败: %v", reminder.ID, err)
+		}
+		return
+	}
+
+	ch, ok := s.channels[reminder.Channel]
+	if !ok {
+		s.retry(reminder, now, notify.ErrUnknownChannel, true)
+		return
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, s.lease/2)
+	defer cancel()
+	if err := ch.Send(sendCtx, reminderMessage(reminder)); err != nil {
+		s.retry(reminder, now, err, reminder.Attempts+1 >= maxReminderRetries)
+		return
+	}
+
+	if err := repository.FinishReminder(reminder, s.owner, "sent", now); err != nil {
+		log.Printf("更新提醒 %d 失败: %v", reminder.ID, err)
+	}
+}
+
+// renew 续租，返回是否可以继续处理
+func (s *ReminderScheduler) renew(reminder *models.Reminder) bool {
+	ok, err := repository.RenewReminderLease(reminder, s.owner, time.Now().Add(s.lease))
+	if err != nil {
+		log.Printf("续租提醒 %d 失败: %v", reminder.ID, err)
+		return false
+	}
+	return ok
+}
+
+// retry 按指数退避安排下一次发送
+func (s *ReminderScheduler) retry(reminder *models.Reminder, now time.Time, sendErr error, failed bool) {
+	backoff := time.Minute << reminder.Attempts
+	if err := repository.RetryReminder(reminder, s.owner, now.Add(backoff), sendErr.Error(), failed); err != nil {
+		log.Printf("更新提醒 %d 失败: %v", reminder.ID, err)
+	}
+	log.Printf("发送提醒 %d 失败（第 %d 次）: %v", reminder.ID, reminder.Attempts+1, sendErr)
+}
+
+func reminderMessage(reminder *models.Reminder) notify.Message {
+	task := reminder.Task
+	body := fmt.Sprintf("任务「%s」提醒", task.Description)
+	if task.DueDate != nil {
+		due := task.DueDate.In(reminder.User.Location()).Format("2006-01-02 15:04")
+		body = fmt.Sprintf("任务「%s」将于 %s 到期", task.Description, due)
+	}
+
+	return notify.Message{
+		UserID:     reminder.UserID,
+		Email:      reminder.User.Email,
+		TaskID:     reminder.TaskID,
+		ReminderID: reminder.ID,
+		Title:      "任务提醒：" + task.Description,
+		Body:       body,
+	}
+}
+
+// instanceID 生成本进程的租约持有者标识
+func instanceID() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 4)
+	rand.Read(b)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(b))
+}
@@ -0,0 +1,113 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"myproject/config"
+	"myproject/internal/mail"
+	models "myproject/internal/model"
+	"myproject/internal/notify"
+	"myproject/internal/repository"
+	"myproject/internal/testutil"
+)
+
+// createReminder 创建一个在 fireAt 到期、通过邮件发送的提醒
+func createReminder(t *testing.T, user models.User, description string, fireAt time.Time) models.Reminder {
+	t.Helper()
+
+	task := models.Task{Description: description, UserID: user.ID}
+	if err := repository.CreateTask(&task); err != nil {
+		t.Fatal(err)
+	}
+	reminder := models.Reminder{TaskID: task.ID, UserID: user.ID, RemindAt: &fireAt, FireAt: fireAt, Channel: notify.ChannelEmail, Status: "pending"}
+	if err := repository.CreateReminder(&reminder); err != nil {
+		t.Fatal(err)
+	}
+	return reminder
+}
+
+func reminderStatus(t *testing.T, id uint) models.Reminder {
+	t.Helper()
+
+	var reminder models.Reminder
+	if err := config.DB.First(&reminder, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return reminder
+}
+
+func TestReminderSchedulerSendsEmailOnce(t *testing.T) {
+	testutil.OpenDB(t)
+	smtpServer := testutil.StartSMTPServer(t)
+	user := testutil.CreateUser(t, "alice")
+
+	now := time.Now()
+	due := createReminder(t, user, "Pay rent", now.Add(-time.Minute))
+	later := createReminder(t, user, "Not yet", now.Add(time.Hour))
+
+	mailer := mail.NewSMTPMailer(mail.SMTPConfig{Host: smtpServer.Host, Port: smtpServer.Port, From: "taskflow@test"})
+	s := NewReminderScheduler(notify.NewEmailChannel(mailer))
+	s.RunOnce(context.Background(), now)
+	s.RunOnce(context.Background(), now)
+
+	messages := smtpServer.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("sent %d emails, want 1", len(messages))
+	}
+	if messages[0].To[0] != user.Email || messages[0].Subject() != "任务提醒：Pay rent" {
+		t.Errorf("to = %v, subject = %q", messages[0].To, messages[0].Subject())
+	}
+	if got := reminderStatus(t, due.ID); got.Status != "sent" || got.LeaseOwner != "" {
+		t.Errorf("due reminder: status = %q, lease owner = %q", got.Status, got.LeaseOwner)
+	}
+	if got := reminderStatus(t, later.ID); got.Status != "pending" {
+		t.Errorf("future reminder status = %q, want pending", got.Status)
+	}
+}
+
+// slowChannel 发送第一条提醒时耗时过长：同一批其余提醒的租约过期，被另一个实例领走
+type slowChannel struct {
+	t    *testing.T
+	sent []uint
+}
+
+func (c *slowChannel) Name() string { return notify.ChannelEmail }
+
+func (c *slowChannel) Send(ctx context.Context, msg notify.Message) error {
+	if len(c.sent) == 0 {
+		now := time.Now()
+		if err := config.DB.Model(&models.Reminder{}).Where("id <> ?", msg.ReminderID).Update("lease_until", now.Add(-time.Second)).Error; err != nil {
+			c.t.Fatal(err)
+		}
+		if _, err := repository.ClaimDueReminders("other-instance", now, now.Add(time.Minute), 100); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+	c.sent = append(c.sent, msg.ReminderID)
+	return nil
+}
+
+func TestReminderSchedulerSkipsRemindersClaimedByAnotherInstance(t *testing.T) {
+	testutil.OpenDB(t)
+	user := testutil.CreateUser(t, "alice")
+
+	now := time.Now()
+	first := createReminder(t, user, "first", now.Add(-2*time.Minute))
+	second := createReminder(t, user, "second", now.Add(-time.Minute))
+
+	ch := &slowChannel{t: t}
+	s := NewReminderScheduler(ch)
+	s.RunOnce(context.Background(), now)
+
+	if len(ch.sent) != 1 || ch.sent[0] != first.ID {
+		t.Fatalf("sent %v, want only reminder %d", ch.sent, first.ID)
+	}
+	if got := reminderStatus(t, first.ID); got.Status != "sent" {
+		t.Errorf("first reminder status = %q, want sent", got.Status)
+	}
+	if got := reminderStatus(t, second.ID); got.Status != "pending" || got.LeaseOwner != "other-instance" {
+		t.Errorf("second reminder: status = %q, lease owner = %q, want pending and owned by the other instance", got.Status, got.LeaseOwner)
+	}
+}
@@ -0,0 +1,172 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	models "myproject/internal/model"
+	"myproject/internal/netguard"
+	"myproject/internal/repository"
+)
+
+const (
+	webhookInterval     = 5 * time.Second
+	webhookTimeout      = 10 * time.Second
+	maxWebhookAttempts  = 8
+	webhookBaseBackoff  = 30 * time.Second
+	maxResponseBodySize = 4 << 10
+)
+
+// WebhookDispatcher 从 webhook_deliveries 队列领取到期的投递并发送。
+// 请求体带有 HMAC-SHA256 签名；非 2xx 响应或网络错误按指数退避重试，超过次数后标记为 failed。
+// 每条投递在发送前单独续租，请求超时远小于租约，所以一批投递耗时再长也不会被其他实例重复发送。
+// 回调地址由用户填写，只允许访问公网地址且不跟随重定向；只记录响应状态码，不保存响应内容。
+type WebhookDispatcher struct {
+	owner  string
+	client *http.Client
+	batch  int
+}
+
+func NewWebhookDispatcher() *WebhookDispatcher {
+	return &WebhookDispatcher{
+		owner:  instanceID(),
+		client: netguard.NewClient(webhookTimeout),
+		batch:  defaultBatchSize,
+	}
+}
+
+// Run 阻塞运行直到 ctx 结束
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(webhookInterval)
+	defer ticker.Stop()
+
+	for {
+		d.RunOnce(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce 领取并发送一批在 now 之前到期的投递
+func (d *WebhookDispatcher) RunOnce(ctx context.Context, now time.Time) {
+	deliveries, err := repository.ClaimDueDeliveries(d.owner, now, now.Add(defaultLease), d.batch)
+	if err != nil {
+		log.Printf("领取 webhook 投递失败: %v", err)
+		return
+	}
+
+	for i := range deliveries {
+		if ctx.Err() != nil {
+			return
+		}
+		d.deliver(ctx, &deliveries[i])
+	}
+}
+
+func (d *WebhookDispatcher) deliver(ctx context.Context, delivery *models.WebhookDelivery) {
+	attempts := delivery.Attempts + 1
+	updates := map[string]interface{}{"attempts": attempts}
+
+	// 前面的投递较慢时，这一条的租约可能已经过期并被其他实例领取
+	ok, err := repository.RenewDeliveryLease(delivery, d.owner, time.Now().Add(defaultLease))
+	if err != nil {
+		log.Printf("续租 webhook 投递 %d 失败: %v", delivery.ID, err)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	// webhook 已被删除时不再投递
+	if delivery.Webhook.ID == 0 {
+		updates["status"] = "failed"
+		updates["last_error"] = "webhook deleted"
+		d.finish(delivery, updates)
+		return
+	}
+
+	status, err := d.send(ctx, delivery)
+	updates["response_status"] = status
+	now := time.Now()
+
+	if err == nil && status >= 200 && status < 300 {
+		updates["status"] = "succeeded"
+		updates["last_error"] = ""
+		updates["delivered_at"] = now
+		d.finish(delivery, updates)
+		return
+	}
+
+	if err == nil {
+		err = fmt.Errorf("unexpected status %d", status)
+	}
+	updates["last_error"] = truncate(err.Error(), 512)
+	if attempts >= maxWebhookAttempts {
+		updates["status"] = "failed"
+	} else {
+		updates["next_attempt_at"] = now.Add(webhookBaseBackoff << delivery.Attempts)
+	}
+	d.finish(delivery, updates)
+	log.Printf("投递 webhook %d 失败（第 %d 次）: %v", delivery.ID, attempts, err)
+}
+
+// send 发送一次请求，返回响应状态码
+func (d *WebhookDispatcher) send(ctx context.Context, delivery *models.WebhookDelivery) (int, error) {
+	payload := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "TaskFlow-Webhook/1.0")
+	req.Header.Set("X-TaskFlow-Event", delivery.Event)
+	req.Header.Set("X-TaskFlow-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set("X-TaskFlow-Signature", "t="+timestamp+",v1="+Sign(delivery.Webhook.Secret, timestamp, payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// 读完少量响应体以便复用连接
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBodySize))
+	return resp.StatusCode, nil
+}
+
+func (d *WebhookDispatcher) finish(delivery *models.WebhookDelivery, updates map[string]interface{}) {
+	if err := repository.FinishDelivery(delivery, d.owner, updates); err != nil {
+		log.Printf("更新 webhook 投递 %d 失败: %v", delivery.ID, err)
+	}
+}
+
+// Sign 计算 webhook 签名：hex(HMAC-SHA256(secret, timestamp + "." + body))。
+// 接收方应以同样方式计算并用常量时间比较，同时拒绝时间戳过旧的请求以防重放。
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
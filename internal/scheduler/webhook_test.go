@@ -0,0 +1,242 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"myproject/config"
+	"myproject/internal/events"
+	models "myproject/internal/model"
+	"myproject/internal/netguard"
+	"myproject/internal/repository"
+	"myproject/internal/service"
+	"myproject/internal/testutil"
+)
+
+// receiver 记录收到的 webhook 请求，并校验签名
+type receiver struct {
+	t      *testing.T
+	secret string
+	status int
+	// onRequest 在每个请求处理前调用，用于模拟慢速接收方
+	onRequest func(delivery string)
+
+	mu        sync.Mutex
+	delivered []string // X-TaskFlow-Delivery
+	events    []string
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	delivery := r.Header.Get("X-TaskFlow-Delivery")
+	if rc.onRequest != nil {
+		rc.onRequest(delivery)
+	}
+
+	timestamp, signature, ok := parseSignature(r.Header.Get("X-TaskFlow-Signature"))
+	if !ok || signature != Sign(rc.secret, timestamp, body) {
+		rc.t.Errorf("delivery %s: bad signature %q", delivery, r.Header.Get("X-TaskFlow-Signature"))
+	}
+	var payload service.WebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil || payload.Event != r.Header.Get("X-TaskFlow-Event") {
+		rc.t.Errorf("delivery %s: payload %s does not match event header", delivery, body)
+	}
+
+	rc.mu.Lock()
+	rc.delivered = append(rc.delivered, delivery)
+	rc.events = append(rc.events, payload.Event)
+	rc.mu.Unlock()
+
+	status := rc.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	io.WriteString(w, "ok")
+}
+
+func parseSignature(header string) (timestamp, signature string, ok bool) {
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature = value
+		}
+	}
+	return timestamp, signature, timestamp != "" && signature != ""
+}
+
+// subscribe 为用户注册指向 handler 的 webhook
+func subscribe(t *testing.T, user models.User, handler http.Handler) models.Webhook {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	webhook := models.Webhook{UserID: user.ID, URL: server.URL, Secret: "s3cret", Active: true}
+	if err := repository.CreateWebhook(&webhook); err != nil {
+		t.Fatal(err)
+	}
+	return webhook
+}
+
+// newTestDispatcher 返回允许访问 httptest 回环地址的投递器
+func newTestDispatcher() *WebhookDispatcher {
+	d := NewWebhookDispatcher()
+	d.client = &http.Client{Timeout: webhookTimeout}
+	return d
+}
+
+func deliveries(t *testing.T) []models.WebhookDelivery {
+	t.Helper()
+
+	var deliveries []models.WebhookDelivery
+	if err := config.DB.Order("id").Find(&deliveries).Error; err != nil {
+		t.Fatal(err)
+	}
+	return deliveries
+}
+
+func TestWebhookDispatcherDeliversSignedEvents(t *testing.T) {
+	testutil.OpenDB(t)
+	user := testutil.CreateUser(t, "alice")
+	rc := &receiver{t: t, secret: "s3cret"}
+	subscribe(t, user, rc)
+
+	task := models.Task{ID: 7, Description: "Ship it", UserID: user.ID}
+	service.HandleTaskEvent(events.Event{Type: events.TaskCreated, UserID: user.ID, Task: task, OccurredAt: time.Now()})
+	service.HandleTaskEvent(events.Event{Type: events.TaskCompleted, UserID: user.ID, Task: task, OccurredAt: time.Now()})
+
+	d := newTestDispatcher()
+	d.RunOnce(context.Background(), time.Now())
+	d.RunOnce(context.Background(), time.Now())
+
+	if strings.Join(rc.events, ",") != events.TaskCreated+","+events.TaskCompleted {
+		t.Errorf("received events %v", rc.events)
+	}
+	for _, delivery := range deliveries(t) {
+		if delivery.Status != "succeeded" || delivery.Attempts != 1 || delivery.ResponseStatus != http.StatusOK || delivery.DeliveredAt == nil {
+			t.Errorf("delivery %d: status=%s attempts=%d response=%d", delivery.ID, delivery.Status, delivery.Attempts, delivery.ResponseStatus)
+		}
+	}
+}
+
+func TestWebhookDispatcherRetriesWithBackoff(t *testing.T) {
+	testutil.OpenDB(t)
+	user := testutil.CreateUser(t, "alice")
+	rc := &receiver{t: t, secret: "s3cret", status: http.StatusServiceUnavailable}
+	subscribe(t, user, rc)
+
+	service.HandleTaskEvent(events.Event{Type: events.TaskCreated, UserID: user.ID, Task: models.Task{ID: 7}, OccurredAt: time.Now()})
+
+	d := newTestDispatcher()
+	before := time.Now()
+	d.RunOnce(context.Background(), before)
+
+	got := deliveries(t)[0]
+	if got.Status != "pending" || got.Attempts != 1 || got.ResponseStatus != http.StatusServiceUnavailable || got.LeaseOwner != "" {
+		t.Fatalf("after failure: status=%s attempts=%d response=%d lease=%q", got.Status, got.Attempts, got.ResponseStatus, got.LeaseOwner)
+	}
+	if got.NextAttemptAt.Before(before.Add(webhookBaseBackoff)) {
+		t.Errorf("next attempt at %v, want at least %v later", got.NextAttemptAt, webhookBaseBackoff)
+	}
+
+	// 退避时间未到时不会重试
+	d.RunOnce(context.Background(), before.Add(time.Second))
+	if len(rc.delivered) != 1 {
+		t.Fatalf("retried before the backoff elapsed: %d requests", len(rc.delivered))
+	}
+
+	rc.status = http.StatusNoContent
+	d.RunOnce(context.Background(), got.NextAttemptAt)
+	if got := deliveries(t)[0]; got.Status != "succeeded" || got.Attempts != 2 {
+		t.Errorf("after retry: status=%s attempts=%d", got.Status, got.Attempts)
+	}
+}
+
+func TestWebhookDispatcherSkipsDeliveriesClaimedByAnotherInstance(t *testing.T) {
+	testutil.OpenDB(t)
+	user := testutil.CreateUser(t, "alice")
+
+	// 第一个请求耗时过长：同一批其余投递的租约过期，被另一个实例领走
+	rc := &receiver{t: t, secret: "s3cret"}
+	rc.onRequest = func(string) {
+		if len(rc.delivered) > 0 {
+			return
+		}
+		now := time.Now()
+		if err := config.DB.Model(&models.WebhookDelivery{}).Where("status = ?", "pending").Update("lease_until", now.Add(-time.Second)).Error; err != nil {
+			t.Error(err)
+		}
+		if _, err := repository.ClaimDueDeliveries("other-instance", now, now.Add(time.Minute), 100); err != nil {
+			t.Error(err)
+		}
+	}
+	subscribe(t, user, rc)
+
+	for i := 0; i < 3; i++ {
+		service.HandleTaskEvent(events.Event{Type: events.TaskUpdated, UserID: user.ID, Task: models.Task{ID: 7}, OccurredAt: time.Now()})
+	}
+
+	newTestDispatcher().RunOnce(context.Background(), time.Now())
+
+	if len(rc.delivered) != 1 {
+		t.Fatalf("delivered %v, want only the first delivery", rc.delivered)
+	}
+	for _, delivery := range deliveries(t)[1:] {
+		if delivery.Status != "pending" || delivery.Attempts != 0 || delivery.LeaseOwner != "other-instance" {
+			t.Errorf("delivery %d: status=%s attempts=%d lease=%q, want untouched and owned by the other instance", delivery.ID, delivery.Status, delivery.Attempts, delivery.LeaseOwner)
+		}
+	}
+}
+
+func TestWebhookDispatcherRefusesPrivateAddresses(t *testing.T) {
+	testutil.OpenDB(t)
+	user := testutil.CreateUser(t, "alice")
+	rc := &receiver{t: t, secret: "s3cret"}
+	subscribe(t, user, rc)
+
+	service.HandleTaskEvent(events.Event{Type: events.TaskCreated, UserID: user.ID, Task: models.Task{ID: 7}, OccurredAt: time.Now()})
+	NewWebhookDispatcher().RunOnce(context.Background(), time.Now())
+
+	if len(rc.delivered) != 0 {
+		t.Fatalf("loopback receiver got %v", rc.delivered)
+	}
+	got := deliveries(t)[0]
+	if got.Status != "pending" || got.ResponseStatus != 0 || !strings.Contains(got.LastError, netguard.ErrPrivateAddress.Error()) {
+		t.Errorf("delivery: status=%s response=%d error=%q", got.Status, got.ResponseStatus, got.LastError)
+	}
+}
+
+func TestWebhookPayloadOmitsUser(t *testing.T) {
+	testutil.OpenDB(t)
+	user := testutil.CreateUser(t, "alice")
+	if err := repository.CreateWebhook(&models.Webhook{UserID: user.ID, URL: "https://example.com/hook", Secret: "s3cret", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	service.HandleTaskEvent(events.Event{Type: events.TaskCreated, UserID: user.ID, Task: models.Task{ID: 7, Description: "Ship it"}, OccurredAt: time.Now()})
+
+	var payload struct {
+		Data struct {
+			Task map[string]json.RawMessage `json:"task"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(deliveries(t)[0].Payload), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := payload.Data.Task["user"]; ok {
+		t.Errorf("payload contains a user object: %s", deliveries(t)[0].Payload)
+	}
+	if string(payload.Data.Task["description"]) != `"Ship it"` {
+		t.Errorf("payload lost the task fields: %s", deliveries(t)[0].Payload)
+	}
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	models "myproject/internal/model"
+	"myproject/internal/repository"
+)
+
+var (
+	ErrInvalidScope          = errors.New("invalid scope")
+	ErrInvalidAccessToken    = errors.New("invalid access token")
+	ErrAccessTokenExpired    = errors.New("access token expired")
+	ErrAccessTokenNotFound   = errors.New("access token not found")
+	ErrCreateAccessTokenFail = errors.New("create access token failed")
+	ErrQueryAccessTokenFail  = errors.New("query access token failed")
+	ErrDeleteAccessTokenFail = errors.New("delete access token failed")
+)
+
+// 个人访问令牌的前缀，认证中间件据此与 JWT 区分
+const AccessTokenPrefix = "tfp_"
+
+// 个人访问令牌可以申请的权限
+const (
+	ScopeTasksRead          = "tasks:read"
+	ScopeTasksWrite         = "tasks:write"
+	ScopeTemplatesRead      = "templates:read"
+	ScopeTemplatesWrite     = "templates:write"
+	ScopeNotificationsRead  = "notifications:read"
+	ScopeNotificationsWrite = "notifications:write"
+	ScopeDigestRead         = "digest:read"
+	ScopeDigestWrite        = "digest:write"
+	ScopeWebhooksRead       = "webhooks:read"
+	ScopeWebhooksWrite      = "webhooks:write"
+)
+
+var Scopes = []string{
+	ScopeTasksRead, ScopeTasksWrite,
+	ScopeTemplatesRead, ScopeTemplatesWrite,
+	ScopeNotificationsRead, ScopeNotificationsWrite,
+	ScopeDigestRead, ScopeDigestWrite,
+	ScopeWebhooksRead, ScopeWebhooksWrite,
+}
+
+// 最后使用时间的落库间隔
+const accessTokenTouchInterval = time.Minute
+
+// CreateAccessToken 创建个人访问令牌，返回的明文只有这一次机会看到。expiresAt 为空表示永不过期
+func CreateAccessToken(user models.User, name string, scopes []string, expiresAt *time.Time) (*models.PersonalAccessToken, string, error) {
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	random, err := randomToken()
+	if err != nil {
+		return nil, "", ErrCreateAccessTokenFail
+	}
+	raw := AccessTokenPrefix + random
+
+	token := models.PersonalAccessToken{
+		UserID:      user.ID,
+		Name:        name,
+		TokenPrefix: raw[:len(AccessTokenPrefix)+6],
+		TokenHash:   hashToken(raw),
+		Scopes:      scopes,
+		ExpiresAt:   expiresAt,
+	}
+	if err := repository.CreateAccessToken(&token); err != nil {
+		return nil, "", ErrCreateAccessTokenFail
+	}
+	return &token, raw, nil
+}
+
+// GetAccessTokens 获取用户的个人访问令牌
+func GetAccessTokens(user models.User) ([]models.PersonalAccessToken, error) {
+	tokens, err := repository.GetAccessTokensByUser(user.ID)
+	if err != nil {
+		return nil, ErrQueryAccessTokenFail
+	}
+	return tokens, nil
+}
+
+// DeleteAccessToken 吊销个人访问令牌
+func DeleteAccessToken(user models.User, id string) error {
+	token, err := repository.GetAccessTokenByID(id, user.ID)
+	if err != nil {
+		return ErrAccessTokenNotFound
+	}
+	if err := repository.DeleteAccessToken(token); err != nil {
+		return ErrDeleteAccessTokenFail
+	}
+	return nil
+}
+
+// AuthenticateAccessToken 校验个人访问令牌并返回所属用户，按间隔更新最后使用时间
+func AuthenticateAccessToken(raw string) (*models.PersonalAccessToken, *models.User, error) {
+	token, err := repository.GetAccessTokenByHash(hashToken(raw))
+	if err != nil {
+		return nil, nil, ErrInvalidAccessToken
+	}
+
+	now := time.Now()
+	if token.ExpiresAt != nil && now.After(*token.ExpiresAt) {
+		return nil, nil, ErrAccessTokenExpired
+	}
+
+	user, err := repository.GetUserByID(token.UserID)
+	if err != nil {
+		return nil, nil, ErrInvalidAccessToken
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= accessTokenTouchInterval {
+		if err := repository.TouchAccessToken(token.ID, now, now.Add(-accessTokenTouchInterval)); err != nil {
+			log.Printf("更新访问令牌 %d 使用时间失败: %v", token.ID, err)
+		}
+	}
+	return token, user, nil
+}
+
+// HasScope 判断权限列表中是否包含 scope
+func HasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeScopes 校验并去重，写权限隐含读权限
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, ErrInvalidScope
+	}
+	var result []string
+	add := func(scope string) {
+		if !HasScope(result, scope) {
+			result = append(result, scope)
+		}
+	}
+	for _, scope := range scopes {
+		if !HasScope(Scopes, scope) {
+			return nil, ErrInvalidScope
+		}
+		if resource, ok := strings.CutSuffix(scope, ":write"); ok {
+			add(resource + ":read")
+		}
+		add(scope)
+	}
+	return result, nil
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"myproject/internal/mail"
+	models "myproject/internal/model"
+	"myproject/internal/repository"
+	"myproject/utils"
+)
+
+var (
+	ErrInvalidActionToken = errors.New("invalid or expired token")
+	ErrEmailNotVerified   = errors.New("email not verified")
+	ErrUpdatePasswordFail = errors.New("update password failed")
+	ErrVerifyEmailFail    = errors.New("verify email failed")
+)
+
+// 一次性令牌的用途
+const (
+	PurposeVerifyEmail   = "verify_email"
+	PurposeResetPassword = "reset_password"
+)
+
+const (
+	verifyEmailTTL   = 48 * time.Hour
+	resetPasswordTTL = time.Hour
+	mailSendTimeout  = 30 * time.Second
+)
+
+// 账号相关邮件的发送器，启动时通过 SetMailer 设置
+var mailer mail.Mailer = mail.NewLogMailer("", "taskflow@localhost")
+
+// SetMailer 设置发送验证邮件和重置密码邮件使用的发送器
+func SetMailer(m mail.Mailer) {
+	mailer = m
+}
+
+// EmailVerificationRequired 是否要求验证邮箱后才能登录（REQUIRE_EMAIL_VERIFICATION=true）
+func EmailVerificationRequired() bool {
+	return os.Getenv("REQUIRE_EMAIL_VERIFICATION") == "true"
+}
+
+// SendVerificationEmail 给用户当前邮箱发送验证链接
+func SendVerificationEmail(user models.User) error {
+	token, err := newActionToken(user, PurposeVerifyEmail, verifyEmailTTL)
+	if err != nil {
+		return err
+	}
+	link := appURL("/verify-email", token)
+	sendMail(mail.Message{
+		To:      user.Email,
+		Subject: "验证你的邮箱",
+		Text: fmt.Sprintf("你好 %s：\n\n请在 %d 小时内打开以下链接完成邮箱验证：\n%s\n\n如果这不是你的操作，请忽略本邮件。",
+			user.Username, int(verifyEmailTTL/time.Hour), link),
+	})
+	return nil
+}
+
+// ResendVerificationEmail 重新发送验证邮件。邮箱不存在或已验证时静默返回，避免泄露注册信息
+func ResendVerificationEmail(email string) error {
+	user, err := repository.GetUserByEmail(email)
+	if err != nil || user.EmailVerified {
+		return nil
+	}
+	return SendVerificationEmail(*user)
+}
+
+// VerifyEmail 使用验证链接中的令牌确认邮箱
+func VerifyEmail(token string) (*models.User, error) {
+	claims, err := useActionToken(token, PurposeVerifyEmail)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := repository.GetUserByID(claims.UserID)
+	if err != nil || !strings.EqualFold(user.Email, claims.Email) {
+		// 签发后邮箱已经改变，旧链接作废
+		return nil, ErrInvalidActionToken
+	}
+	if err := repository.UpdateUser(user, map[string]interface{}{"email_verified": true}); err != nil {
+		return nil, ErrVerifyEmailFail
+	}
+	return user, nil
+}
+
+// ForgotPassword 发送重置密码链接。邮箱不存在时同样返回成功，避免泄露注册信息
+func ForgotPassword(email string) error {
+	user, err := repository.GetUserByEmail(email)
+	if err != nil {
+		return nil
+	}
+
+	token, err := newActionToken(*user, PurposeResetPassword, resetPasswordTTL)
+	if err != nil {
+		return err
+	}
+	link := appURL("/reset-password", token)
+	sendMail(mail.Message{
+		To:      user.Email,
+		Subject: "重置密码",
+		Text: fmt.Sprintf("你好 %s：\n\n我们收到了重置密码的请求，请在 %d 分钟内打开以下链接设置新密码：\n%s\n\n如果这不是你的操作，请忽略本邮件，你的密码不会改变。",
+			user.Username, int(resetPasswordTTL/time.Minute), link),
+	})
+	return nil
+}
+
+// ResetPassword 使用重置链接中的令牌设置新密码。成功后其余重置链接作废，
+// 已登录的设备全部退出；能收到邮件也说明邮箱属于该用户，一并标记为已验证
+func ResetPassword(token, password string) error {
+	claims, err := useActionToken(token, PurposeResetPassword)
+	if err != nil {
+		return err
+	}
+
+	user, err := repository.GetUserByID(claims.UserID)
+	if err != nil || !strings.EqualFold(user.Email, claims.Email) {
+		return ErrInvalidActionToken
+	}
+
+	user.Password = password
+	if err := user.HashPassword(); err != nil {
+		return ErrHashPasswordFailed
+	}
+
+	now := time.Now()
+	if err := repository.UpdateUser(user, map[string]interface{}{
+		"password":       user.Password,
+		"email_verified": true,
+	}); err != nil {
+		return ErrUpdatePasswordFail
+	}
+	if err := repository.InvalidateActionTokens(user.ID, PurposeResetPassword, now); err != nil {
+		log.Printf("作废用户 %d 的重置密码链接失败: %v", user.ID, err)
+	}
+	return LogoutAll(*user)
+}
+
+// newActionToken 签发一次性令牌并记录 jti
+func newActionToken(user models.User, purpose string, ttl time.Duration) (string, error) {
+	jti, err := randomToken()
+	if err != nil {
+		return "", ErrGenerateTokenFailed
+	}
+	record := models.ActionToken{
+		JTI:       jti,
+		UserID:    user.ID,
+		Purpose:   purpose,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := repository.CreateActionToken(&record); err != nil {
+		return "", ErrGenerateTokenFailed
+	}
+
+	token, err := utils.GenerateActionToken(user.ID, user.Email, purpose, jti, ttl)
+	if err != nil {
+		return "", ErrGenerateTokenFailed
+	}
+	return token, nil
+}
+
+// useActionToken 校验签名、用途和有效期，并把令牌标记为已使用
+func useActionToken(token, purpose string) (*utils.ActionClaims, error) {
+	claims, err := utils.ParseActionToken(token, purpose)
+	if err != nil {
+		return nil, ErrInvalidActionToken
+	}
+	used, err := repository.UseActionToken(claims.ID, claims.UserID, purpose, time.Now())
+	if err != nil || !used {
+		return nil, ErrInvalidActionToken
+	}
+	return claims, nil
+}
+
+// appURL 生成带令牌的前端页面链接
+func appURL(path, token string) string {
+	return appBaseURL() + path + "?token=" + url.QueryEscape(token)
+}
+
+// appBaseURL 前端地址，由 APP_URL 配置
+func appBaseURL() string {
+	base := os.Getenv("APP_URL")
+	if base == "" {
+		base = "http://localhost:5173"
+	}
+	return strings.TrimRight(base, "/")
+}
+
+// sendMail 在后台发送邮件，不阻塞请求，失败只记录日志
+func sendMail(msg mail.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailSendTimeout)
+		defer cancel()
+		if err := mailer.Send(ctx, msg); err != nil {
+			log.Printf("发送邮件到 %s 失败: %v", msg.To, err)
+		}
+	}()
+}
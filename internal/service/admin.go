@@ -0,0 +1,247 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	models "myproject/internal/model"
+	"myproject/internal/repository"
+)
+
+var (
+	ErrUserNotFound     = errors.New("user not found")
+	ErrCannotModifySelf = errors.New("cannot modify own account")
+	ErrQueryUsersFail   = errors.New("query users failed")
+	ErrUpdateUserFail   = errors.New("update user failed")
+	ErrQueryStatsFail   = errors.New("query stats failed")
+	ErrQueryAuditFail   = errors.New("query audit logs failed")
+)
+
+// 管理操作的审计动作，查询同样记录
+const (
+	AuditAdminUsersListed   = "admin.users_listed"
+	AuditAdminUserViewed    = "admin.user_viewed"
+	AuditAdminUserDisabled  = "admin.user_disabled"
+	AuditAdminUserEnabled   = "admin.user_enabled"
+	AuditAdminPasswordReset = "admin.password_reset"
+	AuditAdminRoleChanged   = "admin.role_changed"
+	AuditAdminStatsViewed   = "admin.stats_viewed"
+	AuditAdminAuditViewed   = "admin.audit_viewed"
+)
+
+// UserFilter 管理员搜索用户的条件
+type UserFilter struct {
+	Query    string
+	Role     string
+	Disabled *bool
+	Page     int
+	PageSize int
+}
+
+// AuditFilter 管理员查询审计记录的条件
+type AuditFilter struct {
+	Action   string
+	UserID   *uint
+	Page     int
+	PageSize int
+}
+
+// SystemStats 系统概况
+type SystemStats struct {
+	Users struct {
+		Total        int64 `json:"total"`
+		Disabled     int64 `json:"disabled"`
+		Admins       int64 `json:"admins"`
+		Verified     int64 `json:"verified"`
+		NewLast7Days int64 `json:"new_last_7_days"`
+	} `json:"users"`
+	Tasks struct {
+		Total int64 `json:"total"`
+		Done  int64 `json:"done"`
+	} `json:"tasks"`
+	ActiveSessions    int64 `json:"active_sessions"`
+	WebhookDeliveries struct {
+		Pending int64 `json:"pending"`
+		Failed  int64 `json:"failed"`
+	} `json:"webhook_deliveries"`
+	PendingDataExports int64     `json:"pending_data_exports"`
+	GeneratedAt        time.Time `json:"generated_at"`
+}
+
+// ListUsers 搜索用户
+func ListUsers(admin models.User, filter UserFilter, client ClientInfo) ([]models.User, int64, error) {
+	users, total, err := repository.SearchUsers(filter.Query, filter.Role, filter.Disabled, (filter.Page-1)*filter.PageSize, filter.PageSize)
+	if err != nil {
+		return nil, 0, ErrQueryUsersFail
+	}
+	recordAudit(AuditAdminUsersListed, &admin.ID, "users", client, fmt.Sprintf("q=%q role=%q page=%d", filter.Query, filter.Role, filter.Page))
+	return users, total, nil
+}
+
+// GetUser 查看用户详情
+func GetUser(admin models.User, id string, client ClientInfo) (*models.User, error) {
+	user, err := findUser(id)
+	if err != nil {
+		return nil, err
+	}
+	recordAudit(AuditAdminUserViewed, &admin.ID, userTarget(user), client, "")
+	return user, nil
+}
+
+// DisableUser 禁用用户：不能再登录，已登录的设备全部退出，个人访问令牌在启用前不可用
+func DisableUser(admin models.User, id, reason string, client ClientInfo) (*models.User, error) {
+	user, err := findUser(id)
+	if err != nil {
+		return nil, err
+	}
+	if user.ID == admin.ID {
+		return nil, ErrCannotModifySelf
+	}
+
+	if user.DisabledAt == nil {
+		if err := repository.UpdateUser(user, map[string]interface{}{"disabled_at": time.Now()}); err != nil {
+			return nil, ErrUpdateUserFail
+		}
+		if err := LogoutAll(*user); err != nil {
+			return nil, err
+		}
+	}
+	recordAudit(AuditAdminUserDisabled, &admin.ID, userTarget(user), client, reason)
+	return repository.GetUserByID(user.ID)
+}
+
+// EnableUser 重新启用被禁用的用户
+func EnableUser(admin models.User, id string, client ClientInfo) (*models.User, error) {
+	user, err := findUser(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := repository.UpdateUser(user, map[string]interface{}{"disabled_at": nil}); err != nil {
+		return nil, ErrUpdateUserFail
+	}
+	recordAudit(AuditAdminUserEnabled, &admin.ID, userTarget(user), client, "")
+	return repository.GetUserByID(user.ID)
+}
+
+// ResetUserPassword 重置用户密码：原密码立即失效，已登录的设备全部退出，并向用户邮箱发送设置新密码的链接。
+// 管理员不会看到也不能指定新密码
+func ResetUserPassword(admin models.User, id string, client ClientInfo) error {
+	user, err := findUser(id)
+	if err != nil {
+		return err
+	}
+
+	if err := repository.UpdateUser(user, map[string]interface{}{"password": ""}); err != nil {
+		return ErrUpdatePasswordFail
+	}
+	if err := LogoutAll(*user); err != nil {
+		return err
+	}
+	if err := ForgotPassword(user.Email); err != nil {
+		log.Printf("发送重置密码邮件给用户 %d 失败: %v", user.ID, err)
+	}
+	recordAudit(AuditAdminPasswordReset, &admin.ID, userTarget(user), client, "")
+	return nil
+}
+
+// SetUserRole 修改用户的角色和单独授予的权限。管理员不能修改自己的角色，避免误操作后系统没有管理员
+func SetUserRole(admin models.User, id, role string, perms []string, client ClientInfo) (*models.User, error) {
+	if err := validateRole(role, perms); err != nil {
+		return nil, err
+	}
+	user, err := findUser(id)
+	if err != nil {
+		return nil, err
+	}
+	if user.ID == admin.ID {
+		return nil, ErrCannotModifySelf
+	}
+
+	detail := fmt.Sprintf("role %s -> %s, permissions [%s] -> [%s]", user.Role, role, strings.Join(user.Permissions, ","), strings.Join(perms, ","))
+	if err := repository.UpdateUserRole(user, role, perms); err != nil {
+		return nil, ErrUpdateUserFail
+	}
+	recordAudit(AuditAdminRoleChanged, &admin.ID, userTarget(user), client, detail)
+	return repository.GetUserByID(user.ID)
+}
+
+// AssignRole 在命令行中设置用户角色，用于创建第一个管理员
+func AssignRole(username, role string) (*models.User, error) {
+	if err := validateRole(role, nil); err != nil {
+		return nil, err
+	}
+	user, err := repository.GetUserByUsername(username)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if err := repository.UpdateUserRole(user, role, user.Permissions); err != nil {
+		return nil, ErrUpdateUserFail
+	}
+	recordAudit(AuditAdminRoleChanged, nil, userTarget(user), ClientInfo{}, fmt.Sprintf("role %s -> %s (cli)", user.Role, role))
+	return user, nil
+}
+
+// GetSystemStats 统计系统概况
+func GetSystemStats(admin models.User, client ClientInfo) (*SystemStats, error) {
+	now := time.Now()
+	stats := &SystemStats{GeneratedAt: now}
+
+	counts := []struct {
+		dest  *int64
+		model interface{}
+		where string
+		args  []interface{}
+	}{
+		{&stats.Users.Total, &models.User{}, "", nil},
+		{&stats.Users.Disabled, &models.User{}, "disabled_at IS NOT NULL", nil},
+		{&stats.Users.Admins, &models.User{}, "role = ?", []interface{}{RoleAdmin}},
+		{&stats.Users.Verified, &models.User{}, "email_verified = ?", []interface{}{true}},
+		{&stats.Users.NewLast7Days, &models.User{}, "created_at >= ?", []interface{}{now.AddDate(0, 0, -7)}},
+		{&stats.Tasks.Total, &models.Task{}, "", nil},
+		{&stats.Tasks.Done, &models.Task{}, "status = ?", []interface{}{"done"}},
+		{&stats.ActiveSessions, &models.Session{}, "revoked_at IS NULL AND expires_at > ?", []interface{}{now}},
+		{&stats.WebhookDeliveries.Pending, &models.WebhookDelivery{}, "status = ?", []interface{}{"pending"}},
+		{&stats.WebhookDeliveries.Failed, &models.WebhookDelivery{}, "status = ?", []interface{}{"failed"}},
+		{&stats.PendingDataExports, &models.DataExport{}, "status = ?", []interface{}{"pending"}},
+	}
+	for _, c := range counts {
+		n, err := repository.Count(c.model, c.where, c.args...)
+		if err != nil {
+			return nil, ErrQueryStatsFail
+		}
+		*c.dest = n
+	}
+
+	recordAudit(AuditAdminStatsViewed, &admin.ID, "stats", client, "")
+	return stats, nil
+}
+
+// GetAuditLogs 查询审计记录
+func GetAuditLogs(admin models.User, filter AuditFilter, client ClientInfo) ([]models.AuditLog, int64, error) {
+	logs, total, err := repository.GetAuditLogs(filter.Action, filter.UserID, (filter.Page-1)*filter.PageSize, filter.PageSize)
+	if err != nil {
+		return nil, 0, ErrQueryAuditFail
+	}
+	recordAudit(AuditAdminAuditViewed, &admin.ID, "audit_logs", client, fmt.Sprintf("action=%q page=%d", filter.Action, filter.Page))
+	return logs, total, nil
+}
+
+func findUser(id string) (*models.User, error) {
+	uid, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	user, err := repository.GetUserByID(uint(uid))
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+func userTarget(user *models.User) string {
+	return fmt.Sprintf("user:%d", user.ID)
+}
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"log"
+
+	models "myproject/internal/model"
+	"myproject/internal/repository"
+)
+
+// 审计动作
+const (
+	AuditLoginFailed    = "login.failed"
+	AuditLoginBlocked   = "login.blocked"
+	AuditLoginLocked    = "login.locked"
+	AuditLoginMFAFailed = "login.mfa_failed"
+)
+
+// recordAudit 写入审计记录，失败只记录日志，不影响业务
+func recordAudit(action string, userID *uint, target string, client ClientInfo, detail string) {
+	entry := models.AuditLog{
+		UserID:    userID,
+		Action:    action,
+		Target:    truncate(target, 255),
+		IP:        client.IP,
+		UserAgent: truncate(client.UserAgent, 255),
+		Detail:    detail,
+	}
+	if err := repository.CreateAuditLog(&entry); err != nil {
+		log.Printf("写入审计记录 %s 失败: %v", action, err)
+	}
+}
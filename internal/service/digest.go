@@ -0,0 +1,101 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"myproject/internal/digest"
+	models "myproject/internal/model"
+	"myproject/internal/repository"
+)
+
+var (
+	ErrInvalidSendTime  = errors.New("invalid send time")
+	ErrSaveDigestFail   = errors.New("save digest setting failed")
+	ErrQueryDigestFail  = errors.New("query digest failed")
+	ErrRenderDigestFail = errors.New("render digest failed")
+)
+
+const (
+	defaultDigestSendTime   = "08:00"
+	maxDigestHistoryEntries = 30
+)
+
+// GetDigestSetting 获取摘要设置，未设置时返回默认值（关闭）
+func GetDigestSetting(user models.User) (*models.DigestSetting, error) {
+	setting, err := repository.GetDigestSetting(user.ID)
+	if err != nil {
+		return &models.DigestSetting{UserID: user.ID, SendTime: defaultDigestSendTime}, nil
+	}
+	return setting, nil
+}
+
+// UpdateDigestSetting 开启或关闭摘要并设置发送时间（用户时区下的 HH:MM）
+func UpdateDigestSetting(user models.User, enabled bool, sendTime string) (*models.DigestSetting, error) {
+	if sendTime == "" {
+		sendTime = defaultDigestSendTime
+	}
+	t, err := time.Parse(models.SendTimeLayout, sendTime)
+	if err != nil {
+		return nil, ErrInvalidSendTime
+	}
+
+	// 统一保存为两位小时，例如 8:00 保存为 08:00
+	setting := models.DigestSetting{UserID: user.ID, Enabled: enabled, SendTime: t.Format(models.SendTimeLayout)}
+	if err := repository.SaveDigestSetting(&setting); err != nil {
+		return nil, ErrSaveDigestFail
+	}
+	return &setting, nil
+}
+
+// GetDigestHistory 获取最近的发送记录
+func GetDigestHistory(user models.User) ([]models.DigestLog, error) {
+	logs, err := repository.GetDigestLogs(user.ID, maxDigestHistoryEntries)
+	if err != nil {
+		return nil, ErrQueryDigestFail
+	}
+	return logs, nil
+}
+
+// BuildDigest 汇总用户在 day（用户时区）这一天的摘要：当天到期、已逾期和前一天完成的任务
+func BuildDigest(user models.User, day time.Time) (*digest.Digest, error) {
+	loc := user.Location()
+	y, m, d := day.In(loc).Date()
+	start := time.Date(y, m, d, 0, 0, 0, 0, loc)
+	end := start.AddDate(0, 0, 1)
+	yesterday := start.AddDate(0, 0, -1)
+
+	today, err := repository.GetOpenTasksDueBetween(user.ID, start, end)
+	if err != nil {
+		return nil, ErrQueryDigestFail
+	}
+	overdue, err := repository.GetOpenTasksDueBetween(user.ID, time.Time{}, start)
+	if err != nil {
+		return nil, ErrQueryDigestFail
+	}
+	completed, err := repository.GetTasksCompletedBetween(user.ID, yesterday, start)
+	if err != nil {
+		return nil, ErrQueryDigestFail
+	}
+
+	return &digest.Digest{
+		User:      user,
+		Date:      start,
+		Today:     today,
+		Overdue:   overdue,
+		Completed: completed,
+	}, nil
+}
+
+// PreviewDigest 渲染今天的摘要，返回纯文本和 HTML
+func PreviewDigest(user models.User) (string, string, error) {
+	d, err := BuildDigest(user, time.Now())
+	if err != nil {
+		return "", "", err
+	}
+	text, html, err := digest.Render(*d)
+	if err != nil {
+		return "", "", ErrRenderDigestFail
+	}
+	return text, html, nil
+}
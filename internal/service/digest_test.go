@@ -0,0 +1,29 @@
+package service
+
+import (
+	"testing"
+
+	"myproject/internal/testutil"
+)
+
+func TestUpdateDigestSettingNormalizesSendTime(t *testing.T) {
+	testutil.OpenDB(t)
+	user := testutil.CreateUser(t, "alice")
+
+	setting, err := UpdateDigestSetting(user, true, "8:05")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if setting.SendTime != "08:05" {
+		t.Errorf("send time = %q, want 08:05", setting.SendTime)
+	}
+	if saved, _ := GetDigestSetting(user); saved.SendTime != "08:05" {
+		t.Errorf("saved send time = %q, want 08:05", saved.SendTime)
+	}
+
+	for _, sendTime := range []string{"24:00", "8", "08:60", "8am"} {
+		if _, err := UpdateDigestSetting(user, true, sendTime); err != ErrInvalidSendTime {
+			t.Errorf("%q: err = %v, want ErrInvalidSendTime", sendTime, err)
+		}
+	}
+}
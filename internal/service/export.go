@@ -0,0 +1,229 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"myproject/internal/dataexport"
+	"myproject/internal/mail"
+	models "myproject/internal/model"
+	"myproject/internal/repository"
+	"myproject/utils"
+)
+
+var (
+	ErrExportNotFound   = errors.New("export not found")
+	ErrCreateExportFail = errors.New("create export failed")
+)
+
+// PurposeDataExport 数据导出下载链接中的令牌
+const PurposeDataExport = "data_export"
+
+// 导出文件生成后的保留时间，下载链接同时失效
+const dataExportTTL = 24 * time.Hour
+
+// exportDir 导出文件的保存目录，由 EXPORT_DIR 配置
+func exportDir() string {
+	if dir := os.Getenv("EXPORT_DIR"); dir != "" {
+		return dir
+	}
+	return "tmp/exports"
+}
+
+// RequestDataExport 申请导出个人数据，由后台生成。已有生成中的任务时直接返回该任务，created 为 false
+func RequestDataExport(user models.User) (export *models.DataExport, created bool, err error) {
+	if pending, err := repository.GetPendingDataExport(user.ID); err == nil {
+		return pending, false, nil
+	}
+
+	export = &models.DataExport{UserID: user.ID, Status: "pending"}
+	if err := repository.CreateDataExport(export); err != nil {
+		return nil, false, ErrCreateExportFail
+	}
+	return export, true, nil
+}
+
+// GetDataExport 获取用户的导出任务
+func GetDataExport(user models.User, id string) (*models.DataExport, error) {
+	export, err := repository.GetDataExport(id, user.ID)
+	if err != nil {
+		return nil, ErrExportNotFound
+	}
+	return export, nil
+}
+
+// DataExportDownloadURL 生成导出文件的下载链接，链接与文件同时过期。文件未生成时返回空字符串
+func DataExportDownloadURL(user models.User, export *models.DataExport) (string, error) {
+	if export.Status != "ready" || export.ExpiresAt == nil {
+		return "", nil
+	}
+	ttl := time.Until(*export.ExpiresAt)
+	if ttl <= 0 {
+		return "", nil
+	}
+	token, err := utils.GenerateActionToken(user.ID, user.Email, PurposeDataExport, strconv.FormatUint(uint64(export.ID), 10), ttl)
+	if err != nil {
+		return "", ErrGenerateTokenFailed
+	}
+	return "/exports/download?token=" + url.QueryEscape(token), nil
+}
+
+// OpenDataExport 校验下载链接中的令牌，返回可以下载的导出任务
+func OpenDataExport(token string) (*models.DataExport, error) {
+	claims, err := utils.ParseActionToken(token, PurposeDataExport)
+	if err != nil {
+		return nil, ErrInvalidActionToken
+	}
+	export, err := repository.GetDataExport(claims.ID, claims.UserID)
+	if err != nil || export.Status != "ready" || export.ExpiresAt == nil || time.Now().After(*export.ExpiresAt) {
+		return nil, ErrExportNotFound
+	}
+	if _, err := os.Stat(export.FilePath); err != nil {
+		return nil, ErrExportNotFound
+	}
+	return export, nil
+}
+
+// ProcessDataExport 生成导出文件并写入结果，由持有租约的后台任务调用。
+// 成功后通过邮件通知用户；失败时标记为 failed，用户可以重新申请
+func ProcessDataExport(export models.DataExport, owner string) {
+	now := time.Now()
+	expiresAt := now.Add(dataExportTTL)
+	updates := map[string]interface{}{"completed_at": now, "expires_at": expiresAt}
+
+	user, err := repository.GetUserByID(export.UserID)
+	if err == nil {
+		var path string
+		var size int64
+		path, size, err = buildDataExport(*user, export)
+		if err == nil {
+			updates["status"] = "ready"
+			updates["file_path"] = path
+			updates["size"] = size
+		}
+	}
+	if err != nil {
+		log.Printf("生成数据导出 %d 失败: %v", export.ID, err)
+		updates["status"] = "failed"
+		updates["last_error"] = truncate(err.Error(), 512)
+	}
+
+	finished, ferr := repository.FinishDataExport(&export, owner, updates)
+	if ferr != nil || !finished {
+		// 租约已被接管或用户已注销，丢弃生成的文件
+		if path, ok := updates["file_path"].(string); ok {
+			os.Remove(path)
+		}
+		if ferr != nil {
+			log.Printf("更新数据导出 %d 失败: %v", export.ID, ferr)
+		}
+		return
+	}
+
+	if updates["status"] == "ready" {
+		sendMail(mail.Message{
+			To:      user.Email,
+			Subject: "你的数据导出已完成",
+			Text: fmt.Sprintf("你好 %s：\n\n你申请的个人数据导出已经生成，请在 %d 小时内登录后下载，过期后文件将被删除。",
+				user.Username, int(dataExportTTL/time.Hour)),
+		})
+	}
+}
+
+// buildDataExport 收集用户的全部数据写入 ZIP 文件，返回文件路径和大小
+func buildDataExport(user models.User, export models.DataExport) (string, int64, error) {
+	archive, err := collectUserData(user)
+	if err != nil {
+		return "", 0, err
+	}
+
+	dir := exportDir()
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", 0, err
+	}
+	f, err := os.CreateTemp(dir, fmt.Sprintf("export-%d-*.zip", export.ID))
+	if err != nil {
+		return "", 0, err
+	}
+	if err := dataexport.Write(f, *archive); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", 0, err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", 0, err
+	}
+
+	info, err := os.Stat(f.Name())
+	if err != nil {
+		os.Remove(f.Name())
+		return "", 0, err
+	}
+	return f.Name(), info.Size(), nil
+}
+
+// collectUserData 读取用户的全部个人数据
+func collectUserData(user models.User) (*dataexport.Archive, error) {
+	archive := &dataexport.Archive{GeneratedAt: time.Now(), User: user}
+
+	queries := []struct {
+		dest     interface{}
+		preloads []string
+	}{
+		{&archive.Tasks, []string{"Tags"}},
+		{&archive.Tags, nil},
+		{&archive.Reminders, nil},
+		{&archive.Notifications, nil},
+		{&archive.Templates, nil},
+		{&archive.DigestSettings, nil},
+		{&archive.DigestLogs, nil},
+		{&archive.Webhooks, nil},
+		{&archive.Sessions, nil},
+		{&archive.AccessTokens, nil},
+		{&archive.Identities, nil},
+		{&archive.AuditLogs, nil},
+	}
+	for _, q := range queries {
+		if err := repository.FindByUser(q.dest, user.ID, q.preloads...); err != nil {
+			return nil, err
+		}
+	}
+
+	deliveries, err := repository.GetDeliveriesByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	archive.WebhookDeliveries = deliveries
+	return archive, nil
+}
+
+// CleanupDataExports 删除过期的导出文件和记录
+func CleanupDataExports(now time.Time) error {
+	exports, err := repository.GetExpiredDataExports(now)
+	if err != nil {
+		return err
+	}
+	for _, export := range exports {
+		removeExportFile(export)
+		if err := repository.DeleteDataExport(export.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeExportFile 删除导出文件，文件不存在时忽略
+func removeExportFile(export models.DataExport) {
+	if export.FilePath == "" {
+		return
+	}
+	if err := os.Remove(export.FilePath); err != nil && !os.IsNotExist(err) {
+		log.Printf("删除数据导出文件 %s 失败: %v", export.FilePath, err)
+	}
+}
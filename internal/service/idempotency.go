@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	models "myproject/internal/model"
+	"myproject/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrIdempotencyKeyMismatch = errors.New("idempotency key reused with a different request")
+	ErrIdempotencyKeyInUse    = errors.New("idempotency key is being processed")
+	ErrIdempotencyFail        = errors.New("idempotency store failed")
+)
+
+const defaultIdempotencyTTL = 24 * time.Hour
+
+var (
+	// 首次请求的锁时长。处理期间由 HoldIdempotentRequest 定期续期，只有处理请求的实例退出、
+	// 锁不再续期并过期后，重试的请求才能接手；这也是重复请求等待首次请求完成的最长时间
+	idempotencyLock = 30 * time.Second
+	// 同一个键的并发请求等待首次请求完成时的轮询间隔
+	idempotencyPoll = 100 * time.Millisecond
+)
+
+// IdempotencyTTL 幂等键的保留时长，由 IDEMPOTENCY_TTL 配置（如 24h），默认 24 小时
+func IdempotencyTTL() time.Duration {
+	if ttl, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_TTL")); err == nil && ttl > 0 {
+		return ttl
+	}
+	return defaultIdempotencyTTL
+}
+
+// BeginIdempotentRequest 为请求登记幂等键。
+// 键第一次出现时返回本次请求持有的 record，处理完后调用 FinishIdempotentRequest；
+// 键已有完成的响应时返回 replay，调用方应原样回放；
+// 同一个键的请求正在处理时等待其完成，所以并发的重复请求会被串行化。
+func BeginIdempotentRequest(ctx context.Context, user models.User, key, fingerprint string) (record, replay *models.IdempotencyKey, err error) {
+	deadline := time.Now().Add(idempotencyLock)
+	for {
+		now := time.Now()
+		record = &models.IdempotencyKey{
+			UserID:      user.ID,
+			Key:         key,
+			Fingerprint: fingerprint,
+			Status:      "processing",
+			LockedUntil: now.Add(idempotencyLock),
+			ExpiresAt:   now.Add(IdempotencyTTL()),
+		}
+		claimed, err := repository.ClaimIdempotencyKey(record)
+		if err != nil {
+			return nil, nil, ErrIdempotencyFail
+		}
+		if claimed {
+			return record, nil, nil
+		}
+
+		existing, err := repository.GetIdempotencyKey(user.ID, key)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, ErrIdempotencyFail
+		}
+
+		switch {
+		case existing.ExpiresAt.Before(now):
+			if err := repository.DeleteIdempotencyKey(existing, time.Time{}); err != nil {
+				return nil, nil, ErrIdempotencyFail
+			}
+			continue
+		case existing.Fingerprint != fingerprint:
+			return nil, nil, ErrIdempotencyKeyMismatch
+		case existing.Status == "completed":
+			return nil, existing, nil
+		case existing.LockedUntil.Before(now):
+			// 首次请求已中断，删除后重新登记
+			if err := repository.DeleteIdempotencyKey(existing, now); err != nil {
+				return nil, nil, ErrIdempotencyFail
+			}
+			continue
+		}
+
+		if now.After(deadline) {
+			return nil, nil, ErrIdempotencyKeyInUse
+		}
+		select {
+		case <-ctx.Done():
+			return nil, nil, ErrIdempotencyKeyInUse
+		case <-time.After(idempotencyPoll):
+		}
+	}
+}
+
+// HoldIdempotentRequest 在首次请求处理期间定期延长 record 的锁，阻塞直到 ctx 结束。
+// 调用方在开始处理时启动，处理完成后取消 ctx
+func HoldIdempotentRequest(ctx context.Context, record *models.IdempotencyKey) {
+	ticker := time.NewTicker(idempotencyLock / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := repository.RenewIdempotencyKey(record, now.Add(idempotencyLock)); err != nil {
+				log.Printf("延长幂等键锁失败: %v", err)
+			}
+		}
+	}
+}
+
+// FinishIdempotentRequest 保存首次请求的响应。5xx 响应不保存，客户端可以用同一个键重试
+func FinishIdempotentRequest(record *models.IdempotencyKey, status int, headers map[string]string, body []byte) error {
+	if status >= 500 {
+		return repository.DeleteIdempotencyKey(record, time.Time{})
+	}
+	return repository.CompleteIdempotencyKey(record, status, headers, body)
+}
+
+// CleanupIdempotencyKeys 删除过期的幂等键
+func CleanupIdempotencyKeys(now time.Time) error {
+	return repository.DeleteExpiredIdempotencyKeys(now)
+}
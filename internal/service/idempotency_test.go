@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"myproject/internal/testutil"
+)
+
+func TestIdempotentRequestHoldsLockUntilFinished(t *testing.T) {
+	testutil.OpenDB(t)
+	user := testutil.CreateUser(t, "alice")
+
+	previous := idempotencyLock
+	idempotencyLock = 150 * time.Millisecond
+	t.Cleanup(func() { idempotencyLock = previous })
+
+	ctx := context.Background()
+	record, replay, err := BeginIdempotentRequest(ctx, user, "key", "fp")
+	if err != nil || record == nil || replay != nil {
+		t.Fatalf("first request: record=%v replay=%v err=%v", record, replay, err)
+	}
+	hold, release := context.WithCancel(ctx)
+	go HoldIdempotentRequest(hold, record)
+
+	// 首次请求的处理时间远超锁时长，重试的请求不能接手
+	time.Sleep(3 * idempotencyLock)
+	if _, _, err := BeginIdempotentRequest(ctx, user, "key", "fp"); err != ErrIdempotencyKeyInUse {
+		t.Fatalf("retry while the first request is running: err = %v, want ErrIdempotencyKeyInUse", err)
+	}
+
+	release()
+	if err := FinishIdempotentRequest(record, http.StatusCreated, nil, []byte(`{"id":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	_, replay, err = BeginIdempotentRequest(ctx, user, "key", "fp")
+	if err != nil || replay == nil || replay.ResponseStatus != http.StatusCreated || string(replay.ResponseBody) != `{"id":1}` {
+		t.Fatalf("retry after the first request finished: replay=%+v err=%v", replay, err)
+	}
+}
+
+func TestIdempotentRequestTakenOverAfterHolderStops(t *testing.T) {
+	testutil.OpenDB(t)
+	user := testutil.CreateUser(t, "alice")
+
+	previous := idempotencyLock
+	idempotencyLock = 100 * time.Millisecond
+	t.Cleanup(func() { idempotencyLock = previous })
+
+	// 首次请求所在的实例退出，不再续期
+	if _, _, err := BeginIdempotentRequest(context.Background(), user, "key", "fp"); err != nil {
+		t.Fatal(err)
+	}
+	record, replay, err := BeginIdempotentRequest(context.Background(), user, "key", "fp")
+	if err != nil || record == nil || replay != nil {
+		t.Fatalf("retry after the lock expired: record=%v replay=%v err=%v", record, replay, err)
+	}
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"myproject/internal/throttle"
+)
+
+// 同一用户名的失败：3 次以内不限制，之后从 1 秒开始翻倍退避，10 次后锁定 15 分钟
+var usernamePolicy = throttle.Policy{
+	FreeAttempts:     3,
+	BaseDelay:        time.Second,
+	MaxDelay:         5 * time.Minute,
+	LockoutThreshold: 10,
+	LockoutDuration:  15 * time.Minute,
+	Window:           time.Hour,
+}
+
+// 同一 IP 的失败：阈值更宽松，避免误伤同一出口的多个用户，主要用于拦截撞库
+var ipPolicy = throttle.Policy{
+	FreeAttempts:     20,
+	BaseDelay:        time.Second,
+	MaxDelay:         5 * time.Minute,
+	LockoutThreshold: 100,
+	LockoutDuration:  time.Hour,
+	Window:           time.Hour,
+}
+
+// 登录失败计数的存储，启动时通过 SetLoginThrottleStore 设置
+var loginThrottle throttle.Store = throttle.NewMemoryStore()
+
+// SetLoginThrottleStore 设置登录失败计数的存储，多实例部署时需要使用共享的存储
+func SetLoginThrottleStore(store throttle.Store) {
+	loginThrottle = store
+}
+
+// LoginThrottledError 登录尝试过于频繁，RetryAfter 之后才能再次尝试
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return fmt.Sprintf("too many login attempts, retry after %s", e.RetryAfter)
+}
+
+type throttleKey struct {
+	key    string
+	policy throttle.Policy
+}
+
+func loginThrottleKeys(username string, client ClientInfo) []throttleKey {
+	keys := []throttleKey{{key: "user:" + strings.ToLower(username), policy: usernamePolicy}}
+	if client.IP != "" {
+		keys = append(keys, throttleKey{key: "ip:" + client.IP, policy: ipPolicy})
+	}
+	return keys
+}
+
+// checkLoginThrottle 在校验密码之前检查用户名和 IP 是否处于退避或锁定中。
+// 存储出错时放行，只记录日志，避免计数存储故障导致所有人无法登录
+func checkLoginThrottle(username string, client ClientInfo) error {
+	ctx := context.Background()
+	now := time.Now()
+
+	var wait time.Duration
+	for _, k := range loginThrottleKeys(username, client) {
+		counter, err := loginThrottle.Get(ctx, k.key, now)
+		if err != nil {
+			log.Printf("读取登录失败计数 %s 失败: %v", k.key, err)
+			continue
+		}
+		if w := k.policy.RetryAfter(counter, now); w > wait {
+			wait = w
+		}
+	}
+	if wait == 0 {
+		return nil
+	}
+
+	recordAudit(AuditLoginBlocked, nil, username, client, fmt.Sprintf("retry after %s", wait.Round(time.Second)))
+	return &LoginThrottledError{RetryAfter: wait}
+}
+
+// recordLoginFailure 记录一次失败的登录并写入审计，达到阈值时额外记录锁定
+func recordLoginFailure(action, username string, userID *uint, client ClientInfo) {
+	ctx := context.Background()
+	now := time.Now()
+
+	for _, k := range loginThrottleKeys(username, client) {
+		counter, err := loginThrottle.Incr(ctx, k.key, now, k.policy.Window)
+		if err != nil {
+			log.Printf("记录登录失败计数 %s 失败: %v", k.key, err)
+			continue
+		}
+		if counter.Failures == k.policy.LockoutThreshold {
+			recordAudit(AuditLoginLocked, userID, username, client, fmt.Sprintf("%s locked for %s", k.key, k.policy.LockoutDuration))
+		}
+	}
+	recordAudit(action, userID, username, client, "")
+}
+
+// resetLoginFailures 登录成功后清除该用户名的失败计数。IP 的计数不清除，
+// 否则攻击者可以穿插登录自己的账号来绕过限制
+func resetLoginFailures(username string) {
+	if err := loginThrottle.Reset(context.Background(), "user:"+strings.ToLower(username)); err != nil {
+		log.Printf("清除用户 %s 的登录失败计数失败: %v", username, err)
+	}
+}
+
+// CleanupLoginAttempts 删除过期的登录失败计数
+func CleanupLoginAttempts(now time.Time) error {
+	return loginThrottle.Prune(context.Background(), now)
+}
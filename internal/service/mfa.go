@@ -0,0 +1,223 @@
+package service
+
+import (
+	"crypto/rand"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	models "myproject/internal/model"
+	"myproject/internal/repository"
+	"myproject/internal/totp"
+	"myproject/utils"
+)
+
+var (
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication already enabled")
+	ErrMFANotEnabled     = errors.New("two-factor authentication not enabled")
+	ErrMFASetupRequired  = errors.New("two-factor authentication setup not started")
+	ErrInvalidMFACode    = errors.New("invalid verification code")
+	ErrInvalidMFAToken   = errors.New("invalid or expired mfa token")
+	ErrUpdateMFAFail     = errors.New("update two-factor authentication failed")
+)
+
+const PurposeMFALogin = "mfa_login"
+
+const (
+	mfaTokenTTL       = 5 * time.Minute
+	mfaMaxAttempts    = 5
+	recoveryCodeCount = 10
+	totpSkew          = 1 // 允许前后各一个时间步的时钟偏差
+)
+
+// TOTPSetup 开启两步验证时返回给客户端的密钥
+type TOTPSetup struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"` // 生成二维码供验证器应用扫描
+}
+
+// SetupTOTP 生成新的 TOTP 密钥，需要用第一个验证码确认后才会启用
+func SetupTOTP(user models.User) (*TOTPSetup, error) {
+	if user.TOTPEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, ErrUpdateMFAFail
+	}
+	if err := repository.UpdateUser(&user, map[string]interface{}{"totp_secret": secret}); err != nil {
+		return nil, ErrUpdateMFAFail
+	}
+	return &TOTPSetup{
+		Secret: secret,
+		URI:    totp.URI(totpIssuer(), user.Username, secret),
+	}, nil
+}
+
+// ConfirmTOTP 校验第一个验证码并启用两步验证，返回只展示一次的恢复码
+func ConfirmTOTP(user models.User, code string) ([]string, error) {
+	if user.TOTPEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrMFASetupRequired
+	}
+
+	step, ok := totp.Validate(user.TOTPSecret, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, records, err := newRecoveryCodes(user.ID)
+	if err != nil {
+		return nil, ErrUpdateMFAFail
+	}
+	if err := repository.EnableTOTP(user.ID, step, records); err != nil {
+		return nil, ErrUpdateMFAFail
+	}
+	return codes, nil
+}
+
+// DisableTOTP 关闭两步验证，需要验证码（或恢复码）。设置过密码的用户还需要提供密码，
+// 只通过外部身份提供方登录的用户只校验验证码
+func DisableTOTP(user models.User, password, code string) error {
+	if !user.TOTPEnabled {
+		return ErrMFANotEnabled
+	}
+	if user.HasPassword() && !user.CheckPassword(password) {
+		return ErrInvalidCredentials
+	}
+	if err := verifySecondFactor(user, code); err != nil {
+		return err
+	}
+	if err := repository.DisableTOTP(user.ID); err != nil {
+		return ErrUpdateMFAFail
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，旧的恢复码全部作废
+func RegenerateRecoveryCodes(user models.User, code string) ([]string, error) {
+	if !user.TOTPEnabled {
+		return nil, ErrMFANotEnabled
+	}
+	if err := verifySecondFactor(user, code); err != nil {
+		return nil, err
+	}
+
+	codes, records, err := newRecoveryCodes(user.ID)
+	if err != nil {
+		return nil, ErrUpdateMFAFail
+	}
+	if err := repository.ReplaceRecoveryCodes(user.ID, records); err != nil {
+		return nil, ErrUpdateMFAFail
+	}
+	return codes, nil
+}
+
+// CompleteMFALogin 登录第二步：校验挑战令牌和验证码（或恢复码），成功后创建会话并签发令牌。
+// 挑战令牌只能成功使用一次，验证码错误达到上限后作废，需要重新输入密码
+func CompleteMFALogin(mfaToken, code string, client ClientInfo) (*LoginResult, error) {
+	claims, err := utils.ParseActionToken(mfaToken, PurposeMFALogin)
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+	now := time.Now()
+	record, err := repository.GetActionToken(claims.ID)
+	if err != nil || record.UserID != claims.UserID || record.UsedAt != nil || now.After(record.ExpiresAt) {
+		return nil, ErrInvalidMFAToken
+	}
+
+	user, err := repository.GetUserByID(claims.UserID)
+	if err != nil || !user.TOTPEnabled {
+		return nil, ErrInvalidMFAToken
+	}
+
+	if err := checkLoginThrottle(user.Username, client); err != nil {
+		return nil, err
+	}
+	if err := verifySecondFactor(*user, code); err != nil {
+		if err := repository.FailActionToken(record.JTI, mfaMaxAttempts, now); err != nil {
+			log.Printf("记录两步验证失败次数失败: %v", err)
+		}
+		recordLoginFailure(AuditLoginMFAFailed, user.Username, &user.ID, client)
+		return nil, err
+	}
+	resetLoginFailures(user.Username)
+	if used, err := repository.UseActionToken(record.JTI, user.ID, PurposeMFALogin, now); err != nil || !used {
+		return nil, ErrInvalidMFAToken
+	}
+
+	tokens, err := startSession(*user, client)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResult{User: user, Tokens: tokens}, nil
+}
+
+// verifySecondFactor 校验 6 位 TOTP 验证码或恢复码。同一时间步的验证码和已用过的恢复码都会被拒绝
+func verifySecondFactor(user models.User, code string) error {
+	code = strings.TrimSpace(code)
+	now := time.Now()
+
+	if len(code) == totp.Digits && strings.Trim(code, "0123456789") == "" {
+		step, ok := totp.Validate(user.TOTPSecret, code, now, totpSkew)
+		if !ok {
+			return ErrInvalidMFACode
+		}
+		consumed, err := repository.ConsumeTOTPStep(user.ID, step)
+		if err != nil || !consumed {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	used, err := repository.UseRecoveryCode(user.ID, hashToken(normalizeRecoveryCode(code)), now)
+	if err != nil || !used {
+		return ErrInvalidMFACode
+	}
+	if remaining, err := repository.CountUnusedRecoveryCodes(user.ID); err == nil && remaining == 0 {
+		log.Printf("用户 %d 的恢复码已全部用完", user.ID)
+	}
+	return nil
+}
+
+// newMFAChallenge 为开启了两步验证的用户签发登录挑战令牌
+func newMFAChallenge(user models.User) (string, error) {
+	return newActionToken(user, PurposeMFALogin, mfaTokenTTL)
+}
+
+// newRecoveryCodes 生成恢复码，格式为 xxxxx-xxxxx
+func newRecoveryCodes(userID uint) ([]string, []models.RecoveryCode, error) {
+	const alphabet = "abcdefghijklmnopqrstuvwxyz234567"
+	codes := make([]string, recoveryCodeCount)
+	records := make([]models.RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		for j := range b {
+			b[j] = alphabet[b[j]&31]
+		}
+		codes[i] = string(b[:5]) + "-" + string(b[5:])
+		records[i] = models.RecoveryCode{UserID: userID, CodeHash: hashToken(string(b))}
+	}
+	return codes, records, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// totpIssuer 验证器应用中显示的服务名，由 TOTP_ISSUER 配置
+func totpIssuer() string {
+	if issuer := os.Getenv("TOTP_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return "TaskFlow"
+}
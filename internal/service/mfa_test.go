@@ -0,0 +1,94 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"myproject/config"
+	models "myproject/internal/model"
+	"myproject/internal/repository"
+	"myproject/internal/testutil"
+	"myproject/internal/totp"
+)
+
+// enableTOTP 为用户开启两步验证，返回密钥
+func enableTOTP(t *testing.T, user *models.User) string {
+	t.Helper()
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := config.DB.Model(user).Updates(map[string]interface{}{"totp_secret": secret, "totp_enabled": true}).Error; err != nil {
+		t.Fatal(err)
+	}
+	user.TOTPSecret, user.TOTPEnabled = secret, true
+	return secret
+}
+
+func currentCode(t *testing.T, secret string) string {
+	t.Helper()
+	code, err := totp.CodeAt(secret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func TestDisableTOTPRequiresPasswordWhenSet(t *testing.T) {
+	testutil.OpenDB(t)
+	user := models.User{Username: "alice", Email: "alice@example.com", Password: "secret"}
+	if err := user.HashPassword(); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.DB.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	secret := enableTOTP(t, &user)
+
+	if err := DisableTOTP(user, "", currentCode(t, secret)); err != ErrInvalidCredentials {
+		t.Errorf("missing password: err = %v, want ErrInvalidCredentials", err)
+	}
+	code := []byte(currentCode(t, secret))
+	code[0] = '0' + (code[0]-'0'+1)%10
+	if err := DisableTOTP(user, "secret", string(code)); err != ErrInvalidMFACode {
+		t.Errorf("wrong code: err = %v, want ErrInvalidMFACode", err)
+	}
+	if err := DisableTOTP(user, "secret", currentCode(t, secret)); err != nil {
+		t.Fatalf("disable: %v", err)
+	}
+	if saved, _ := repository.GetUserByID(user.ID); saved.TOTPEnabled || saved.TOTPSecret != "" {
+		t.Errorf("two-factor authentication still enabled: %+v", saved)
+	}
+}
+
+// 只通过外部身份提供方登录的用户没有密码，凭验证码即可关闭两步验证
+func TestDisableTOTPWithoutPassword(t *testing.T) {
+	testutil.OpenDB(t)
+	user := models.User{Username: "bob", Email: "bob@example.com"}
+	if err := config.DB.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	secret := enableTOTP(t, &user)
+
+	if err := DisableTOTP(user, "", currentCode(t, secret)); err != nil {
+		t.Fatalf("disable: %v", err)
+	}
+	if saved, _ := repository.GetUserByID(user.ID); saved.TOTPEnabled {
+		t.Error("two-factor authentication still enabled")
+	}
+}
+
+// 同一个验证码不能使用两次
+func TestVerifySecondFactorRejectsReplayedCode(t *testing.T) {
+	testutil.OpenDB(t)
+	user := testutil.CreateUser(t, "carol")
+	secret := enableTOTP(t, &user)
+
+	code := currentCode(t, secret)
+	if err := verifySecondFactor(user, code); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	if err := verifySecondFactor(user, code); err != ErrInvalidMFACode {
+		t.Errorf("replayed code: err = %v, want ErrInvalidMFACode", err)
+	}
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	models "myproject/internal/model"
+	"myproject/internal/repository"
+)
+
+var (
+	ErrNotificationNotFound    = errors.New("notification not found")
+	ErrQueryNotificationFail   = errors.New("query notification failed")
+	ErrDismissNotificationFail = errors.New("dismiss notification failed")
+)
+
+const maxNotifications = 200
+
+// GetNotifications 获取通知列表，默认不含已关闭的通知
+func GetNotifications(user models.User, includeDismissed bool) ([]models.Notification, error) {
+	notifications, err := repository.GetNotificationsByUser(user.ID, includeDismissed, maxNotifications)
+	if err != nil {
+		return nil, ErrQueryNotificationFail
+	}
+	return notifications, nil
+}
+
+// DismissNotification 关闭一条通知
+func DismissNotification(user models.User, id string) error {
+	notification, err := repository.GetNotificationByID(id, user.ID)
+	if err != nil {
+		return ErrNotificationNotFound
+	}
+	if notification.DismissedAt != nil {
+		return nil
+	}
+	if err := repository.DismissNotification(notification, time.Now()); err != nil {
+		return ErrDismissNotificationFail
+	}
+	return nil
+}
+
+// DismissAllNotifications 关闭全部通知
+func DismissAllNotifications(user models.User) error {
+	if err := repository.DismissAllNotifications(user.ID, time.Now()); err != nil {
+		return ErrDismissNotificationFail
+	}
+	return nil
+}
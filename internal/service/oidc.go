@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	models "myproject/internal/model"
+	"myproject/internal/oidc"
+	"myproject/internal/repository"
+)
+
+var (
+	ErrUnknownProvider         = errors.New("unknown identity provider")
+	ErrProviderUnavailable     = errors.New("identity provider unavailable")
+	ErrInvalidOAuthState       = errors.New("invalid or expired oauth state")
+	ErrOIDCLoginFailed         = errors.New("oidc login failed")
+	ErrProviderEmailUnverified = errors.New("email not verified by identity provider")
+	ErrOIDCUserNotFound        = errors.New("no user for identity")
+	// 邮箱已被一个未验证邮箱的本地账号注册。任何人都能用别人的邮箱注册，不能据此把外部身份关联过去
+	ErrOIDCEmailUnverifiedAccount = errors.New("email belongs to an account with an unverified email")
+)
+
+const (
+	// PurposeOIDCLogin 外部登录回调签发给前端的一次性登录码
+	PurposeOIDCLogin = "oidc_login"
+
+	// OAuthStateTTL 外部登录从跳转到回调的最长时间，也是浏览器中 state cookie 的有效期
+	OAuthStateTTL  = 10 * time.Minute
+	oidcLoginTTL   = time.Minute
+	oidcTimeout    = 15 * time.Second
+	AuditOIDCLogin = "login.oidc"
+	AuditOIDCFail  = "login.oidc_failed"
+	AuditOIDCLink  = "identity.linked"
+	AuditProvision = "user.provisioned"
+)
+
+// 已配置的身份提供方，启动时通过 SetOIDCProviders 设置
+var oidcProviders = map[string]*oidc.Provider{}
+
+// SetOIDCProviders 设置可用的身份提供方，缺少必要配置的提供方会被忽略
+func SetOIDCProviders(configs []oidc.Config) {
+	providers := make(map[string]*oidc.Provider, len(configs))
+	for _, cfg := range configs {
+		if cfg.Issuer == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+			log.Printf("身份提供方 %s 缺少 ISSUER、CLIENT_ID 或 REDIRECT_URL，已忽略", cfg.Name)
+			continue
+		}
+		providers[cfg.Name] = oidc.NewProvider(cfg)
+	}
+	oidcProviders = providers
+}
+
+// OIDCProviders 返回已配置的身份提供方名字
+func OIDCProviders() []string {
+	names := make([]string, 0, len(oidcProviders))
+	for name := range oidcProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BeginOIDCLogin 生成 state、nonce 和 PKCE code_verifier，返回跳转到提供方的授权地址和 state。
+// 调用方需要把 state 保存在发起登录的浏览器中（cookie），回调时交给 CompleteOIDCLogin 比对
+func BeginOIDCLogin(name string) (authURL, state string, err error) {
+	provider, ok := oidcProviders[name]
+	if !ok {
+		return "", "", ErrUnknownProvider
+	}
+
+	state, err1 := oidc.RandomString()
+	nonce, err2 := oidc.RandomString()
+	verifier, err3 := oidc.RandomString()
+	if err := errors.Join(err1, err2, err3); err != nil {
+		return "", "", ErrOIDCLoginFailed
+	}
+
+	record := models.OAuthState{
+		State:        state,
+		Provider:     name,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(OAuthStateTTL),
+	}
+	if err := repository.CreateOAuthState(&record); err != nil {
+		return "", "", ErrOIDCLoginFailed
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), oidcTimeout)
+	defer cancel()
+	authURL, err = provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		log.Printf("身份提供方 %s 不可用: %v", name, err)
+		return "", "", ErrProviderUnavailable
+	}
+	return authURL, state, nil
+}
+
+// CompleteOIDCLogin 处理提供方回调：校验 state 与发起登录的浏览器保存的 browserState 一致，
+// 用授权码换取并校验 ID Token，找到关联的用户（必要时按已验证的邮箱关联或自动创建）后
+// 返回一次性登录码。回调是浏览器跳转，令牌不能放在响应中，前端用登录码调用 ExchangeOIDCLoginCode 换取
+func CompleteOIDCLogin(name, code, state, browserState string, client ClientInfo) (string, error) {
+	provider, ok := oidcProviders[name]
+	if !ok {
+		return "", ErrUnknownProvider
+	}
+	// state 必须来自本浏览器发起的登录，防止攻击者把自己的授权回调塞给受害者（登录 CSRF）
+	if browserState == "" || subtle.ConstantTimeCompare([]byte(state), []byte(browserState)) != 1 {
+		return "", ErrInvalidOAuthState
+	}
+	record, err := repository.TakeOAuthState(state, name, time.Now())
+	if err != nil {
+		return "", ErrInvalidOAuthState
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), oidcTimeout)
+	defer cancel()
+	token, err := provider.Exchange(ctx, code, record.CodeVerifier)
+	if err != nil {
+		log.Printf("身份提供方 %s 换取令牌失败: %v", name, err)
+		recordAudit(AuditOIDCFail, nil, name, client, "token exchange failed")
+		return "", ErrOIDCLoginFailed
+	}
+	claims, err := provider.VerifyIDToken(ctx, token.IDToken, record.Nonce)
+	if err != nil {
+		log.Printf("身份提供方 %s 的 ID Token 校验失败: %v", name, err)
+		recordAudit(AuditOIDCFail, nil, name, client, err.Error())
+		return "", ErrOIDCLoginFailed
+	}
+
+	user, err := resolveOIDCUser(provider, claims, client)
+	if err != nil {
+		recordAudit(AuditOIDCFail, nil, name+":"+claims.Subject, client, err.Error())
+		return "", err
+	}
+	recordAudit(AuditOIDCLogin, &user.ID, name+":"+claims.Subject, client, "")
+
+	if user.DisabledAt != nil {
+		return "", ErrAccountDisabled
+	}
+	return newActionToken(*user, PurposeOIDCLogin, oidcLoginTTL)
+}
+
+// ExchangeOIDCLoginCode 用外部登录回调签发的一次性登录码换取登录结果，与普通登录相同：
+// 开启了两步验证的用户返回挑战令牌，否则创建会话并签发令牌
+func ExchangeOIDCLoginCode(code string, client ClientInfo) (*LoginResult, error) {
+	claims, err := useActionToken(code, PurposeOIDCLogin)
+	if err != nil {
+		return nil, err
+	}
+	user, err := repository.GetUserByID(claims.UserID)
+	if err != nil {
+		return nil, ErrInvalidActionToken
+	}
+	if user.DisabledAt != nil {
+		return nil, ErrAccountDisabled
+	}
+
+	// 开启了两步验证的用户仍然需要第二步
+	if user.TOTPEnabled {
+		mfaToken, err := newMFAChallenge(*user)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResult{User: user, MFAToken: mfaToken}, nil
+	}
+
+	tokens, err := startSession(*user, client)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResult{User: user, Tokens: tokens}, nil
+}
+
+// OIDCResultURL 外部登录结束后跳转的前端页面。结果（登录码 code 或错误 error）放在 URL fragment 中，
+// 不会发送到服务器，也不会出现在日志和 Referer 里
+func OIDCResultURL(result url.Values) string {
+	return appBaseURL() + "/auth/callback#" + result.Encode()
+}
+
+// resolveOIDCUser 按 provider + subject 查找已关联的用户；没有关联时，提供方确认过的邮箱
+// 如果属于邮箱已验证的用户就直接关联，否则在配置允许时自动创建一个没有密码的用户。
+// 邮箱属于未验证邮箱的用户时拒绝关联：该账号可能是别人抢注的，关联后双方会共用一个账号
+func resolveOIDCUser(provider *oidc.Provider, claims *oidc.Claims, client ClientInfo) (*models.User, error) {
+	if identity, err := repository.GetIdentity(provider.Name, claims.Subject); err == nil {
+		user, err := repository.GetUserByID(identity.UserID)
+		if err != nil {
+			return nil, ErrOIDCUserNotFound
+		}
+		return user, nil
+	}
+
+	if !claims.Verified() {
+		return nil, ErrProviderEmailUnverified
+	}
+	identity := models.UserIdentity{
+		Provider: provider.Name,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}
+
+	if user, err := repository.GetUserByEmail(claims.Email); err == nil {
+		if !user.EmailVerified {
+			return nil, ErrOIDCEmailUnverifiedAccount
+		}
+		identity.UserID = user.ID
+		if err := repository.CreateIdentity(&identity); err != nil {
+			return nil, ErrOIDCLoginFailed
+		}
+		recordAudit(AuditOIDCLink, &user.ID, provider.Name+":"+claims.Subject, client, "")
+		return user, nil
+	}
+
+	if !provider.AutoProvision {
+		return nil, ErrOIDCUserNotFound
+	}
+	username, err := uniqueUsername(claims)
+	if err != nil {
+		return nil, ErrOIDCLoginFailed
+	}
+	user := models.User{
+		Username:      username,
+		Email:         claims.Email,
+		EmailVerified: true,
+	}
+	if err := repository.CreateUserWithIdentity(&user, &identity); err != nil {
+		return nil, ErrOIDCLoginFailed
+	}
+	recordAudit(AuditProvision, &user.ID, provider.Name+":"+claims.Subject, client, "")
+	return &user, nil
+}
+
+// uniqueUsername 从 preferred_username 或邮箱前缀生成用户名，重名时追加随机后缀
+func uniqueUsername(claims *oidc.Claims) (string, error) {
+	base := claims.PreferredUsername
+	if base == "" {
+		base, _, _ = strings.Cut(claims.Email, "@")
+	}
+	base = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+			return r
+		}
+		return -1
+	}, base)
+	if len(base) > 50 {
+		base = base[:50]
+	}
+	if len(base) < 3 {
+		base = "user" + base
+	}
+
+	username := base
+	for i := 0; i < 5; i++ {
+		exists, err := repository.UsernameExists(username)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return username, nil
+		}
+		suffix := make([]byte, 3)
+		if _, err := rand.Read(suffix); err != nil {
+			return "", err
+		}
+		username = fmt.Sprintf("%s-%s", base, hex.EncodeToString(suffix))
+	}
+	return "", errors.New("no available username")
+}
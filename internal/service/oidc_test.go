@@ -0,0 +1,152 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"myproject/config"
+	models "myproject/internal/model"
+	"myproject/internal/oidc"
+	"myproject/internal/repository"
+	"myproject/internal/testutil"
+)
+
+// useOIDCProvider 把模拟的身份提供方配置为 "mock"，测试结束后恢复
+func useOIDCProvider(t *testing.T, autoProvision bool) *testutil.OIDCProvider {
+	t.Helper()
+
+	testutil.UseSigningKey(t)
+	mock := testutil.StartOIDCProvider(t, "taskflow")
+	previous := oidcProviders
+	SetOIDCProviders([]oidc.Config{{
+		Name:          "mock",
+		Issuer:        mock.Issuer,
+		ClientID:      "taskflow",
+		RedirectURL:   "http://localhost:8080/auth/mock/callback",
+		Scopes:        []string{"openid", "email"},
+		AutoProvision: autoProvision,
+	}})
+	t.Cleanup(func() { oidcProviders = previous })
+	return mock
+}
+
+// oidcLogin 走完一次外部登录，browserState 为空时使用发起登录时的 state
+func oidcLogin(t *testing.T, mock *testutil.OIDCProvider, identity testutil.OIDCIdentity, browserState string) (string, error) {
+	t.Helper()
+
+	authURL, state, err := BeginOIDCLogin("mock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := mock.Authorize(t, authURL, identity)
+	if browserState == "" {
+		browserState = state
+	}
+	return CompleteOIDCLogin("mock", code, state, browserState, ClientInfo{})
+}
+
+func identityCount(t *testing.T) int64 {
+	t.Helper()
+
+	var n int64
+	if err := config.DB.Model(&models.UserIdentity{}).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// 攻击者先用受害者的邮箱注册（邮箱未验证），受害者用 SSO 登录时不能被关联到攻击者的账号
+func TestOIDCLoginDoesNotLinkAccountWithUnverifiedEmail(t *testing.T) {
+	testutil.OpenDB(t)
+	mock := useOIDCProvider(t, true)
+	squatter := testutil.CreateUser(t, "squatter")
+	if err := config.DB.Model(&squatter).Update("email", "victim@example.com").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := oidcLogin(t, mock, testutil.OIDCIdentity{Subject: "victim", Email: "victim@example.com", EmailVerified: true}, "")
+	if !errors.Is(err, ErrOIDCEmailUnverifiedAccount) {
+		t.Fatalf("err = %v, want ErrOIDCEmailUnverifiedAccount", err)
+	}
+	if n := identityCount(t); n != 0 {
+		t.Errorf("created %d identities, want none", n)
+	}
+	user, _ := repository.GetUserByID(squatter.ID)
+	if user.EmailVerified {
+		t.Error("the squatter's email was marked as verified")
+	}
+}
+
+func TestOIDCLoginLinksAccountWithVerifiedEmail(t *testing.T) {
+	testutil.OpenDB(t)
+	mock := useOIDCProvider(t, false)
+	alice := testutil.CreateUser(t, "alice")
+	if err := config.DB.Model(&alice).Update("email_verified", true).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	identity := testutil.OIDCIdentity{Subject: "alice-sub", Email: alice.Email, EmailVerified: true}
+	code, err := oidcLogin(t, mock, identity, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := ExchangeOIDCLoginCode(code, ClientInfo{})
+	if err != nil || result.User.ID != alice.ID || result.Tokens == nil {
+		t.Fatalf("exchange: result=%+v err=%v", result, err)
+	}
+	// 登录码只能使用一次
+	if _, err := ExchangeOIDCLoginCode(code, ClientInfo{}); !errors.Is(err, ErrInvalidActionToken) {
+		t.Errorf("second exchange: err = %v, want ErrInvalidActionToken", err)
+	}
+
+	// 之后按 provider + subject 找到同一个用户
+	identity.Email = "renamed@example.com"
+	code, err = oidcLogin(t, mock, identity, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result, err := ExchangeOIDCLoginCode(code, ClientInfo{}); err != nil || result.User.ID != alice.ID {
+		t.Fatalf("second login: result=%+v err=%v", result, err)
+	}
+}
+
+func TestOIDCLoginProvisionsUserWhenAllowed(t *testing.T) {
+	testutil.OpenDB(t)
+	mock := useOIDCProvider(t, true)
+
+	if _, err := oidcLogin(t, mock, testutil.OIDCIdentity{Subject: "bob-sub", Email: "bob@example.com"}, ""); !errors.Is(err, ErrProviderEmailUnverified) {
+		t.Fatalf("unverified provider email: err = %v, want ErrProviderEmailUnverified", err)
+	}
+
+	code, err := oidcLogin(t, mock, testutil.OIDCIdentity{Subject: "bob-sub", Email: "bob@example.com", EmailVerified: true}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := ExchangeOIDCLoginCode(code, ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.User.Username != "bob" || !result.User.EmailVerified || result.User.Password != "" {
+		t.Errorf("provisioned user = %+v", result.User)
+	}
+}
+
+// 回调中的 state 必须来自发起登录的同一个浏览器（登录 CSRF）
+func TestOIDCLoginRejectsStateFromAnotherBrowser(t *testing.T) {
+	testutil.OpenDB(t)
+	mock := useOIDCProvider(t, true)
+
+	identity := testutil.OIDCIdentity{Subject: "mallory", Email: "mallory@example.com", EmailVerified: true}
+	if _, err := oidcLogin(t, mock, identity, "state-of-another-browser"); !errors.Is(err, ErrInvalidOAuthState) {
+		t.Fatalf("err = %v, want ErrInvalidOAuthState", err)
+	}
+
+	authURL, state, err := BeginOIDCLogin("mock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := mock.Authorize(t, authURL, identity)
+	if _, err := CompleteOIDCLogin("mock", code, state, "", ClientInfo{}); !errors.Is(err, ErrInvalidOAuthState) {
+		t.Fatalf("missing browser state: err = %v, want ErrInvalidOAuthState", err)
+	}
+}
@@ -0,0 +1,299 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"myproject/internal/jsonpatch"
+	models "myproject/internal/model"
+	"myproject/internal/repository"
+)
+
+var (
+	ErrInvalidPatch      = errors.New("invalid patch")
+	ErrPatchTestFailed   = errors.New("patch test failed")
+	ErrFieldNotPatchable = errors.New("field not patchable")
+	ErrInvalidTask       = errors.New("invalid task")
+)
+
+// 修补格式
+const (
+	PatchMerge = "merge" // RFC 7396 JSON Merge Patch
+	PatchJSON  = "json"  // RFC 6902 JSON Patch
+)
+
+const (
+	maxRecurrenceLen = 255
+	maxTaskTags      = 50
+	// 未带 If-Match 的修补与并发写入冲突时，重新读取任务后重试的次数
+	maxPatchRetries = 3
+)
+
+// TaskDocument 任务中客户端可以整体替换或修补的字段，id、user_id 等字段不在其中
+type TaskDocument struct {
+	Description string     `json:"description"`
+	Status      string     `json:"status"`
+	Priority    string     `json:"priority"`
+	DueDate     *time.Time `json:"due_date"`
+	Recurrence  string     `json:"recurrence"`
+	Tags        []string   `json:"tags"`
+}
+
+// patchableFields TaskDocument 的 JSON 字段
+var patchableFields = map[string]bool{
+	"description": true,
+	"status":      true,
+	"priority":    true,
+	"due_date":    true,
+	"recurrence":  true,
+	"tags":        true,
+}
+
+// TaskValidationError 任务字段不合法
+type TaskValidationError struct {
+	Field  string
+	Reason string
+}
+
+func (e *TaskValidationError) Error() string {
+	return fmt.Sprintf("%s: %s %s", ErrInvalidTask, e.Field, e.Reason)
+}
+
+func (e *TaskValidationError) Unwrap() error { return ErrInvalidTask }
+
+// Validate 规范化并校验字段，规则与创建任务相同：状态默认为 pending
+func (d *TaskDocument) Validate() error {
+	d.Description = strings.TrimSpace(d.Description)
+	d.Status = strings.TrimSpace(d.Status)
+	d.Priority = strings.TrimSpace(d.Priority)
+	d.Recurrence = strings.TrimSpace(d.Recurrence)
+
+	switch d.Status {
+	case "":
+		d.Status = "pending"
+	case "pending", "done":
+	default:
+		return &TaskValidationError{"status", "must be pending or done"}
+	}
+	switch d.Priority {
+	case "", "high", "medium", "low":
+	default:
+		return &TaskValidationError{"priority", "must be high, medium or low"}
+	}
+	if len(d.Recurrence) > maxRecurrenceLen {
+		return &TaskValidationError{"recurrence", fmt.Sprintf("exceeds %d characters", maxRecurrenceLen)}
+	}
+
+	seen := make(map[string]bool, len(d.Tags))
+	tags := make([]string, 0, len(d.Tags))
+	for _, tag := range d.Tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tag) > maxTagNameLen {
+			return &TaskValidationError{"tags", fmt.Sprintf("tag %q exceeds %d characters", tag, maxTagNameLen)}
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	if len(tags) > maxTaskTags {
+		return &TaskValidationError{"tags", fmt.Sprintf("at most %d tags", maxTaskTags)}
+	}
+	d.Tags = tags
+	return nil
+}
+
+// Replace 以 doc 整体替换任务的可编辑字段，doc 中没有给出的字段会被清空
+func (s *TaskService) Replace(user models.User, id string, doc TaskDocument, ifMatch []uint64) (*models.Task, error) {
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+	return s.rewrite(user, id, ifMatch, func(*models.Task) (TaskDocument, error) {
+		return doc, nil
+	})
+}
+
+// Patch 按 patchType 指定的格式修补任务，修补结果按与创建相同的规则校验
+func (s *TaskService) Patch(user models.User, id, patchType string, patch []byte, ifMatch []uint64) (*models.Task, error) {
+	var apply func(doc interface{}) (interface{}, error)
+	switch patchType {
+	case PatchMerge:
+		mergePatch, err := jsonpatch.Decode(patch)
+		if err != nil {
+			return nil, ErrInvalidPatch
+		}
+		if _, ok := mergePatch.(map[string]interface{}); !ok {
+			return nil, ErrInvalidPatch
+		}
+		apply = func(doc interface{}) (interface{}, error) {
+			return jsonpatch.MergePatch(doc, mergePatch), nil
+		}
+	case PatchJSON:
+		ops, err := jsonpatch.ParsePatch(patch)
+		if err != nil {
+			return nil, ErrInvalidPatch
+		}
+		apply = func(doc interface{}) (interface{}, error) {
+			return jsonpatch.Apply(doc, ops)
+		}
+	default:
+		return nil, ErrInvalidPatch
+	}
+
+	return s.rewrite(user, id, ifMatch, func(task *models.Task) (TaskDocument, error) {
+		current, err := json.Marshal(taskDocument(task))
+		if err != nil {
+			return TaskDocument{}, ErrUpdateTaskFail
+		}
+		doc, err := jsonpatch.Decode(current)
+		if err != nil {
+			return TaskDocument{}, ErrUpdateTaskFail
+		}
+
+		patched, err := apply(doc)
+		if errors.Is(err, jsonpatch.ErrTestFailed) {
+			return TaskDocument{}, ErrPatchTestFailed
+		}
+		if err != nil {
+			return TaskDocument{}, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+		return decodeTaskDocument(patched)
+	})
+}
+
+// rewrite 加载任务，用 build 得到新的字段值后只写入有变化的字段，新标签与任务在同一事务中写入。
+// 写入以加载时的版本做比较并交换；没有 If-Match 时与并发写入冲突会重新加载后重试
+func (s *TaskService) rewrite(user models.User, id string, ifMatch []uint64, build func(task *models.Task) (TaskDocument, error)) (*models.Task, error) {
+	for attempt := 0; ; attempt++ {
+		task, err := s.tasks.GetByID(id, user.ID)
+		if err != nil {
+			return nil, ErrTaskNotFound
+		}
+		if ifMatch != nil && !containsRevision(ifMatch, task.Revision) {
+			return nil, ErrPreconditionFailed
+		}
+
+		doc, err := build(task)
+		if err != nil {
+			return nil, err
+		}
+
+		var updates map[string]interface{}
+		var completed bool
+		err = s.uow.Do(func(repos repository.Repositories) error {
+			var err error
+			updates, err = documentUpdates(repos.Tasks, user, task, doc)
+			if err != nil || len(updates) == 0 {
+				return err
+			}
+			completed, err = saveTaskUpdates(repos.Tasks, task, updates, true)
+			return err
+		})
+		if err == ErrPreconditionFailed && ifMatch == nil && attempt < maxPatchRetries {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(updates) == 0 {
+			return task, nil
+		}
+		if err := s.afterTaskUpdate(task, updates, completed); err != nil {
+			return nil, err
+		}
+		return task, nil
+	}
+}
+
+func taskDocument(task *models.Task) TaskDocument {
+	return TaskDocument{
+		Description: task.Description,
+		Status:      task.Status,
+		Priority:    task.Priority,
+		DueDate:     task.DueDate,
+		Recurrence:  task.Recurrence,
+		Tags:        tagNames(task.Tags),
+	}
+}
+
+// decodeTaskDocument 把修补后的通用 JSON 值转换为 TaskDocument，出现不允许修改的字段时报错
+func decodeTaskDocument(v interface{}) (TaskDocument, error) {
+	var doc TaskDocument
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return doc, fmt.Errorf("%w: task must be an object", ErrInvalidPatch)
+	}
+	var fields []string
+	for field := range obj {
+		if !patchableFields[field] {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) > 0 {
+		sort.Strings(fields)
+		return doc, fmt.Errorf("%w: %s", ErrFieldNotPatchable, strings.Join(fields, ", "))
+	}
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return doc, ErrInvalidPatch
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&doc); err != nil {
+		return doc, fmt.Errorf("%w: %v", ErrInvalidTask, err)
+	}
+	if err := doc.Validate(); err != nil {
+		return doc, err
+	}
+	return doc, nil
+}
+
+// documentUpdates 比较任务和新文档，返回有变化的列
+func documentUpdates(tasks repository.TaskRepository, user models.User, task *models.Task, doc TaskDocument) (map[string]interface{}, error) {
+	updates := make(map[string]interface{})
+	if doc.Description != task.Description {
+		updates["description"] = doc.Description
+	}
+	if doc.Status != task.Status {
+		updates["status"] = doc.Status
+	}
+	if doc.Priority != task.Priority {
+		updates["priority"] = doc.Priority
+	}
+	if !sameTime(doc.DueDate, task.DueDate) {
+		updates["due_date"] = doc.DueDate
+	}
+	if doc.Recurrence != task.Recurrence {
+		updates["recurrence"] = doc.Recurrence
+	}
+	if strings.Join(doc.Tags, "\x00") != strings.Join(tagNames(task.Tags), "\x00") {
+		tags, err := tasks.FindOrCreateTags(user.ID, doc.Tags)
+		if err != nil {
+			return nil, ErrUpdateTaskFail
+		}
+		updates["tags"] = tags
+	}
+	return updates, nil
+}
+
+func tagNames(tags []models.Tag) []string {
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
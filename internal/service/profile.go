@@ -0,0 +1,212 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"myproject/internal/mail"
+	models "myproject/internal/model"
+	"myproject/internal/repository"
+)
+
+var (
+	ErrUsernameTaken      = errors.New("username already in use")
+	ErrEmailTaken         = errors.New("email already in use")
+	ErrInvalidTimeZone    = errors.New("invalid time zone")
+	ErrUpdateProfileFail  = errors.New("update profile failed")
+	ErrDeleteConfirmation = errors.New("delete confirmation mismatch")
+	ErrDeleteUserFail     = errors.New("delete user failed")
+)
+
+// PurposeChangeEmail 确认新邮箱的一次性令牌
+const PurposeChangeEmail = "change_email"
+
+const changeEmailTTL = 24 * time.Hour
+
+// 审计动作
+const (
+	AuditPasswordChanged = "user.password_changed"
+	AuditEmailChanged    = "user.email_changed"
+	AuditUserDeleted     = "user.deleted"
+)
+
+// ProfileUpdate 修改个人资料，nil 表示不修改。修改邮箱需要当前密码
+type ProfileUpdate struct {
+	Username        *string
+	Email           *string
+	TimeZone        *string
+	CurrentPassword string
+}
+
+// UpdateProfile 修改用户名、时区和邮箱。新邮箱先保存为待确认，
+// 用户打开发到新邮箱的链接后才会生效，在此之前原邮箱照常使用
+func (s *UserService) UpdateProfile(user models.User, input ProfileUpdate) (*models.User, error) {
+	updates := make(map[string]interface{})
+
+	if input.Username != nil && *input.Username != user.Username {
+		if _, err := s.users.GetByUsername(*input.Username); err == nil {
+			return nil, ErrUsernameTaken
+		}
+		updates["username"] = *input.Username
+	}
+
+	if input.TimeZone != nil {
+		if *input.TimeZone != "" {
+			if _, err := time.LoadLocation(*input.TimeZone); err != nil {
+				return nil, ErrInvalidTimeZone
+			}
+		}
+		updates["time_zone"] = *input.TimeZone
+	}
+
+	newEmail := ""
+	if input.Email != nil {
+		if strings.EqualFold(*input.Email, user.Email) {
+			// 改回原邮箱，放弃尚未确认的修改
+			updates["pending_email"] = ""
+		} else {
+			if user.HasPassword() && !user.CheckPassword(input.CurrentPassword) {
+				return nil, ErrInvalidCredentials
+			}
+			if _, err := s.users.GetByEmail(*input.Email); err == nil {
+				return nil, ErrEmailTaken
+			}
+			newEmail = *input.Email
+			updates["pending_email"] = newEmail
+		}
+	}
+
+	if len(updates) == 0 {
+		return &user, nil
+	}
+	if err := s.users.Update(&user, updates); err != nil {
+		// 预检查之后被并发占用时由唯一索引拒绝
+		if username, ok := updates["username"].(string); ok {
+			if _, err := s.users.GetByUsername(username); err == nil {
+				return nil, ErrUsernameTaken
+			}
+		}
+		return nil, ErrUpdateProfileFail
+	}
+
+	if newEmail != "" {
+		if err := sendEmailChangeConfirmation(user, newEmail); err != nil {
+			log.Printf("发送邮箱修改确认邮件给用户 %d 失败: %v", user.ID, err)
+		}
+	}
+	return s.users.GetByID(user.ID)
+}
+
+// sendEmailChangeConfirmation 向新邮箱发送确认链接，并通知原邮箱
+func sendEmailChangeConfirmation(user models.User, newEmail string) error {
+	target := user
+	target.Email = newEmail
+	token, err := newActionToken(target, PurposeChangeEmail, changeEmailTTL)
+	if err != nil {
+		return err
+	}
+	sendMail(mail.Message{
+		To:      newEmail,
+		Subject: "确认你的新邮箱",
+		Text: fmt.Sprintf("你好 %s：\n\n请在 %d 小时内打开以下链接，把账号邮箱修改为 %s：\n%s\n\n如果这不是你的操作，请忽略本邮件。",
+			user.Username, int(changeEmailTTL/time.Hour), newEmail, appURL("/confirm-email", token)),
+	})
+	sendMail(mail.Message{
+		To:      user.Email,
+		Subject: "账号邮箱修改申请",
+		Text: fmt.Sprintf("你好 %s：\n\n你的账号申请把邮箱修改为 %s，确认后将不再使用本邮箱。\n\n如果这不是你的操作，请尽快修改密码。",
+			user.Username, newEmail),
+	})
+	return nil
+}
+
+// ConfirmEmailChange 使用发到新邮箱的链接确认修改，新邮箱随即生效并视为已验证
+func (s *UserService) ConfirmEmailChange(token string) (*models.User, error) {
+	claims, err := useActionToken(token, PurposeChangeEmail)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.users.GetByID(claims.UserID)
+	if err != nil || user.PendingEmail == "" || !strings.EqualFold(user.PendingEmail, claims.Email) {
+		// 之后又申请了别的邮箱或已撤销，旧链接作废
+		return nil, ErrInvalidActionToken
+	}
+	if _, err := s.users.GetByEmail(user.PendingEmail); err == nil {
+		return nil, ErrEmailTaken
+	}
+
+	oldEmail := user.Email
+	if err := s.users.Update(user, map[string]interface{}{
+		"email":          user.PendingEmail,
+		"email_verified": true,
+		"pending_email":  "",
+	}); err != nil {
+		return nil, ErrUpdateProfileFail
+	}
+	recordAudit(AuditEmailChanged, &user.ID, user.Username, ClientInfo{}, fmt.Sprintf("%s -> %s", oldEmail, user.Email))
+	return s.users.GetByID(user.ID)
+}
+
+// ChangePassword 修改密码。设置过密码的用户需要提供当前密码；只通过外部身份提供方登录的用户可以直接设置。
+// 修改后除 currentSessionID 以外的会话全部退出，未使用的重置密码链接作废
+func (s *UserService) ChangePassword(user models.User, currentPassword, newPassword string, currentSessionID uint, client ClientInfo) error {
+	if user.HasPassword() && !user.CheckPassword(currentPassword) {
+		return ErrInvalidCredentials
+	}
+
+	user.Password = newPassword
+	if err := user.HashPassword(); err != nil {
+		return ErrHashPasswordFailed
+	}
+
+	now := time.Now()
+	if err := s.users.Update(&user, map[string]interface{}{"password": user.Password}); err != nil {
+		return ErrUpdatePasswordFail
+	}
+	if err := repository.InvalidateActionTokens(user.ID, PurposeResetPassword, now); err != nil {
+		log.Printf("作废用户 %d 的重置密码链接失败: %v", user.ID, err)
+	}
+	if err := repository.RevokeOtherSessions(user.ID, currentSessionID, now); err != nil {
+		return ErrRevokeSessionFail
+	}
+
+	recordAudit(AuditPasswordChanged, &user.ID, user.Username, client, "")
+	sendMail(mail.Message{
+		To:      user.Email,
+		Subject: "密码已修改",
+		Text:    fmt.Sprintf("你好 %s：\n\n你的账号密码刚刚被修改，其它设备已退出登录。\n\n如果这不是你的操作，请立即通过找回密码重新设置。", user.Username),
+	})
+	return nil
+}
+
+// DeleteAccount 注销账号：删除用户的任务及全部相关数据，所有令牌随之失效。
+// 设置过密码的用户需要提供密码，否则需要输入用户名确认
+func DeleteAccount(user models.User, password, confirm string) error {
+	if user.HasPassword() {
+		if !user.CheckPassword(password) {
+			return ErrInvalidCredentials
+		}
+	} else if confirm != user.Username {
+		return ErrDeleteConfirmation
+	}
+
+	exports, err := repository.GetDataExportsByUser(user.ID)
+	if err != nil {
+		return ErrDeleteUserFail
+	}
+	if err := repository.DeleteUser(&user); err != nil {
+		return ErrDeleteUserFail
+	}
+	for _, export := range exports {
+		removeExportFile(export)
+	}
+
+	resetLoginFailures(user.Username)
+	// 审计记录中不保留用户名等可识别信息
+	recordAudit(AuditUserDeleted, nil, "", ClientInfo{}, fmt.Sprintf("user %d", user.ID))
+	return nil
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	models "myproject/internal/model"
+	"myproject/internal/notify"
+	"myproject/internal/repository"
+)
+
+var (
+	ErrReminderNotFound     = errors.New("reminder not found")
+	ErrInvalidReminder      = errors.New("invalid reminder")
+	ErrReminderNeedsDueDate = errors.New("reminder offset requires a due date")
+	ErrCreateReminderFail   = errors.New("create reminder failed")
+	ErrQueryReminderFail    = errors.New("query reminder failed")
+	ErrDeleteReminderFail   = errors.New("delete reminder failed")
+)
+
+// CreateReminder 为任务创建提醒，remindAt 与 offsetMinutes（截止时间前多少分钟）二选一
+func CreateReminder(user models.User, taskID string, remindAt *time.Time, offsetMinutes *int, channel string) (*models.Reminder, error) {
+	if (remindAt == nil) == (offsetMinutes == nil) {
+		return nil, ErrInvalidReminder
+	}
+	if offsetMinutes != nil && *offsetMinutes < 0 {
+		return nil, ErrInvalidReminder
+	}
+	if channel == "" {
+		channel = notify.ChannelInApp
+	}
+	if !notify.IsValidChannel(channel) {
+		return nil, ErrInvalidReminder
+	}
+
+	task, err := repository.GetTaskByID(taskID, user.ID)
+	if err != nil {
+		return nil, ErrTaskNotFound
+	}
+
+	reminder := models.Reminder{
+		TaskID:        task.ID,
+		UserID:        user.ID,
+		RemindAt:      remindAt,
+		OffsetMinutes: offsetMinutes,
+		Channel:       channel,
+		Status:        "pending",
+	}
+	if remindAt != nil {
+		reminder.FireAt = *remindAt
+	} else {
+		if task.DueDate == nil {
+			return nil, ErrReminderNeedsDueDate
+		}
+		reminder.FireAt = task.DueDate.Add(-time.Duration(*offsetMinutes) * time.Minute)
+	}
+
+	if err := repository.CreateReminder(&reminder); err != nil {
+		return nil, ErrCreateReminderFail
+	}
+	return &reminder, nil
+}
+
+// GetReminders 获取任务的提醒列表
+func GetReminders(user models.User, taskID string) ([]models.Reminder, error) {
+	task, err := repository.GetTaskByID(taskID, user.ID)
+	if err != nil {
+		return nil, ErrTaskNotFound
+	}
+
+	reminders, err := repository.GetRemindersByTask(task.ID, user.ID)
+	if err != nil {
+		return nil, ErrQueryReminderFail
+	}
+	return reminders, nil
+}
+
+// DeleteReminder 删除提醒
+func DeleteReminder(user models.User, taskID, reminderID string) error {
+	id, err := strconv.ParseUint(taskID, 10, 64)
+	if err != nil {
+		return ErrReminderNotFound
+	}
+
+	reminder, err := repository.GetReminderByID(reminderID, uint(id), user.ID)
+	if err != nil {
+		return ErrReminderNotFound
+	}
+	if err := repository.DeleteReminder(reminder); err != nil {
+		return ErrDeleteReminderFail
+	}
+	return nil
+}
+
+// rescheduleReminders 截止时间变化后重新计算按偏移设置的提醒；截止时间被清空时这些提醒不再触发
+func rescheduleReminders(task *models.Task) error {
+	return repository.RescheduleOffsetReminders(task)
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+
+	models "myproject/internal/model"
+)
+
+var (
+	ErrUnknownRole       = errors.New("unknown role")
+	ErrUnknownPermission = errors.New("unknown permission")
+	ErrAccountDisabled   = errors.New("account disabled")
+)
+
+// 角色
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// 权限。普通功能按模块划分，管理功能单独授予
+const (
+	PermTasks         = "tasks"
+	PermTemplates     = "templates"
+	PermNotifications = "notifications"
+	PermDigest        = "digest"
+	PermWebhooks      = "webhooks"
+
+	PermAdminUsersRead  = "admin:users:read"
+	PermAdminUsersWrite = "admin:users:write"
+	PermAdminStats      = "admin:stats"
+	PermAdminAudit      = "admin:audit"
+)
+
+var (
+	rolesMu sync.RWMutex
+	roles   = map[string][]string{
+		RoleUser: {PermTasks, PermTemplates, PermNotifications, PermDigest, PermWebhooks},
+		RoleAdmin: {PermTasks, PermTemplates, PermNotifications, PermDigest, PermWebhooks,
+			PermAdminUsersRead, PermAdminUsersWrite, PermAdminStats, PermAdminAudit},
+	}
+	permissions = map[string]bool{}
+)
+
+func init() {
+	for _, perms := range roles {
+		for _, p := range perms {
+			permissions[p] = true
+		}
+	}
+}
+
+// DefineRole 定义或覆盖一个角色及其权限，新出现的权限同时被登记，可以单独授予用户。应在启动时调用
+func DefineRole(role string, perms ...string) {
+	rolesMu.Lock()
+	defer rolesMu.Unlock()
+	roles[role] = append([]string(nil), perms...)
+	for _, p := range perms {
+		permissions[p] = true
+	}
+}
+
+// RolePermissions 角色拥有的权限，未知角色没有任何权限
+func RolePermissions(role string) []string {
+	rolesMu.RLock()
+	defer rolesMu.RUnlock()
+	return append([]string(nil), roles[role]...)
+}
+
+// Roles 全部角色名
+func Roles() []string {
+	rolesMu.RLock()
+	defer rolesMu.RUnlock()
+	names := make([]string, 0, len(roles))
+	for name := range roles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// UserPermissions 用户的全部权限：角色权限加上单独授予的权限
+func UserPermissions(user models.User) []string {
+	perms := RolePermissions(user.Role)
+	for _, p := range user.Permissions {
+		if !slices.Contains(perms, p) {
+			perms = append(perms, p)
+		}
+	}
+	return perms
+}
+
+// HasPermission 用户是否拥有权限，被禁用的用户没有任何权限
+func HasPermission(user models.User, perm string) bool {
+	if user.DisabledAt != nil {
+		return false
+	}
+	return slices.Contains(UserPermissions(user), perm)
+}
+
+// validateRole 校验角色和单独授予的权限是否已定义
+func validateRole(role string, perms []string) error {
+	rolesMu.RLock()
+	defer rolesMu.RUnlock()
+	if _, ok := roles[role]; !ok {
+		return ErrUnknownRole
+	}
+	for _, p := range perms {
+		if !permissions[p] {
+			return ErrUnknownPermission
+		}
+	}
+	return nil
+}
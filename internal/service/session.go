@@ -0,0 +1,102 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	models "myproject/internal/model"
+	"myproject/internal/repository"
+	"myproject/utils"
+)
+
+var (
+	ErrSessionNotFound   = errors.New("session not found")
+	ErrSessionRevoked    = errors.New("session revoked")
+	ErrCreateSessionFail = errors.New("create session failed")
+	ErrQuerySessionFail  = errors.New("query session failed")
+	ErrRevokeSessionFail = errors.New("revoke session failed")
+)
+
+// 最后活跃时间的落库间隔，间隔内的请求不再写数据库
+const sessionTouchInterval = 5 * time.Minute
+
+// ClientInfo 登录或刷新令牌时的客户端信息
+type ClientInfo struct {
+	DeviceName string
+	UserAgent  string
+	IP         string
+}
+
+// startSession 为一次登录创建会话并签发令牌，被禁用的用户不能登录
+func startSession(user models.User, client ClientInfo) (*TokenPair, error) {
+	if user.DisabledAt != nil {
+		return nil, ErrAccountDisabled
+	}
+
+	now := time.Now()
+	session := models.Session{
+		UserID:     user.ID,
+		DeviceName: truncate(client.DeviceName, 100),
+		UserAgent:  truncate(client.UserAgent, 255),
+		IP:         client.IP,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(RefreshTokenTTL()),
+	}
+	if err := repository.CreateSession(&session); err != nil {
+		return nil, ErrCreateSessionFail
+	}
+	return issueTokens(user, session.ID)
+}
+
+// CheckSession 校验访问令牌所属的会话仍然有效，并按间隔更新最后活跃时间
+func CheckSession(claims *utils.Claims) error {
+	session, err := repository.GetSession(claims.SessionID)
+	if err != nil || session.UserID != claims.UserID {
+		return ErrSessionNotFound
+	}
+
+	now := time.Now()
+	if session.RevokedAt != nil || now.After(session.ExpiresAt) {
+		return ErrSessionRevoked
+	}
+	if now.Sub(session.LastSeenAt) >= sessionTouchInterval {
+		if err := repository.TouchSession(session.ID, now, now.Add(-sessionTouchInterval)); err != nil {
+			log.Printf("更新会话 %d 活跃时间失败: %v", session.ID, err)
+		}
+	}
+	return nil
+}
+
+// GetSessions 获取用户当前登录的全部会话，currentID 对应的会话会被标记为当前会话
+func GetSessions(user models.User, currentID uint) ([]models.Session, error) {
+	sessions, err := repository.GetActiveSessionsByUser(user.ID, time.Now())
+	if err != nil {
+		return nil, ErrQuerySessionFail
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+	return sessions, nil
+}
+
+// RevokeSession 吊销用户的某个会话，该会话的令牌随即失效
+func RevokeSession(user models.User, id string) error {
+	now := time.Now()
+	session, err := repository.GetActiveSessionByID(id, user.ID, now)
+	if err != nil {
+		return ErrSessionNotFound
+	}
+	if err := repository.RevokeSession(session.ID, now); err != nil {
+		return ErrRevokeSessionFail
+	}
+	return nil
+}
+
+// truncate 按字符截断，避免超出列长度
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
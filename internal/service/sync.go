@@ -0,0 +1,432 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"myproject/internal/events"
+	models "myproject/internal/model"
+	"myproject/internal/repository"
+)
+
+var (
+	ErrInvalidSyncToken   = errors.New("invalid sync token")
+	ErrTooManySyncChanges = errors.New("too many sync changes")
+	ErrSyncFail           = errors.New("sync failed")
+)
+
+const (
+	// 单次同步最多返回的任务变更数，超过时 has_more 为 true，客户端应带新令牌继续同步
+	syncPageSize = 500
+	// 单次同步最多接受的客户端变更数
+	maxSyncChanges = 500
+	// 一条变更被并发写入抢先后最多重新合并的次数
+	maxSyncRetries = 5
+)
+
+// 客户端变更的处理结果
+const (
+	SyncApplied  = "applied"
+	SyncConflict = "conflict" // 部分或全部字段与服务端的修改冲突，结果见 conflicts
+	SyncRejected = "rejected"
+	SyncNotFound = "not_found"
+)
+
+// SyncChange 客户端离线期间对一个任务的修改
+type SyncChange struct {
+	ID           uint                       `json:"id"`            // 服务端任务ID，新建时为空
+	ClientID     string                     `json:"client_id"`     // 客户端生成的唯一ID，新建时必填，重试同一批变更时用于去重
+	BaseRevision uint64                     `json:"base_revision"` // 客户端修改时所基于的任务版本
+	ModifiedAt   time.Time                  `json:"modified_at"`   // 客户端修改时间，冲突时按字段比较先后
+	Deleted      bool                       `json:"deleted"`
+	Fields       map[string]json.RawMessage `json:"fields"` // 修改过的字段，见 models.SyncFields
+}
+
+// FieldConflict 一个字段的冲突及合并结果
+type FieldConflict struct {
+	Field       string      `json:"field"`
+	Winner      string      `json:"winner"` // client 或 server
+	ServerValue interface{} `json:"server_value"`
+}
+
+// SyncResult 一条客户端变更的处理结果
+type SyncResult struct {
+	ClientID  string          `json:"client_id,omitempty"`
+	ID        uint            `json:"id,omitempty"`
+	Status    string          `json:"status"`
+	Revision  uint64          `json:"revision,omitempty"`
+	Conflicts []FieldConflict `json:"conflicts,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// Tombstone 已删除的任务
+type Tombstone struct {
+	ID  uint   `json:"id"`
+	Seq uint64 `json:"seq"`
+}
+
+// SyncResponse 同步结果
+type SyncResponse struct {
+	SyncToken  string        `json:"sync_token"`
+	FullResync bool          `json:"full_resync"` // 为 true 时 tasks 是全部任务，客户端应丢弃本地未在其中的任务
+	HasMore    bool          `json:"has_more"`
+	Tasks      []models.Task `json:"tasks"`
+	Tombstones []Tombstone   `json:"tombstones"`
+	Results    []SyncResult  `json:"results"`
+}
+
+// Sync 先按字段最后写入者胜出的规则合并客户端变更，再返回 token 之后服务端的全部变更（含删除墓碑）。
+// token 为空表示首次同步，返回全部任务。
+func Sync(user models.User, token string, changes []SyncChange) (*SyncResponse, error) {
+	after, err := parseSyncToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) > maxSyncChanges {
+		return nil, ErrTooManySyncChanges
+	}
+
+	resp := &SyncResponse{
+		Tasks:      []models.Task{},
+		Tombstones: []Tombstone{},
+		Results:    make([]SyncResult, 0, len(changes)),
+	}
+	for i := range changes {
+		resp.Results = append(resp.Results, applySyncChange(user, &changes[i]))
+	}
+
+	latest, err := repository.GetLatestChangeSeq()
+	if err != nil {
+		return nil, ErrSyncFail
+	}
+
+	if after == 0 {
+		resp.FullResync = true
+		resp.SyncToken = formatSyncToken(latest)
+		err := repository.EachTaskByUser(user.ID, exportBatchSize, func(task *models.Task) error {
+			resp.Tasks = append(resp.Tasks, *task)
+			return nil
+		})
+		if err != nil {
+			return nil, ErrSyncFail
+		}
+		return resp, nil
+	}
+
+	changed, err := repository.GetChangedTasks(user.ID, after, latest, syncPageSize+1)
+	if err != nil {
+		return nil, ErrSyncFail
+	}
+	resp.SyncToken = formatSyncToken(latest)
+	if len(changed) > syncPageSize {
+		changed = changed[:syncPageSize]
+		resp.HasMore = true
+		resp.SyncToken = formatSyncToken(changed[len(changed)-1].Seq)
+	}
+
+	ids := make([]uint, 0, len(changed))
+	for _, c := range changed {
+		ids = append(ids, c.TaskID)
+	}
+	tasks, err := repository.GetTasksByIDs(user.ID, ids)
+	if err != nil {
+		return nil, ErrSyncFail
+	}
+	alive := make(map[uint]bool, len(tasks))
+	for _, task := range tasks {
+		alive[task.ID] = true
+	}
+	resp.Tasks = tasks
+	for _, c := range changed {
+		if !alive[c.TaskID] {
+			resp.Tombstones = append(resp.Tombstones, Tombstone{ID: c.TaskID, Seq: c.Seq})
+		}
+	}
+	return resp, nil
+}
+
+func applySyncChange(user models.User, change *SyncChange) SyncResult {
+	result := SyncResult{ClientID: change.ClientID, ID: change.ID}
+	if change.ModifiedAt.IsZero() || change.ModifiedAt.After(time.Now()) {
+		// 客户端时钟不可信时以服务端收到的时间为准
+		change.ModifiedAt = time.Now()
+	}
+
+	updates, err := parseSyncFields(user, change.Fields)
+	if err != nil {
+		result.Status = SyncRejected
+		result.Error = err.Error()
+		return result
+	}
+
+	var task *models.Task
+	switch {
+	case change.ID != 0:
+		task, err = repository.GetTaskByID(strconv.FormatUint(uint64(change.ID), 10), user.ID)
+	case change.ClientID != "":
+		if len(change.ClientID) > maxExternalIDLen {
+			result.Status = SyncRejected
+			result.Error = "client_id too long"
+			return result
+		}
+		// 同一批变更重试时，client_id 对应的任务已经创建过
+		task, err = repository.GetTaskByExternalID(change.ClientID, user.ID)
+		if err != nil {
+			if change.Deleted {
+				result.Status = SyncApplied
+				return result
+			}
+			return createSyncedTask(user, change, updates)
+		}
+	default:
+		result.Status = SyncRejected
+		result.Error = "id or client_id is required"
+		return result
+	}
+	if err != nil {
+		result.Status = SyncNotFound
+		return result
+	}
+	result.ID = task.ID
+
+	// 合并基于读取时的字段修改记录，写入时以版本号为条件。被其他写入抢先时重新读取任务再合并，
+	// 期间服务端的修改同样参与按字段的先后比较，不会被直接覆盖
+	for i := 0; i < maxSyncRetries; i++ {
+		if change.Deleted {
+			result, err = deleteSyncedTask(task, change, result)
+		} else {
+			result, err = updateSyncedTask(task, change, updates, result)
+		}
+		if err != repository.ErrRevisionMismatch {
+			return result
+		}
+
+		task, err = repository.GetTaskByID(strconv.FormatUint(uint64(task.ID), 10), user.ID)
+		if err != nil {
+			// 任务已被并发删除
+			result = SyncResult{ClientID: change.ClientID, ID: result.ID, Status: SyncNotFound}
+			if change.Deleted {
+				result.Status = SyncApplied
+			}
+			return result
+		}
+	}
+	result = SyncResult{ClientID: change.ClientID, ID: result.ID, Status: SyncRejected, Error: ErrUpdateTaskFail.Error()}
+	if change.Deleted {
+		result.Error = ErrDeleteTaskFail.Error()
+	}
+	return result
+}
+
+// updateSyncedTask 按字段合并客户端的修改后写入。task 在读取之后被修改过时返回 repository.ErrRevisionMismatch
+func updateSyncedTask(task *models.Task, change *SyncChange, updates map[string]interface{}, result SyncResult) (SyncResult, error) {
+	// 服务端在客户端基准版本之后改过的字段视为冲突，按修改时间决定保留哪一方
+	merged := make(map[string]interface{}, len(updates))
+	result.Conflicts = nil
+	for _, field := range models.SyncFields {
+		value, ok := updates[field]
+		if !ok {
+			continue
+		}
+		stamp := task.FieldStamps[field]
+		if stamp.Revision <= change.BaseRevision {
+			merged[field] = value
+			continue
+		}
+		conflict := FieldConflict{Field: field, Winner: "client", ServerValue: taskFieldValue(task, field)}
+		if change.ModifiedAt.After(stamp.UpdatedAt) {
+			merged[field] = value
+		} else {
+			conflict.Winner = "server"
+		}
+		result.Conflicts = append(result.Conflicts, conflict)
+	}
+
+	result.Status = SyncApplied
+	if len(result.Conflicts) > 0 {
+		result.Status = SyncConflict
+	}
+	if len(merged) == 0 {
+		result.Revision = task.Revision
+		return result, nil
+	}
+
+	completed := trackCompletion(task, merged)
+	if err := repository.UpdateTaskAtIfUnchanged(task, merged, change.ModifiedAt); err != nil {
+		if err == repository.ErrRevisionMismatch {
+			return result, err
+		}
+		result.Status = SyncRejected
+		result.Error = ErrUpdateTaskFail.Error()
+		result.Conflicts = nil
+		return result, nil
+	}
+	if _, ok := merged["due_date"]; ok {
+		rescheduleReminders(task)
+	}
+	publishTaskUpdate(*task, completed)
+	result.Revision = task.Revision
+	return result, nil
+}
+
+func createSyncedTask(user models.User, change *SyncChange, updates map[string]interface{}) SyncResult {
+	result := SyncResult{ClientID: change.ClientID}
+	clientID := change.ClientID
+	task := models.Task{UserID: user.ID, ExternalID: &clientID}
+	if v, ok := updates["description"].(string); ok {
+		task.Description = v
+	}
+	if v, ok := updates["status"].(string); ok {
+		task.Status = v
+	}
+	if v, ok := updates["priority"].(string); ok {
+		task.Priority = v
+	}
+	if v, ok := updates["due_date"].(*time.Time); ok {
+		task.DueDate = v
+	}
+	if v, ok := updates["recurrence"].(string); ok {
+		task.Recurrence = v
+	}
+	if v, ok := updates["tags"].([]models.Tag); ok {
+		task.Tags = v
+	}
+	if task.Description == "" {
+		result.Status = SyncRejected
+		result.Error = "description is required"
+		return result
+	}
+	if task.Status == "done" {
+		completedAt := change.ModifiedAt
+		task.CompletedAt = &completedAt
+	}
+
+	if err := repository.CreateTask(&task); err != nil {
+		result.Status = SyncRejected
+		result.Error = ErrCreateTaskFail.Error()
+		return result
+	}
+	publishTaskEvent(events.TaskCreated, task)
+	result.Status = SyncApplied
+	result.ID = task.ID
+	result.Revision = task.Revision
+	return result
+}
+
+// deleteSyncedTask 删除与修改冲突时同样按时间先后决定：服务端在客户端删除之后还修改过任务，则保留任务。
+// task 在读取之后被修改过时返回 repository.ErrRevisionMismatch
+func deleteSyncedTask(task *models.Task, change *SyncChange, result SyncResult) (SyncResult, error) {
+	if task.Revision > change.BaseRevision && !change.ModifiedAt.After(task.UpdatedAt) {
+		result.Status = SyncConflict
+		result.Revision = task.Revision
+		result.Conflicts = []FieldConflict{{Field: "deleted", Winner: "server", ServerValue: false}}
+		return result, nil
+	}
+
+	if err := repository.DeleteTaskIfUnchanged(task); err != nil {
+		if err == repository.ErrRevisionMismatch {
+			return result, err
+		}
+		result.Status = SyncRejected
+		result.Error = ErrDeleteTaskFail.Error()
+		return result, nil
+	}
+	publishTaskEvent(events.TaskDeleted, *task)
+	result.Status = SyncApplied
+	return result, nil
+}
+
+// parseSyncFields 校验客户端提交的字段并转换为列名到值的映射
+func parseSyncFields(user models.User, fields map[string]json.RawMessage) (map[string]interface{}, error) {
+	updates := make(map[string]interface{}, len(fields))
+	for field, raw := range fields {
+		switch field {
+		case "description", "status", "priority", "recurrence":
+			var v string
+			if err := json.Unmarshal(raw, &v); err != nil {
+				return nil, fmt.Errorf("invalid %s", field)
+			}
+			v = strings.TrimSpace(v)
+			switch {
+			case field == "description" && v == "":
+				return nil, errors.New("description is required")
+			case field == "status" && v != "pending" && v != "done":
+				return nil, fmt.Errorf("invalid status %q", v)
+			case field == "priority" && v != "" && v != "high" && v != "medium" && v != "low":
+				return nil, fmt.Errorf("invalid priority %q", v)
+			}
+			updates[field] = v
+		case "due_date":
+			var v *time.Time
+			if err := json.Unmarshal(raw, &v); err != nil {
+				return nil, errors.New("invalid due_date")
+			}
+			updates[field] = v
+		case "tags":
+			var names []string
+			if err := json.Unmarshal(raw, &names); err != nil {
+				return nil, errors.New("invalid tags")
+			}
+			cleaned := names[:0]
+			for _, name := range names {
+				name = strings.TrimSpace(name)
+				if name == "" {
+					continue
+				}
+				if len(name) > maxTagNameLen {
+					return nil, fmt.Errorf("tag %q exceeds %d characters", name, maxTagNameLen)
+				}
+				cleaned = append(cleaned, name)
+			}
+			tags, err := repository.FindOrCreateTags(user.ID, cleaned)
+			if err != nil {
+				return nil, ErrSyncFail
+			}
+			updates[field] = tags
+		default:
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+	}
+	return updates, nil
+}
+
+func taskFieldValue(task *models.Task, field string) interface{} {
+	switch field {
+	case "description":
+		return task.Description
+	case "status":
+		return task.Status
+	case "priority":
+		return task.Priority
+	case "due_date":
+		return task.DueDate
+	case "recurrence":
+		return task.Recurrence
+	case "tags":
+		names := make([]string, 0, len(task.Tags))
+		for _, tag := range task.Tags {
+			names = append(names, tag.Name)
+		}
+		return names
+	}
+	return nil
+}
+
+func parseSyncToken(token string) (uint64, error) {
+	if token == "" {
+		return 0, nil
+	}
+	seq, err := strconv.ParseUint(token, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidSyncToken
+	}
+	return seq, nil
+}
+
+func formatSyncToken(seq uint64) string {
+	return strconv.FormatUint(seq, 10)
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	models "myproject/internal/model"
+	"myproject/internal/repository"
+	"myproject/internal/testutil"
+
+	"gorm.io/gorm"
+)
+
+// 服务端在同步读取任务之后、写入之前修改了同一字段，写入时重新合并，较新的服务端修改不会被覆盖
+func TestSyncRemergesAfterConcurrentServerEdit(t *testing.T) {
+	db := testutil.OpenDB(t)
+	user := testutil.CreateUser(t, "alice")
+	task := models.Task{Description: "draft", UserID: user.ID}
+	if err := repository.CreateTask(&task); err != nil {
+		t.Fatal(err)
+	}
+	id := strconv.FormatUint(uint64(task.ID), 10)
+
+	// 同步第一次读取任务后，模拟另一个请求修改描述
+	edited := false
+	err := db.Callback().Query().After("gorm:query").Register("test:concurrent_edit", func(tx *gorm.DB) {
+		if edited || tx.Statement.Table != "tasks" {
+			return
+		}
+		edited = true
+		current, err := repository.GetTaskByID(id, user.ID)
+		if err == nil {
+			err = repository.UpdateTask(current, map[string]interface{}{"description": "server edit"})
+		}
+		if err != nil {
+			t.Error(err)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := Sync(user, "", []SyncChange{{
+		ID:           task.ID,
+		BaseRevision: task.Revision,
+		ModifiedAt:   time.Now().Add(-time.Minute),
+		Fields: map[string]json.RawMessage{
+			"description": json.RawMessage(`"client edit"`),
+			"priority":    json.RawMessage(`"high"`),
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !edited {
+		t.Fatal("concurrent edit did not run")
+	}
+
+	result := resp.Results[0]
+	if result.Status != SyncConflict || len(result.Conflicts) != 1 || result.Conflicts[0].Field != "description" || result.Conflicts[0].Winner != "server" {
+		t.Errorf("result = %+v, want a description conflict won by the server", result)
+	}
+	got, err := repository.GetTaskByID(id, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 不冲突的字段仍然写入
+	if got.Description != "server edit" || got.Priority != "high" || got.Revision != 3 || result.Revision != 3 {
+		t.Errorf("task = %q priority=%q revision=%d, result revision %d", got.Description, got.Priority, got.Revision, result.Revision)
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"errors"
 	"time"
 
+	"myproject/internal/events"
 	models "myproject/internal/model"
+	"myproject/internal/quickadd"
 	"myproject/internal/repository"
 )
 
@@ -14,68 +16,206 @@ var (
 	ErrQueryTaskFail  = errors.New("query task failed")
 	ErrUpdateTaskFail = errors.New("update task failed")
 	ErrDeleteTaskFail = errors.New("delete task failed")
+	ErrEmptyTaskTitle = errors.New("empty task title")
+	ErrInvalidDate    = errors.New("invalid date")
+	// ErrPreconditionFailed If-Match 给出的版本与任务当前版本不一致
+	ErrPreconditionFailed = errors.New("precondition failed")
 )
 
-// CreateTask 创建任务
-func CreateTask(user models.User, description string) (*models.Task, error) {
+// TaskService 任务的增删改查。存储通过构造函数注入，测试时可以换成 repository/memory 的实现
+type TaskService struct {
+	tasks repository.TaskRepository
+	uow   repository.UnitOfWork
+	// rescheduleReminders 截止时间变化后调整提醒，测试时可以替换
+	rescheduleReminders func(task *models.Task) error
+}
+
+// NewTaskService 创建任务服务，uow 用于需要在同一事务中完成的多步写入
+func NewTaskService(tasks repository.TaskRepository, uow repository.UnitOfWork) *TaskService {
+	return &TaskService{tasks: tasks, uow: uow, rescheduleReminders: rescheduleReminders}
+}
+
+// Create 创建任务
+func (s *TaskService) Create(user models.User, description string) (*models.Task, error) {
 	task := models.Task{
 		Description: description,
 		UserID:      user.ID,
 	}
 
-	if err := repository.CreateTask(&task); err != nil {
+	if err := s.tasks.Create(&task); err != nil {
 		return nil, ErrCreateTaskFail
 	}
 
+	publishTaskEvent(events.TaskCreated, task)
 	return &task, nil
 }
 
-// GetTasks 获取任务列表（按日期）
-func GetTasks(user models.User, date string) ([]models.Task, error) {
+// QuickAdd 解析一句自然语言并创建任务，日期按用户所在时区计算。标签和任务在同一事务中创建
+func (s *TaskService) QuickAdd(user models.User, text string) (*models.Task, *quickadd.Result, error) {
+	parsed := quickadd.Parse(text, time.Now().In(user.Location()))
+	if parsed.Title == "" {
+		return nil, &parsed, ErrEmptyTaskTitle
+	}
+
+	task := models.Task{
+		Description: parsed.Title,
+		Priority:    parsed.Priority,
+		DueDate:     parsed.DueDate,
+		Recurrence:  parsed.Recurrence,
+		UserID:      user.ID,
+	}
+
+	err := s.uow.Do(func(repos repository.Repositories) error {
+		tags, err := repos.Tasks.FindOrCreateTags(user.ID, parsed.Tags)
+		if err != nil {
+			return err
+		}
+		task.Tags = tags
+		return repos.Tasks.Create(&task)
+	})
+	if err != nil {
+		return nil, &parsed, ErrCreateTaskFail
+	}
+
+	publishTaskEvent(events.TaskCreated, task)
+	return &task, &parsed, nil
+}
+
+// List 获取任务列表（按日期）
+func (s *TaskService) List(user models.User, date string) ([]models.Task, error) {
 	if date == "" {
 		date = time.Now().Format("2006-01-02")
 	}
 
-	tasks, err := repository.GetTasksByUserAndDate(user.ID, date)
+	tasks, err := s.tasks.ListByUserAndDate(user.ID, date)
+	if err == repository.ErrInvalidDate {
+		return nil, ErrInvalidDate
+	}
 	if err != nil {
 		return nil, ErrQueryTaskFail
 	}
 	return tasks, nil
 }
 
-// GetTask 获取单个任务
-func GetTask(user models.User, id string) (*models.Task, error) {
-	task, err := repository.GetTaskByID(id, user.ID)
+// Get 获取单个任务
+func (s *TaskService) Get(user models.User, id string) (*models.Task, error) {
+	task, err := s.tasks.GetByID(id, user.ID)
 	if err != nil {
 		return nil, ErrTaskNotFound
 	}
 	return task, nil
 }
 
-// UpdateTask 更新任务
-func UpdateTask(user models.User, id string, updates map[string]interface{}) (*models.Task, error) {
-	task, err := repository.UpdateTask(id, user.ID)
+// Update 更新任务。ifMatch 不为空时只有任务当前版本在其中才更新，
+// 并以该版本做比较并交换，期间被其他请求修改则返回 ErrPreconditionFailed
+func (s *TaskService) Update(user models.User, id string, updates map[string]interface{}, ifMatch []uint64) (*models.Task, error) {
+	task, err := s.tasks.GetByID(id, user.ID)
 	if err != nil {
 		return nil, ErrTaskNotFound
 	}
-
-	if err := repository.UpdateTask(task, updates); err != nil {
-		return nil, ErrUpdateTaskFail
+	if ifMatch != nil && !containsRevision(ifMatch, task.Revision) {
+		return nil, ErrPreconditionFailed
 	}
 
+	completed, err := saveTaskUpdates(s.tasks, task, updates, ifMatch != nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.afterTaskUpdate(task, updates, completed); err != nil {
+		return nil, err
+	}
 	return task, nil
 }
 
-// DeleteTask 删除任务
-func DeleteTask(user models.User, id string) error {
-	task, err := repository.GetTaskByID(id, user.ID)
+// saveTaskUpdates 写入已加载任务的更新，返回任务是否因此被完成。conditional 为 true 时
+// 只有存储中的版本仍与 task 相同才写入，否则返回 ErrPreconditionFailed
+func saveTaskUpdates(tasks repository.TaskRepository, task *models.Task, updates map[string]interface{}, conditional bool) (bool, error) {
+	completed := trackCompletion(task, updates)
+	var err error
+	if conditional {
+		err = tasks.UpdateIfUnchanged(task, updates)
+	} else {
+		err = tasks.Update(task, updates)
+	}
+	if err == repository.ErrRevisionMismatch {
+		return false, ErrPreconditionFailed
+	}
+	if err != nil {
+		return false, ErrUpdateTaskFail
+	}
+	return completed, nil
+}
+
+// afterTaskUpdate 更新写入后调整提醒并通知订阅者
+func (s *TaskService) afterTaskUpdate(task *models.Task, updates map[string]interface{}, completed bool) error {
+	if _, ok := updates["due_date"]; ok {
+		if err := s.rescheduleReminders(task); err != nil {
+			return ErrUpdateTaskFail
+		}
+	}
+
+	publishTaskUpdate(*task, completed)
+	return nil
+}
+
+// trackCompletion 状态变为 done 时记录完成时间，重新打开时清空；返回任务是否因此被完成
+func trackCompletion(task *models.Task, updates map[string]interface{}) bool {
+	status, ok := updates["status"]
+	if !ok || status == task.Status {
+		return false
+	}
+	if status == "done" {
+		updates["completed_at"] = time.Now()
+		return true
+	}
+	updates["completed_at"] = nil
+	return false
+}
+
+// Delete 删除任务，ifMatch 的含义同 Update
+func (s *TaskService) Delete(user models.User, id string, ifMatch []uint64) error {
+	task, err := s.tasks.GetByID(id, user.ID)
 	if err != nil {
 		return ErrTaskNotFound
 	}
+	if ifMatch != nil && !containsRevision(ifMatch, task.Revision) {
+		return ErrPreconditionFailed
+	}
 
-	if err := repository.DeleteTask(task); err != nil {
+	if ifMatch != nil {
+		err = s.tasks.DeleteIfUnchanged(task)
+	} else {
+		err = s.tasks.Delete(task)
+	}
+	if err == repository.ErrRevisionMismatch {
+		return ErrPreconditionFailed
+	}
+	if err != nil {
 		return ErrDeleteTaskFail
 	}
 
+	publishTaskEvent(events.TaskDeleted, *task)
 	return nil
-}
\ No newline at end of file
+}
+
+func containsRevision(revisions []uint64, revision uint64) bool {
+	for _, r := range revisions {
+		if r == revision {
+			return true
+		}
+	}
+	return false
+}
+
+// publishTaskEvent 通知订阅者任务已变更
+func publishTaskEvent(typ string, task models.Task) {
+	events.Publish(events.Event{Type: typ, UserID: task.UserID, Task: task})
+}
+
+// publishTaskUpdate 发布 task.updated，任务刚被完成时再发布 task.completed
+func publishTaskUpdate(task models.Task, completed bool) {
+	publishTaskEvent(events.TaskUpdated, task)
+	if completed {
+		publishTaskEvent(events.TaskCompleted, task)
+	}
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	models "myproject/internal/model"
+	"myproject/internal/repository"
+	"myproject/internal/repository/memory"
+)
+
+// newMemoryTaskService 创建使用内存存储的 TaskService，rescheduled 记录被调整提醒的任务
+func newMemoryTaskService() (*TaskService, *memory.Store, *[]uint) {
+	store := memory.New()
+	s := NewTaskService(store.Tasks(), store)
+	var rescheduled []uint
+	s.rescheduleReminders = func(task *models.Task) error {
+		rescheduled = append(rescheduled, task.ID)
+		return nil
+	}
+	return s, store, &rescheduled
+}
+
+func taskID(task *models.Task) string {
+	return strconv.FormatUint(uint64(task.ID), 10)
+}
+
+func TestTaskServiceCreateAndGet(t *testing.T) {
+	s, _, _ := newMemoryTaskService()
+	alice, bob := models.User{ID: 1}, models.User{ID: 2}
+
+	task, err := s.Create(alice, "Write report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Revision != 1 || task.Status != "pending" {
+		t.Errorf("created task = %+v", task)
+	}
+
+	got, err := s.Get(alice, taskID(task))
+	if err != nil || got.Description != "Write report" {
+		t.Fatalf("get: task=%+v err=%v", got, err)
+	}
+	if _, err := s.Get(bob, taskID(task)); err != ErrTaskNotFound {
+		t.Errorf("other user's task: err = %v, want ErrTaskNotFound", err)
+	}
+
+	today := time.Now().Format("2006-01-02")
+	if tasks, err := s.List(alice, today); err != nil || len(tasks) != 1 {
+		t.Errorf("list today: %d tasks, err = %v", len(tasks), err)
+	}
+	if _, err := s.List(alice, "2024-13-01"); err != ErrInvalidDate {
+		t.Errorf("list with a bad date: err = %v, want ErrInvalidDate", err)
+	}
+}
+
+func TestTaskServiceQuickAddCreatesTags(t *testing.T) {
+	s, _, _ := newMemoryTaskService()
+	user := models.User{ID: 1}
+
+	task, parsed, err := s.QuickAdd(user, "Send report tomorrow 5pm #work !high")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Description != "Send report" || task.Priority != "high" || task.DueDate == nil || parsed.Title != "Send report" {
+		t.Errorf("quick add task = %+v", task)
+	}
+	if len(task.Tags) != 1 || task.Tags[0].Name != "work" {
+		t.Errorf("tags = %+v", task.Tags)
+	}
+
+	// 同名标签复用
+	again, _, err := s.QuickAdd(user, "Review PR #work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Tags[0].ID != task.Tags[0].ID {
+		t.Errorf("tag was not reused: %d vs %d", again.Tags[0].ID, task.Tags[0].ID)
+	}
+
+	if _, _, err := s.QuickAdd(user, "#work !high"); err != ErrEmptyTaskTitle {
+		t.Errorf("quick add without a title: err = %v, want ErrEmptyTaskTitle", err)
+	}
+}
+
+// failingTasks 创建任务总是失败，用于验证 QuickAdd 的事务回滚
+type failingTasks struct{ repository.TaskRepository }
+
+func (failingTasks) Create(task *models.Task) error { return errors.New("disk full") }
+
+type failingUnitOfWork struct{ store *memory.Store }
+
+func (u failingUnitOfWork) Do(fn func(repos repository.Repositories) error) error {
+	return u.store.Do(func(repos repository.Repositories) error {
+		repos.Tasks = failingTasks{repos.Tasks}
+		return fn(repos)
+	})
+}
+
+func TestTaskServiceQuickAddRollsBackTags(t *testing.T) {
+	store := memory.New()
+	s := NewTaskService(store.Tasks(), failingUnitOfWork{store})
+	user := models.User{ID: 1}
+
+	if _, _, err := s.QuickAdd(user, "Send report #work"); err != ErrCreateTaskFail {
+		t.Fatalf("err = %v, want ErrCreateTaskFail", err)
+	}
+
+	// 标签随事务回滚，之后创建的标签重新分配同一个ID
+	tags, err := store.Tasks().FindOrCreateTags(user.ID, []string{"work"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tags[0].ID != 1 {
+		t.Errorf("tag created inside the failed transaction was kept (new tag ID %d)", tags[0].ID)
+	}
+}
+
+func TestTaskServiceUpdate(t *testing.T) {
+	s, _, rescheduled := newMemoryTaskService()
+	user := models.User{ID: 1}
+	task, _ := s.Create(user, "Write report")
+
+	updated, err := s.Update(user, taskID(task), map[string]interface{}{"status": "done"}, []uint64{task.Revision})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Status != "done" || updated.CompletedAt == nil || updated.Revision != 2 {
+		t.Errorf("completed task = %+v", updated)
+	}
+	if stamp := updated.FieldStamps["status"]; stamp.Revision != 2 {
+		t.Errorf("status stamp = %+v, want revision 2", stamp)
+	}
+
+	// 基于旧版本的条件更新被拒绝
+	if _, err := s.Update(user, taskID(task), map[string]interface{}{"status": "pending"}, []uint64{1}); err != ErrPreconditionFailed {
+		t.Errorf("stale If-Match: err = %v, want ErrPreconditionFailed", err)
+	}
+
+	due := time.Now().Add(24 * time.Hour)
+	reopened, err := s.Update(user, taskID(task), map[string]interface{}{"status": "pending", "due_date": &due}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.CompletedAt != nil || reopened.DueDate == nil || reopened.Revision != 3 {
+		t.Errorf("reopened task = %+v", reopened)
+	}
+	if len(*rescheduled) != 1 || (*rescheduled)[0] != task.ID {
+		t.Errorf("rescheduled reminders for %v, want only task %d", *rescheduled, task.ID)
+	}
+
+	// 调用方修改返回的任务不影响存储
+	reopened.FieldStamps["status"] = models.FieldStamp{Revision: 99}
+	if got, _ := s.Get(user, taskID(task)); got.FieldStamps["status"].Revision != 3 {
+		t.Errorf("stored field stamps were modified through a returned task")
+	}
+}
+
+func TestTaskServiceDelete(t *testing.T) {
+	s, _, _ := newMemoryTaskService()
+	user := models.User{ID: 1}
+	task, _ := s.Create(user, "Write report")
+
+	if err := s.Delete(user, taskID(task), []uint64{task.Revision + 1}); err != ErrPreconditionFailed {
+		t.Errorf("delete with a wrong If-Match: err = %v, want ErrPreconditionFailed", err)
+	}
+	if err := s.Delete(models.User{ID: 2}, taskID(task), nil); err != ErrTaskNotFound {
+		t.Errorf("delete another user's task: err = %v, want ErrTaskNotFound", err)
+	}
+	if err := s.Delete(user, taskID(task), []uint64{task.Revision}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(user, taskID(task)); err != ErrTaskNotFound {
+		t.Errorf("get after delete: err = %v, want ErrTaskNotFound", err)
+	}
+}
@@ -0,0 +1,324 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"myproject/internal/events"
+	models "myproject/internal/model"
+	"myproject/internal/repository"
+)
+
+var (
+	ErrTemplateNotFound        = errors.New("template not found")
+	ErrCreateTemplateFail      = errors.New("create template failed")
+	ErrQueryTemplateFail       = errors.New("query template failed")
+	ErrDeleteTemplateFail      = errors.New("delete template failed")
+	ErrEmptyTemplate           = errors.New("empty template")
+	ErrMissingTemplateVariable = errors.New("missing template variable")
+	ErrInvalidAnchorDate       = errors.New("invalid anchor date")
+	ErrInstantiateTemplateFail = errors.New("instantiate template failed")
+)
+
+// MissingVariablesError 实例化模板时缺少的变量
+type MissingVariablesError struct {
+	Names []string
+}
+
+func (e *MissingVariablesError) Error() string {
+	return ErrMissingTemplateVariable.Error() + ": " + strings.Join(e.Names, ", ")
+}
+
+func (e *MissingVariablesError) Unwrap() error { return ErrMissingTemplateVariable }
+
+// 模板树最多展开的层数，防止任务数据异常时无限递归
+const maxTemplateDepth = 10
+
+var templateVariable = regexp.MustCompile(`\{\{\s*([\p{L}\p{N}_]+)\s*\}\}`)
+
+// CreateTemplateFromTask 以已有任务（及其子任务）为蓝本创建模板。
+// 截止时间保存为相对锚定日期的偏移：根任务有截止时间时以它为锚，否则以树中最早的截止时间为锚。
+func CreateTemplateFromTask(user models.User, taskID, name, description string) (*models.Template, error) {
+	root, err := repository.GetTaskByID(taskID, user.ID)
+	if err != nil {
+		return nil, ErrTaskNotFound
+	}
+
+	tree, err := loadTaskTree(*root, user.ID, 0)
+	if err != nil {
+		return nil, ErrQueryTaskFail
+	}
+
+	anchor := root.DueDate
+	if anchor == nil {
+		anchor = earliestDueDate(tree)
+	}
+
+	if name == "" {
+		name = root.Description
+	}
+	return CreateTemplate(user, name, description, []models.TemplateItem{templateItemFromTask(tree, anchor)})
+}
+
+// CreateTemplate 直接以任务树创建模板，每一项按与创建任务相同的规则校验并规范化，
+// 不合法时返回 *TaskValidationError
+func CreateTemplate(user models.User, name, description string, items []models.TemplateItem) (*models.Template, error) {
+	if len(items) == 0 {
+		return nil, ErrEmptyTemplate
+	}
+	if err := validateTemplateItems(items, 0); err != nil {
+		return nil, err
+	}
+
+	template := models.Template{
+		Name:        name,
+		Description: description,
+		Items:       items,
+		Variables:   templateVariables(items),
+		UserID:      user.ID,
+	}
+	if err := repository.CreateTemplate(&template); err != nil {
+		return nil, ErrCreateTemplateFail
+	}
+	return &template, nil
+}
+
+// GetTemplates 获取模板列表
+func GetTemplates(user models.User) ([]models.Template, error) {
+	templates, err := repository.GetTemplatesByUser(user.ID)
+	if err != nil {
+		return nil, ErrQueryTemplateFail
+	}
+	return templates, nil
+}
+
+// GetTemplate 获取单个模板
+func GetTemplate(user models.User, id string) (*models.Template, error) {
+	template, err := repository.GetTemplateByID(id, user.ID)
+	if err != nil {
+		return nil, ErrTemplateNotFound
+	}
+	return template, nil
+}
+
+// DeleteTemplate 删除模板
+func DeleteTemplate(user models.User, id string) error {
+	template, err := repository.GetTemplateByID(id, user.ID)
+	if err != nil {
+		return ErrTemplateNotFound
+	}
+	if err := repository.DeleteTemplate(template); err != nil {
+		return ErrDeleteTemplateFail
+	}
+	return nil
+}
+
+// InstantiateTemplate 按模板在一个事务中创建任务树。
+// anchor 为 RFC3339 时间或用户时区下的 YYYY-MM-DD，空表示现在；variables 用于替换 {{变量}}。
+func InstantiateTemplate(user models.User, id, anchor string, variables map[string]string) ([]models.Task, error) {
+	template, err := repository.GetTemplateByID(id, user.ID)
+	if err != nil {
+		return nil, ErrTemplateNotFound
+	}
+
+	var missing []string
+	for _, name := range template.Variables {
+		if _, ok := variables[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, &MissingVariablesError{Names: missing}
+	}
+
+	anchorTime, err := parseAnchorDate(anchor, user.Location())
+	if err != nil {
+		return nil, err
+	}
+
+	// 替换变量后的描述可能为空、标签可能过长，创建前按任务的规则再校验一次
+	var tasks []models.Task
+	var parents []int
+	var walk func(items []models.TemplateItem, parent int) error
+	walk = func(items []models.TemplateItem, parent int) error {
+		for _, item := range items {
+			doc := TaskDocument{
+				Description: substituteVariables(item.Description, variables),
+				Priority:    item.Priority,
+				Recurrence:  item.Recurrence,
+			}
+			for _, tag := range item.Tags {
+				doc.Tags = append(doc.Tags, substituteVariables(tag, variables))
+			}
+			if err := validateTemplateDocument(&doc); err != nil {
+				return err
+			}
+
+			task := models.Task{
+				Description: doc.Description,
+				Priority:    doc.Priority,
+				Recurrence:  doc.Recurrence,
+				UserID:      user.ID,
+			}
+			if item.DueOffsetMinutes != nil {
+				due := anchorTime.Add(time.Duration(*item.DueOffsetMinutes) * time.Minute)
+				task.DueDate = &due
+			}
+			for _, tag := range doc.Tags {
+				task.Tags = append(task.Tags, models.Tag{Name: tag})
+			}
+
+			tasks = append(tasks, task)
+			parents = append(parents, parent)
+			if err := walk(item.Children, len(tasks)-1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(template.Items, -1); err != nil {
+		return nil, err
+	}
+
+	if err := repository.CreateTaskTree(tasks, parents); err != nil {
+		return nil, ErrInstantiateTemplateFail
+	}
+	for _, task := range tasks {
+		publishTaskEvent(events.TaskCreated, task)
+	}
+	return tasks, nil
+}
+
+// validateTemplateItems 按创建任务的规则校验并规范化模板项，depth 为 items 所在的层数（根为 0）
+func validateTemplateItems(items []models.TemplateItem, depth int) error {
+	if depth > maxTemplateDepth {
+		return &TaskValidationError{"children", fmt.Sprintf("nested deeper than %d levels", maxTemplateDepth)}
+	}
+	for i := range items {
+		item := &items[i]
+		doc := TaskDocument{Description: item.Description, Priority: item.Priority, Recurrence: item.Recurrence, Tags: item.Tags}
+		if err := validateTemplateDocument(&doc); err != nil {
+			return err
+		}
+		item.Description, item.Priority, item.Recurrence, item.Tags = doc.Description, doc.Priority, doc.Recurrence, doc.Tags
+		if err := validateTemplateItems(item.Children, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateTemplateDocument 校验模板生成的任务，模板项没有状态，描述不能为空
+func validateTemplateDocument(doc *TaskDocument) error {
+	if err := doc.Validate(); err != nil {
+		return err
+	}
+	if doc.Description == "" {
+		return &TaskValidationError{"description", "is required"}
+	}
+	return nil
+}
+
+// taskNode 任务及其子任务
+type taskNode struct {
+	task     models.Task
+	children []taskNode
+}
+
+func loadTaskTree(task models.Task, userID uint, depth int) (taskNode, error) {
+	node := taskNode{task: task}
+	if depth >= maxTemplateDepth {
+		return node, nil
+	}
+
+	children, err := repository.GetSubtasks([]uint{task.ID}, userID)
+	if err != nil {
+		return node, err
+	}
+	for _, child := range children {
+		childNode, err := loadTaskTree(child, userID, depth+1)
+		if err != nil {
+			return node, err
+		}
+		node.children = append(node.children, childNode)
+	}
+	return node, nil
+}
+
+func earliestDueDate(node taskNode) *time.Time {
+	earliest := node.task.DueDate
+	for _, child := range node.children {
+		if d := earliestDueDate(child); d != nil && (earliest == nil || d.Before(*earliest)) {
+			earliest = d
+		}
+	}
+	return earliest
+}
+
+func templateItemFromTask(node taskNode, anchor *time.Time) models.TemplateItem {
+	item := models.TemplateItem{
+		Description: node.task.Description,
+		Priority:    node.task.Priority,
+		Recurrence:  node.task.Recurrence,
+	}
+	if anchor != nil && node.task.DueDate != nil {
+		offset := int64(node.task.DueDate.Sub(*anchor) / time.Minute)
+		item.DueOffsetMinutes = &offset
+	}
+	for _, tag := range node.task.Tags {
+		item.Tags = append(item.Tags, tag.Name)
+	}
+	for _, child := range node.children {
+		item.Children = append(item.Children, templateItemFromTask(child, anchor))
+	}
+	return item
+}
+
+// templateVariables 收集模板中出现的全部变量名
+func templateVariables(items []models.TemplateItem) []string {
+	seen := make(map[string]bool)
+	var collect func(items []models.TemplateItem)
+	collect = func(items []models.TemplateItem) {
+		for _, item := range items {
+			texts := append([]string{item.Description}, item.Tags...)
+			for _, text := range texts {
+				for _, m := range templateVariable.FindAllStringSubmatch(text, -1) {
+					seen[m[1]] = true
+				}
+			}
+			collect(item.Children)
+		}
+	}
+	collect(items)
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func substituteVariables(text string, variables map[string]string) string {
+	return templateVariable.ReplaceAllStringFunc(text, func(match string) string {
+		name := templateVariable.FindStringSubmatch(match)[1]
+		return variables[name]
+	})
+}
+
+func parseAnchorDate(anchor string, loc *time.Location) (time.Time, error) {
+	if anchor == "" {
+		return time.Now().In(loc), nil
+	}
+	if t, err := time.Parse(time.RFC3339, anchor); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", anchor, loc); err == nil {
+		return t, nil
+	}
+	return time.Time{}, ErrInvalidAnchorDate
+}
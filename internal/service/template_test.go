@@ -0,0 +1,87 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	models "myproject/internal/model"
+	"myproject/internal/testutil"
+)
+
+func TestCreateTemplateValidatesItems(t *testing.T) {
+	testutil.OpenDB(t)
+	user := testutil.CreateUser(t, "alice")
+
+	nested := models.TemplateItem{Description: "leaf"}
+	for i := 0; i <= maxTemplateDepth; i++ {
+		nested = models.TemplateItem{Description: "level", Children: []models.TemplateItem{nested}}
+	}
+
+	tests := []struct {
+		name  string
+		item  models.TemplateItem
+		field string
+	}{
+		{"empty description", models.TemplateItem{Description: "  "}, "description"},
+		{"bad priority", models.TemplateItem{Description: "x", Priority: "urgent"}, "priority"},
+		{"long tag", models.TemplateItem{Description: "x", Tags: []string{strings.Repeat("t", maxTagNameLen+1)}}, "tags"},
+		{"bad child", models.TemplateItem{Description: "x", Children: []models.TemplateItem{{Description: "y", Priority: "urgent"}}}, "priority"},
+		{"too deep", nested, "children"},
+	}
+	for _, tt := range tests {
+		_, err := CreateTemplate(user, "t", "", []models.TemplateItem{tt.item})
+		var validationErr *TaskValidationError
+		if !errors.As(err, &validationErr) || validationErr.Field != tt.field {
+			t.Errorf("%s: err = %v, want a %s validation error", tt.name, err, tt.field)
+		}
+	}
+
+	template, err := CreateTemplate(user, "t", "", []models.TemplateItem{{Description: " Launch {{project}} ", Tags: []string{" work ", "work", ""}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := template.Items[0]
+	if item.Description != "Launch {{project}}" || len(item.Tags) != 1 || item.Tags[0] != "work" {
+		t.Errorf("item was not normalized: %+v", item)
+	}
+}
+
+func TestInstantiateTemplateValidatesSubstitutedValues(t *testing.T) {
+	testutil.OpenDB(t)
+	user := testutil.CreateUser(t, "alice")
+
+	template, err := CreateTemplate(user, "t", "", []models.TemplateItem{{
+		Description: "{{title}}",
+		Tags:        []string{"{{tag}}"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := strconv.FormatUint(uint64(template.ID), 10)
+
+	tests := []struct {
+		variables map[string]string
+		field     string
+	}{
+		{map[string]string{"title": " ", "tag": "work"}, "description"},
+		{map[string]string{"title": "Launch", "tag": strings.Repeat("t", maxTagNameLen+1)}, "tags"},
+	}
+	for _, tt := range tests {
+		_, err := InstantiateTemplate(user, id, "", tt.variables)
+		var validationErr *TaskValidationError
+		if !errors.As(err, &validationErr) || validationErr.Field != tt.field {
+			t.Errorf("%v: err = %v, want a %s validation error", tt.variables, err, tt.field)
+		}
+	}
+
+	// 替换后为空的标签被忽略
+	tasks, err := InstantiateTemplate(user, id, "", map[string]string{"title": "Launch", "tag": ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || tasks[0].Description != "Launch" || len(tasks[0].Tags) != 0 {
+		t.Errorf("tasks = %+v", tasks)
+	}
+}
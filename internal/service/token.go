@@ -0,0 +1,170 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	models "myproject/internal/model"
+	"myproject/internal/repository"
+	"myproject/utils"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reused")
+	ErrRevokeTokenFail     = errors.New("revoke token failed")
+)
+
+// 刷新令牌默认有效期，可由 REFRESH_TOKEN_TTL 配置（如 720h）
+const defaultRefreshTokenTTL = 30 * 24 * time.Hour
+
+// TokenPair 登录或刷新后签发的令牌
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"` // 访问令牌的有效秒数
+}
+
+// RefreshTokenTTL 刷新令牌的有效期
+func RefreshTokenTTL() time.Duration {
+	if ttl, err := time.ParseDuration(os.Getenv("REFRESH_TOKEN_TTL")); err == nil && ttl > 0 {
+		return ttl
+	}
+	return defaultRefreshTokenTTL
+}
+
+// RefreshTokens 用刷新令牌换取新的令牌对，旧的刷新令牌随即失效。
+// 已换发过的刷新令牌再次出现说明可能已泄露，所属会话随即被吊销
+func RefreshTokens(refreshToken string, client ClientInfo) (*TokenPair, *models.User, error) {
+	token, err := repository.GetRefreshTokenByHash(hashToken(refreshToken))
+	if err != nil {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	now := time.Now()
+	if token.RevokedAt != nil || now.After(token.ExpiresAt) {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	rotated := false
+	if token.RotatedAt == nil {
+		if rotated, err = repository.RotateRefreshToken(token, now); err != nil {
+			return nil, nil, ErrGenerateTokenFailed
+		}
+	}
+	if !rotated {
+		log.Printf("用户 %d 的刷新令牌被重复使用，吊销会话 %d", token.UserID, token.SessionID)
+		if err := repository.RevokeSession(token.SessionID, now); err != nil {
+			return nil, nil, ErrRevokeTokenFail
+		}
+		return nil, nil, ErrRefreshTokenReused
+	}
+
+	user, err := repository.GetUserByID(token.UserID)
+	if err != nil || user.DisabledAt != nil {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	pair, err := issueTokens(*user, token.SessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	updates := map[string]interface{}{
+		"last_seen_at": now,
+		"expires_at":   now.Add(RefreshTokenTTL()),
+	}
+	if client.UserAgent != "" {
+		updates["user_agent"] = truncate(client.UserAgent, 255)
+	}
+	if client.IP != "" {
+		updates["ip"] = client.IP
+	}
+	if err := repository.ExtendSession(token.SessionID, updates); err != nil {
+		log.Printf("更新会话 %d 失败: %v", token.SessionID, err)
+	}
+	return pair, user, nil
+}
+
+// Logout 注销当前访问令牌并吊销其所属会话
+func Logout(user models.User, claims *utils.Claims) error {
+	if claims.ID != "" && claims.ExpiresAt != nil {
+		if err := repository.RevokeAccessToken(claims.ID, claims.ExpiresAt.Time); err != nil {
+			return ErrRevokeTokenFail
+		}
+	}
+	if err := repository.RevokeSession(claims.SessionID, time.Now()); err != nil {
+		return ErrRevokeTokenFail
+	}
+	return nil
+}
+
+// LogoutAll 退出全部设备：吊销全部会话，此前签发的访问令牌一律失效
+func LogoutAll(user models.User) error {
+	now := time.Now()
+	if err := repository.RevokeUserSessions(user.ID, now); err != nil {
+		return ErrRevokeTokenFail
+	}
+	if err := repository.UpdateUser(&user, map[string]interface{}{"tokens_revoked_at": now}); err != nil {
+		return ErrRevokeTokenFail
+	}
+	return nil
+}
+
+// CleanupExpiredTokens 删除过期的会话、刷新令牌、黑名单记录和外部登录的 state
+func CleanupExpiredTokens(now time.Time) error {
+	if err := repository.DeleteExpiredTokens(now); err != nil {
+		return err
+	}
+	if err := repository.DeleteExpiredOAuthStates(now); err != nil {
+		return err
+	}
+	return repository.DeleteExpiredSessions(now)
+}
+
+// issueTokens 为会话签发访问令牌和刷新令牌
+func issueTokens(user models.User, sessionID uint) (*TokenPair, error) {
+	accessToken, err := utils.GenerateToken(user.ID, sessionID)
+	if err != nil {
+		return nil, ErrGenerateTokenFailed
+	}
+
+	refreshToken, err := randomToken()
+	if err != nil {
+		return nil, ErrGenerateTokenFailed
+	}
+
+	record := models.RefreshToken{
+		UserID:    user.ID,
+		SessionID: sessionID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: time.Now().Add(RefreshTokenTTL()),
+	}
+	if err := repository.CreateRefreshToken(&record); err != nil {
+		return nil, ErrGenerateTokenFailed
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(utils.AccessTokenTTL() / time.Second),
+	}, nil
+}
+
+// randomToken 生成 256 位随机令牌
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,691 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"myproject/internal/events"
+	models "myproject/internal/model"
+	"myproject/internal/plaintext"
+	"myproject/internal/repository"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported format")
+	ErrExportTaskFail    = errors.New("export task failed")
+	ErrMalformedImport   = errors.New("malformed import file")
+)
+
+// 支持的导入导出格式
+const (
+	FormatCSV      = "csv"
+	FormatJSON     = "json"
+	FormatNDJSON   = "ndjson"
+	FormatTodoTxt  = "todotxt"
+	FormatMarkdown = "markdown"
+)
+
+const (
+	exportBatchSize    = 500
+	maxImportRowErrors = 1000
+	maxExternalIDLen   = 191
+	maxTagNameLen      = 64
+	maxNDJSONLineSize  = 1 << 20
+)
+
+var transferContentTypes = map[string]string{
+	FormatCSV:      "text/csv; charset=utf-8",
+	FormatJSON:     "application/json; charset=utf-8",
+	FormatNDJSON:   "application/x-ndjson; charset=utf-8",
+	FormatTodoTxt:  "text/plain; charset=utf-8",
+	FormatMarkdown: "text/markdown; charset=utf-8",
+}
+
+var csvHeader = []string{"id", "external_id", "description", "status", "priority", "due_date", "recurrence", "tags", "created_at", "updated_at", "completed_at"}
+
+// TaskRecord 导入导出时的一条任务记录
+type TaskRecord struct {
+	ID          uint       `json:"id,omitempty"`
+	ExternalID  string     `json:"external_id"`
+	Description string     `json:"description"`
+	Status      string     `json:"status"`
+	Priority    string     `json:"priority,omitempty"`
+	DueDate     *time.Time `json:"due_date,omitempty"`
+	Recurrence  string     `json:"recurrence,omitempty"`
+	Tags        []string   `json:"tags,omitempty"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// ImportRowError 单行导入错误
+type ImportRowError struct {
+	Row        int    `json:"row"`
+	Line       int    `json:"line,omitempty"`
+	ExternalID string `json:"external_id,omitempty"`
+	Error      string `json:"error"`
+}
+
+// ImportResult 导入结果汇总
+type ImportResult struct {
+	DryRun  bool             `json:"dry_run"`
+	Total   int              `json:"total"`
+	Created int              `json:"created"`
+	Updated int              `json:"updated"`
+	Failed  int              `json:"failed"`
+	Errors  []ImportRowError `json:"errors"`
+}
+
+// invalidRowError 表示某一行无法解析，但不影响后续行的读取
+type invalidRowError struct {
+	err error
+}
+
+func (e *invalidRowError) Error() string { return e.err.Error() }
+
+type taskEncoder interface {
+	Encode(rec TaskRecord) error
+	Close() error
+}
+
+type taskDecoder interface {
+	// Decode 读取下一条记录，结束时返回 io.EOF
+	Decode(rec *TaskRecord) error
+}
+
+// lineDecoder 由能够报告源文件行号的解码器实现
+type lineDecoder interface {
+	Line() int
+}
+
+// ContentTypeForFormat 返回格式对应的 Content-Type
+func ContentTypeForFormat(format string) (string, error) {
+	contentType, ok := transferContentTypes[format]
+	if !ok {
+		return "", ErrUnsupportedFormat
+	}
+	return contentType, nil
+}
+
+// FormatFromContentType 根据 Content-Type 推断导入格式
+func FormatFromContentType(contentType string) string {
+	mediaType := strings.TrimSpace(strings.Split(contentType, ";")[0])
+	for format, ct := range transferContentTypes {
+		if strings.HasPrefix(ct, mediaType+";") {
+			return format
+		}
+	}
+	return ""
+}
+
+// ExportTasks 以流的方式导出用户的全部任务
+func ExportTasks(user models.User, format string, w io.Writer) error {
+	enc, err := newTaskEncoder(format, w)
+	if err != nil {
+		return err
+	}
+
+	err = repository.EachTaskByUser(user.ID, exportBatchSize, func(task *models.Task) error {
+		return enc.Encode(recordFromTask(task))
+	})
+	if err != nil {
+		return ErrExportTaskFail
+	}
+
+	if err := enc.Close(); err != nil {
+		return ErrExportTaskFail
+	}
+	return nil
+}
+
+// ImportTasks 以流的方式导入任务。带外部ID的记录会更新已有任务，重复导入不会产生重复数据；
+// dryRun 为 true 时只做校验，不写入数据库。
+func ImportTasks(user models.User, format string, r io.Reader, dryRun bool) (*ImportResult, error) {
+	dec, err := newTaskDecoder(format, r)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{DryRun: dryRun, Errors: []ImportRowError{}}
+	lines, _ := dec.(lineDecoder)
+	seen := make(map[string]bool)
+	for row := 1; ; row++ {
+		var rec TaskRecord
+		err := dec.Decode(&rec)
+		if err == io.EOF {
+			break
+		}
+
+		result.Total++
+		if err == nil {
+			err = validateRecord(&rec)
+		}
+		if err == nil {
+			var created bool
+			created, err = importRecord(user, &rec, dryRun, seen)
+			if created {
+				result.Created++
+			} else if err == nil {
+				result.Updated++
+			}
+		}
+		if err != nil {
+			var rowErr *invalidRowError
+			if !errors.As(err, &rowErr) {
+				result.Total--
+				return result, fmt.Errorf("%w: row %d: %v", ErrMalformedImport, row, err)
+			}
+			result.Failed++
+			if len(result.Errors) < maxImportRowErrors {
+				rowError := ImportRowError{
+					Row:        row,
+					ExternalID: rec.ExternalID,
+					Error:      rowErr.Error(),
+				}
+				if lines != nil {
+					rowError.Line = lines.Line()
+				}
+				result.Errors = append(result.Errors, rowError)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func recordFromTask(task *models.Task) TaskRecord {
+	rec := TaskRecord{
+		ID:          task.ID,
+		Description: task.Description,
+		Status:      task.Status,
+		Priority:    task.Priority,
+		DueDate:     task.DueDate,
+		Recurrence:  task.Recurrence,
+		CreatedAt:   &task.CreatedAt,
+		UpdatedAt:   &task.UpdatedAt,
+		CompletedAt: task.CompletedAt,
+	}
+	for _, tag := range task.Tags {
+		rec.Tags = append(rec.Tags, tag.Name)
+	}
+	if task.ExternalID != nil {
+		rec.ExternalID = *task.ExternalID
+	}
+	return rec
+}
+
+func validateRecord(rec *TaskRecord) error {
+	rec.ExternalID = strings.TrimSpace(rec.ExternalID)
+	rec.Description = strings.TrimSpace(rec.Description)
+	rec.Status = strings.TrimSpace(rec.Status)
+	rec.Priority = strings.TrimSpace(rec.Priority)
+	rec.Recurrence = strings.TrimSpace(rec.Recurrence)
+
+	tags := rec.Tags[:0]
+	seen := make(map[string]bool, len(rec.Tags))
+	for _, tag := range rec.Tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		if len(tag) > maxTagNameLen {
+			return &invalidRowError{fmt.Errorf("tag %q exceeds %d characters", tag, maxTagNameLen)}
+		}
+		tags = append(tags, tag)
+	}
+	rec.Tags = tags
+
+	if rec.Description == "" {
+		return &invalidRowError{errors.New("description is required")}
+	}
+	if len(rec.ExternalID) > maxExternalIDLen {
+		return &invalidRowError{fmt.Errorf("external_id exceeds %d characters", maxExternalIDLen)}
+	}
+	switch rec.Status {
+	case "":
+		rec.Status = "pending"
+	case "pending", "done":
+	default:
+		return &invalidRowError{fmt.Errorf("invalid status %q", rec.Status)}
+	}
+	switch rec.Priority {
+	case "", "high", "medium", "low":
+	default:
+		return &invalidRowError{fmt.Errorf("invalid priority %q", rec.Priority)}
+	}
+	return nil
+}
+
+// importRecord 写入单条记录，返回是否为新建。每条记录在一个事务中写入。
+// 试运行时不写数据库，seen 记录本次文件中已按新建计算的外部ID，同一文件中重复出现的外部ID按更新计算
+func importRecord(user models.User, rec *TaskRecord, dryRun bool, seen map[string]bool) (bool, error) {
+	if dryRun {
+		if rec.ExternalID == "" {
+			return true, nil
+		}
+		if seen[rec.ExternalID] {
+			return false, nil
+		}
+		if _, err := repository.GetTaskByExternalID(rec.ExternalID, user.ID); err == nil {
+			return false, nil
+		}
+		seen[rec.ExternalID] = true
+		return true, nil
+	}
+
+	task := models.Task{
+		Description: rec.Description,
+		Status:      rec.Status,
+		Priority:    rec.Priority,
+		DueDate:     rec.DueDate,
+		Recurrence:  rec.Recurrence,
+		UserID:      user.ID,
+	}
+	if rec.ExternalID != "" {
+		externalID := rec.ExternalID
+		task.ExternalID = &externalID
+	}
+	if rec.CreatedAt != nil {
+		task.CreatedAt = *rec.CreatedAt
+	}
+	if task.Status == "done" {
+		task.CompletedAt = rec.CompletedAt
+		if task.CompletedAt == nil {
+			now := time.Now()
+			task.CompletedAt = &now
+		}
+	}
+
+	completed := false
+	created, err := repository.ImportTask(&task, rec.Tags, func(existing *models.Task) map[string]interface{} {
+		updates := map[string]interface{}{
+			"description": rec.Description,
+			"status":      rec.Status,
+			"priority":    rec.Priority,
+			"due_date":    rec.DueDate,
+			"recurrence":  rec.Recurrence,
+		}
+		completed = trackCompletion(existing, updates)
+		if completed && rec.CompletedAt != nil {
+			updates["completed_at"] = *rec.CompletedAt
+		}
+		return updates
+	})
+	if err != nil {
+		if created {
+			return false, &invalidRowError{ErrCreateTaskFail}
+		}
+		return false, &invalidRowError{ErrUpdateTaskFail}
+	}
+
+	if created {
+		publishTaskEvent(events.TaskCreated, task)
+	} else {
+		publishTaskUpdate(task, completed)
+	}
+	return created, nil
+}
+
+func newTaskEncoder(format string, w io.Writer) (taskEncoder, error) {
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvHeader); err != nil {
+			return nil, err
+		}
+		return &csvEncoder{w: cw}, nil
+	case FormatJSON:
+		return &jsonEncoder{w: w}, nil
+	case FormatNDJSON:
+		return &ndjsonEncoder{enc: json.NewEncoder(w)}, nil
+	case FormatTodoTxt:
+		return &lineEncoder{w: w, format: plaintext.FormatTodoTxt}, nil
+	case FormatMarkdown:
+		return &lineEncoder{w: w, format: plaintext.FormatMarkdown}, nil
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+func newTaskDecoder(format string, r io.Reader) (taskDecoder, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVDecoder(r), nil
+	case FormatJSON:
+		return &jsonDecoder{dec: json.NewDecoder(r)}, nil
+	case FormatNDJSON:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxNDJSONLineSize)
+		return &ndjsonDecoder{scanner: scanner}, nil
+	case FormatTodoTxt:
+		return &lineDecoderAdapter{r: plaintext.NewTodoTxtReader(r)}, nil
+	case FormatMarkdown:
+		return &lineDecoderAdapter{r: plaintext.NewMarkdownReader(r)}, nil
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+// ---- CSV ----
+
+type csvEncoder struct {
+	w *csv.Writer
+}
+
+func (e *csvEncoder) Encode(rec TaskRecord) error {
+	return e.w.Write([]string{
+		strconv.FormatUint(uint64(rec.ID), 10),
+		rec.ExternalID,
+		rec.Description,
+		rec.Status,
+		rec.Priority,
+		formatTime(rec.DueDate),
+		rec.Recurrence,
+		strings.Join(rec.Tags, ";"),
+		formatTime(rec.CreatedAt),
+		formatTime(rec.UpdatedAt),
+		formatTime(rec.CompletedAt),
+	})
+}
+
+func (e *csvEncoder) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type csvDecoder struct {
+	r       *csv.Reader
+	columns map[string]int
+	line    int
+}
+
+func newCSVDecoder(r io.Reader) *csvDecoder {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+	return &csvDecoder{r: cr}
+}
+
+func (d *csvDecoder) Decode(rec *TaskRecord) error {
+	if d.columns == nil {
+		if err := d.readHeader(); err != nil {
+			return err
+		}
+	}
+
+	fields, err := d.r.Read()
+	if err != nil {
+		return err
+	}
+	d.line, _ = d.r.FieldPos(0)
+
+	get := func(name string) string {
+		if i, ok := d.columns[name]; ok && i < len(fields) {
+			return fields[i]
+		}
+		return ""
+	}
+	rec.ExternalID = get("external_id")
+	rec.Description = get("description")
+	rec.Status = get("status")
+	rec.Priority = get("priority")
+	rec.Recurrence = get("recurrence")
+	if tags := get("tags"); tags != "" {
+		rec.Tags = strings.Split(tags, ";")
+	}
+
+	if rec.DueDate, err = parseTimeField("due_date", get("due_date")); err != nil {
+		return err
+	}
+	if rec.CreatedAt, err = parseTimeField("created_at", get("created_at")); err != nil {
+		return err
+	}
+	if rec.CompletedAt, err = parseTimeField("completed_at", get("completed_at")); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *csvDecoder) Line() int {
+	return d.line
+}
+
+func (d *csvDecoder) readHeader() error {
+	header, err := d.r.Read()
+	if err != nil {
+		return err
+	}
+	d.columns = make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		d.columns[name] = i
+	}
+	if _, ok := d.columns["description"]; !ok {
+		return errors.New("csv header must contain a description column")
+	}
+	return nil
+}
+
+// ---- JSON ----
+
+type jsonEncoder struct {
+	w     io.Writer
+	count int
+}
+
+func (e *jsonEncoder) Encode(rec TaskRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	sep := []byte(",\n")
+	if e.count == 0 {
+		sep = []byte("[\n")
+	}
+	e.count++
+	if _, err := e.w.Write(sep); err != nil {
+		return err
+	}
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonEncoder) Close() error {
+	tail := "\n]\n"
+	if e.count == 0 {
+		tail = "[]\n"
+	}
+	_, err := io.WriteString(e.w, tail)
+	return err
+}
+
+type jsonDecoder struct {
+	dec     *json.Decoder
+	started bool
+}
+
+func (d *jsonDecoder) Decode(rec *TaskRecord) error {
+	if !d.started {
+		tok, err := d.dec.Token()
+		if err != nil {
+			if err == io.EOF {
+				return errors.New("empty json document")
+			}
+			return err
+		}
+		if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+			return errors.New("json document must be an array")
+		}
+		d.started = true
+	}
+
+	if !d.dec.More() {
+		if _, err := d.dec.Token(); err != nil {
+			return err
+		}
+		return io.EOF
+	}
+
+	if err := d.dec.Decode(rec); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return &invalidRowError{err}
+		}
+		return err
+	}
+	return nil
+}
+
+// ---- NDJSON ----
+
+type ndjsonEncoder struct {
+	enc *json.Encoder
+}
+
+func (e *ndjsonEncoder) Encode(rec TaskRecord) error {
+	return e.enc.Encode(rec)
+}
+
+func (e *ndjsonEncoder) Close() error {
+	return nil
+}
+
+type ndjsonDecoder struct {
+	scanner *bufio.Scanner
+}
+
+func (d *ndjsonDecoder) Decode(rec *TaskRecord) error {
+	for d.scanner.Scan() {
+		line := bytes.TrimSpace(d.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(line, rec); err != nil {
+			return &invalidRowError{err}
+		}
+		return nil
+	}
+	if err := d.scanner.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+// ---- todo.txt / Markdown ----
+
+// 纯文本清单的优先级是 A-Z，和任务的 high/medium/low 相互映射，D-Z 按 low 导入
+var (
+	priorityToLetter = map[string]string{"high": "A", "medium": "B", "low": "C"}
+	letterToPriority = map[string]string{"A": "high", "B": "medium", "C": "low"}
+)
+
+type lineEncoder struct {
+	w      io.Writer
+	format func(item plaintext.Item) string
+}
+
+func (e *lineEncoder) Encode(rec TaskRecord) error {
+	item := plaintext.Item{
+		Done:      rec.Status == "done",
+		Priority:  priorityToLetter[rec.Priority],
+		CreatedAt: rec.CreatedAt,
+		DueDate:   rec.DueDate,
+		Projects:  tagsMissingFromText(rec.Description, rec.Tags),
+		Text:      rec.Description,
+	}
+	if item.Done {
+		item.CompletedAt = rec.CompletedAt
+		if item.CompletedAt == nil {
+			item.CompletedAt = rec.UpdatedAt
+		}
+	}
+	_, err := io.WriteString(e.w, e.format(item)+"\n")
+	return err
+}
+
+// tagsMissingFromText 返回没有以 +tag 或 @tag 的形式出现在文本中的标签，导出时追加到行尾
+func tagsMissingFromText(text string, tags []string) []string {
+	words := make(map[string]bool)
+	for _, word := range strings.Fields(text) {
+		words[word] = true
+	}
+	var missing []string
+	for _, tag := range tags {
+		if !words["+"+tag] && !words["@"+tag] {
+			missing = append(missing, tag)
+		}
+	}
+	return missing
+}
+
+func (e *lineEncoder) Close() error {
+	return nil
+}
+
+type lineDecoderAdapter struct {
+	r *plaintext.Reader
+}
+
+func (d *lineDecoderAdapter) Decode(rec *TaskRecord) error {
+	item, err := d.r.Read()
+	if err != nil {
+		var parseErr *plaintext.ParseError
+		if errors.As(err, &parseErr) {
+			return &invalidRowError{err}
+		}
+		return err
+	}
+
+	rec.Description = item.Text
+	rec.Status = "pending"
+	if item.Done {
+		rec.Status = "done"
+	}
+	if item.Priority != "" {
+		rec.Priority = "low"
+		if p, ok := letterToPriority[item.Priority]; ok {
+			rec.Priority = p
+		}
+	}
+	rec.DueDate = item.DueDate
+	rec.CreatedAt = item.CreatedAt
+	rec.CompletedAt = item.CompletedAt
+	// +project 和 @context 都作为标签导入，文本中仍保留原样
+	rec.Tags = append(append([]string(nil), item.Projects...), item.Contexts...)
+	return nil
+}
+
+func (d *lineDecoderAdapter) Line() int {
+	return d.r.Line()
+}
+
+// parseTimeField 解析 RFC3339 时间或 YYYY-MM-DD 日期，空值返回 nil
+func parseTimeField(name, value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return &t, nil
+	}
+	return nil, &invalidRowError{fmt.Errorf("invalid %s %q", name, value)}
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
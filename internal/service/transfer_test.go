@@ -0,0 +1,148 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"myproject/config"
+	models "myproject/internal/model"
+	"myproject/internal/repository"
+	"myproject/internal/testutil"
+
+	"gorm.io/gorm"
+)
+
+const duplicateExternalIDs = `{"external_id":"a-1","description":"first","tags":["home"]}
+{"external_id":"a-1","description":"second","tags":["work"]}
+{"description":"no external id"}
+`
+
+func TestImportDryRunCountsRepeatedExternalIDAsUpdate(t *testing.T) {
+	testutil.OpenDB(t)
+	user := testutil.CreateUser(t, "alice")
+
+	result, err := ImportTasks(user, FormatNDJSON, strings.NewReader(duplicateExternalIDs), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Created != 2 || result.Updated != 1 || result.Failed != 0 {
+		t.Fatalf("dry run: created=%d updated=%d failed=%d, want 2/1/0", result.Created, result.Updated, result.Failed)
+	}
+
+	real, err := ImportTasks(user, FormatNDJSON, strings.NewReader(duplicateExternalIDs), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if real.Created != result.Created || real.Updated != result.Updated {
+		t.Fatalf("real run: created=%d updated=%d, dry run predicted %d/%d", real.Created, real.Updated, result.Created, result.Updated)
+	}
+
+	task := taskByExternalID(t, user, "a-1")
+	if task.Description != "second" {
+		t.Errorf("description = %q, want %q", task.Description, "second")
+	}
+	if names := tagNames(task.Tags); len(names) != 1 || names[0] != "work" {
+		t.Errorf("tags = %v, want [work]", names)
+	}
+}
+
+func TestImportUpdateRollsBackOnFailure(t *testing.T) {
+	db := testutil.OpenDB(t)
+	user := testutil.CreateUser(t, "alice")
+
+	if _, err := ImportTasks(user, FormatNDJSON, strings.NewReader(`{"external_id":"a-1","description":"first","tags":["home"]}`), false); err != nil {
+		t.Fatal(err)
+	}
+
+	// 让任务的更新失败，标签的创建和替换必须一起回滚
+	err := db.Callback().Update().Before("gorm:update").Register("test:fail_task_update", func(tx *gorm.DB) {
+		if tx.Statement.Table == "tasks" {
+			tx.AddError(errors.New("update failed"))
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ImportTasks(user, FormatNDJSON, strings.NewReader(`{"external_id":"a-1","description":"second","tags":["fresh"]}`), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Failed != 1 || len(result.Errors) != 1 {
+		t.Fatalf("failed = %d, errors = %v, want one failed row", result.Failed, result.Errors)
+	}
+
+	var count int64
+	db.Model(&models.Tag{}).Where("name = ?", "fresh").Count(&count)
+	if count != 0 {
+		t.Errorf("tag created by the failed row was not rolled back")
+	}
+	task := taskByExternalID(t, user, "a-1")
+	if task.Description != "first" {
+		t.Errorf("description = %q, want %q", task.Description, "first")
+	}
+	if names := tagNames(task.Tags); len(names) != 1 || names[0] != "home" {
+		t.Errorf("tags = %v, want [home]", names)
+	}
+}
+
+func taskByExternalID(t *testing.T, user models.User, externalID string) models.Task {
+	t.Helper()
+
+	var tasks []models.Task
+	if err := config.DB.Preload("Tags").Where("user_id = ? AND external_id = ?", user.ID, externalID).Find(&tasks).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 {
+		t.Fatalf("found %d tasks with external id %q, want 1", len(tasks), externalID)
+	}
+	return tasks[0]
+}
+
+func TestTodoTxtRoundTripKeepsTagsAndCompletionDate(t *testing.T) {
+	testutil.OpenDB(t)
+	user := testutil.CreateUser(t, "alice")
+
+	const input = "x 2024-05-02 2024-05-01 Pay rent +home @bank pri:C\n(A) Call mom @phone\n(D) Someday\n"
+	result, err := ImportTasks(user, FormatTodoTxt, strings.NewReader(input), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Created != 3 || result.Failed != 0 {
+		t.Fatalf("created=%d failed=%d errors=%v", result.Created, result.Failed, result.Errors)
+	}
+
+	var tasks []models.Task
+	config.DB.Preload("Tags").Order("id").Find(&tasks)
+	if names := tagNames(tasks[0].Tags); len(names) != 2 {
+		t.Errorf("tags = %v, want home and bank", names)
+	}
+	if tasks[0].CompletedAt == nil || tasks[0].CompletedAt.Format("2006-01-02") != "2024-05-02" {
+		t.Errorf("completed_at = %v, want 2024-05-02", tasks[0].CompletedAt)
+	}
+	if tasks[0].Priority != "low" || tasks[1].Priority != "high" || tasks[2].Priority != "low" {
+		t.Errorf("priorities = %q %q %q, want low high low", tasks[0].Priority, tasks[1].Priority, tasks[2].Priority)
+	}
+
+	// 任务上另外加的标签导出时追加在行尾
+	work, err := repository.FindOrCreateTags(user.ID, []string{"work"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := config.DB.Model(&tasks[1]).Association("Tags").Append(work); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	if err := ExportTasks(user, FormatTodoTxt, &out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if want := "x 2024-05-02 2024-05-01 Pay rent +home @bank pri:C"; lines[0] != want {
+		t.Errorf("line 1 = %q, want %q", lines[0], want)
+	}
+	if !strings.HasSuffix(lines[1], "Call mom @phone +work") {
+		t.Errorf("line 2 = %q, want the work tag appended", lines[1])
+	}
+}
@@ -2,10 +2,10 @@ package service
 
 import (
 	"errors"
+	"log"
 
 	models "myproject/internal/model"
 	"myproject/internal/repository"
-	"myproject/utils"
 )
 
 var (
@@ -15,8 +15,20 @@ var (
 	ErrGenerateTokenFailed = errors.New("generate token failed")
 )
 
+// UserService 注册、登录和个人资料。用户存储通过构造函数注入，会话、一次性令牌和审计仍使用默认的数据库
+type UserService struct {
+	users repository.UserRepository
+	// sendVerificationEmail 注册后发送邮箱验证链接，测试时可以替换
+	sendVerificationEmail func(user models.User) error
+}
+
+// NewUserService 创建用户服务
+func NewUserService(users repository.UserRepository) *UserService {
+	return &UserService{users: users, sendVerificationEmail: SendVerificationEmail}
+}
+
 // Register 用户注册业务
-func Register(username, password, email string) (*models.User, error) {
+func (s *UserService) Register(username, password, email string) (*models.User, error) {
 	user := models.User{
 		Username: username,
 		Password: password,
@@ -29,32 +41,72 @@ func Register(username, password, email string) (*models.User, error) {
 	}
 
 	// 保存到数据库
-	if err := repository.CreateUser(&user); err != nil {
+	if err := s.users.Create(&user); err != nil {
 		// 这里简化处理为用户已存在，后续可以根据错误类型更精细区分
 		return nil, ErrUserAlreadyExists
 	}
 
+	// 发送邮箱验证链接，失败不影响注册，用户可以重新发送
+	if err := s.sendVerificationEmail(user); err != nil {
+		log.Printf("发送验证邮件给用户 %d 失败: %v", user.ID, err)
+	}
+
 	return &user, nil
 }
 
+// LoginResult 登录结果。开启两步验证的用户只拿到 MFAToken，需要调用 CompleteMFALogin 换取令牌
+type LoginResult struct {
+	User     *models.User
+	Tokens   *TokenPair
+	MFAToken string
+}
+
 // Login 用户登录业务
-func Login(username, password string) (string, *models.User, error) {
+func (s *UserService) Login(username, password string, client ClientInfo) (*LoginResult, error) {
+	// 失败次数过多时先退避，不论用户是否存在
+	if err := checkLoginThrottle(username, client); err != nil {
+		return nil, err
+	}
+
 	// 查找用户
-	user, err := repository.GetUserByUsername(username)
+	user, err := s.users.GetByUsername(username)
 	if err != nil {
-		return "", nil, ErrInvalidCredentials
+		recordLoginFailure(AuditLoginFailed, username, nil, client)
+		return nil, ErrInvalidCredentials
 	}
 
 	// 校验密码
 	if !user.CheckPassword(password) {
-		return "", nil, ErrInvalidCredentials
+		recordLoginFailure(AuditLoginFailed, username, &user.ID, client)
+		return nil, ErrInvalidCredentials
+	}
+
+	// 被管理员禁用的用户不能登录
+	if user.DisabledAt != nil {
+		return nil, ErrAccountDisabled
+	}
+
+	// 配置要求时，未验证邮箱的用户不能登录
+	if EmailVerificationRequired() && !user.EmailVerified {
+		return nil, ErrEmailNotVerified
 	}
 
-	// 生成 token
-	token, err := utils.GenerateToken(user.ID)
+	// 开启两步验证时先返回挑战令牌，失败计数等第二步通过后再清除
+	if user.TOTPEnabled {
+		mfaToken, err := newMFAChallenge(*user)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResult{User: user, MFAToken: mfaToken}, nil
+	}
+
+	resetLoginFailures(username)
+
+	// 创建会话并签发令牌
+	tokens, err := startSession(*user, client)
 	if err != nil {
-		return "", nil, ErrGenerateTokenFailed
+		return nil, err
 	}
 
-	return token, user, nil
-}
\ No newline at end of file
+	return &LoginResult{User: user, Tokens: tokens}, nil
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"testing"
+
+	models "myproject/internal/model"
+	"myproject/internal/repository/memory"
+	"myproject/internal/testutil"
+)
+
+// newMemoryUserService 创建使用内存存储的 UserService，verified 记录收到验证邮件的用户
+func newMemoryUserService() (*UserService, *[]string) {
+	s := NewUserService(memory.New().Users())
+	var verified []string
+	s.sendVerificationEmail = func(user models.User) error {
+		verified = append(verified, user.Email)
+		return nil
+	}
+	return s, &verified
+}
+
+func TestUserServiceRegister(t *testing.T) {
+	s, verified := newMemoryUserService()
+
+	user, err := s.Register("alice", "correct horse", "alice@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Password == "correct horse" || !user.CheckPassword("correct horse") {
+		t.Error("password was not hashed")
+	}
+	if user.Role != "user" || user.EmailVerified {
+		t.Errorf("registered user = %+v", user)
+	}
+	if len(*verified) != 1 || (*verified)[0] != "alice@example.com" {
+		t.Errorf("verification emails sent to %v", *verified)
+	}
+
+	if _, err := s.Register("alice", "x", "other@example.com"); err != ErrUserAlreadyExists {
+		t.Errorf("duplicate username: err = %v, want ErrUserAlreadyExists", err)
+	}
+	if _, err := s.Register("bob", "x", "alice@example.com"); err != ErrUserAlreadyExists {
+		t.Errorf("duplicate email: err = %v, want ErrUserAlreadyExists", err)
+	}
+}
+
+func TestUserServiceUpdateProfile(t *testing.T) {
+	// 用户在内存存储中，邮箱确认令牌仍写入数据库
+	testutil.OpenDB(t)
+	s, _ := newMemoryUserService()
+	alice, _ := s.Register("alice", "secret", "alice@example.com")
+	s.Register("bob", "secret", "bob@example.com")
+
+	str := func(v string) *string { return &v }
+
+	if _, err := s.UpdateProfile(*alice, ProfileUpdate{Username: str("bob")}); err != ErrUsernameTaken {
+		t.Errorf("taken username: err = %v, want ErrUsernameTaken", err)
+	}
+	if _, err := s.UpdateProfile(*alice, ProfileUpdate{TimeZone: str("Mars/Olympus")}); err != ErrInvalidTimeZone {
+		t.Errorf("bad time zone: err = %v, want ErrInvalidTimeZone", err)
+	}
+	if _, err := s.UpdateProfile(*alice, ProfileUpdate{Email: str("new@example.com"), CurrentPassword: "wrong"}); err != ErrInvalidCredentials {
+		t.Errorf("email change with a wrong password: err = %v, want ErrInvalidCredentials", err)
+	}
+	if _, err := s.UpdateProfile(*alice, ProfileUpdate{Email: str("bob@example.com"), CurrentPassword: "secret"}); err != ErrEmailTaken {
+		t.Errorf("taken email: err = %v, want ErrEmailTaken", err)
+	}
+
+	updated, err := s.UpdateProfile(*alice, ProfileUpdate{
+		Username:        str("alice2"),
+		TimeZone:        str("Asia/Shanghai"),
+		Email:           str("new@example.com"),
+		CurrentPassword: "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 新邮箱确认前原邮箱照常使用
+	if updated.Username != "alice2" || updated.TimeZone != "Asia/Shanghai" || updated.Email != "alice@example.com" || updated.PendingEmail != "new@example.com" {
+		t.Errorf("updated profile = %+v", updated)
+	}
+}
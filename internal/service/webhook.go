@@ -0,0 +1,193 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/url"
+	"time"
+
+	"myproject/internal/events"
+	models "myproject/internal/model"
+	"myproject/internal/netguard"
+	"myproject/internal/repository"
+)
+
+var (
+	ErrWebhookNotFound      = errors.New("webhook not found")
+	ErrDeliveryNotFound     = errors.New("webhook delivery not found")
+	ErrInvalidWebhookURL    = errors.New("invalid webhook url")
+	ErrInvalidEventType     = errors.New("invalid event type")
+	ErrCreateWebhookFail    = errors.New("create webhook failed")
+	ErrQueryWebhookFail     = errors.New("query webhook failed")
+	ErrDeleteWebhookFail    = errors.New("delete webhook failed")
+	ErrRedeliverWebhookFail = errors.New("redeliver webhook failed")
+)
+
+// 投递记录列表默认返回的条数
+const deliveryHistoryLimit = 50
+
+// WebhookPayload 投递给回调地址的请求体
+type WebhookPayload struct {
+	Event      string    `json:"event"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Data       struct {
+		Task webhookTask `json:"task"`
+	} `json:"data"`
+}
+
+// webhookTask 事件中的任务不带关联用户，省略零值的 user 字段
+type webhookTask struct {
+	models.Task
+	User *models.User `json:"user,omitempty"`
+}
+
+// CreateWebhook 注册 webhook，events 为空表示订阅全部事件。地址只能是公网的 http(s) 地址。
+// 签名密钥只在创建时返回一次。
+func CreateWebhook(user models.User, rawURL string, eventTypes []string) (*models.Webhook, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" || netguard.CheckHost(u.Hostname()) != nil {
+		return nil, "", ErrInvalidWebhookURL
+	}
+	for _, typ := range eventTypes {
+		if !events.IsValidType(typ) {
+			return nil, "", ErrInvalidEventType
+		}
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", ErrCreateWebhookFail
+	}
+	secret := hex.EncodeToString(b)
+
+	webhook := models.Webhook{
+		UserID: user.ID,
+		URL:    rawURL,
+		Secret: secret,
+		Events: eventTypes,
+		Active: true,
+	}
+	if err := repository.CreateWebhook(&webhook); err != nil {
+		return nil, "", ErrCreateWebhookFail
+	}
+	return &webhook, secret, nil
+}
+
+// GetWebhooks 获取 webhook 列表
+func GetWebhooks(user models.User) ([]models.Webhook, error) {
+	webhooks, err := repository.GetWebhooksByUser(user.ID)
+	if err != nil {
+		return nil, ErrQueryWebhookFail
+	}
+	return webhooks, nil
+}
+
+// GetWebhook 获取单个 webhook
+func GetWebhook(user models.User, id string) (*models.Webhook, error) {
+	webhook, err := repository.GetWebhookByID(id, user.ID)
+	if err != nil {
+		return nil, ErrWebhookNotFound
+	}
+	return webhook, nil
+}
+
+// DeleteWebhook 删除 webhook 及其投递记录
+func DeleteWebhook(user models.User, id string) error {
+	webhook, err := repository.GetWebhookByID(id, user.ID)
+	if err != nil {
+		return ErrWebhookNotFound
+	}
+	if err := repository.DeleteWebhook(webhook); err != nil {
+		return ErrDeleteWebhookFail
+	}
+	return nil
+}
+
+// GetWebhookDeliveries 获取 webhook 最近的投递记录
+func GetWebhookDeliveries(user models.User, id string) ([]models.WebhookDelivery, error) {
+	webhook, err := repository.GetWebhookByID(id, user.ID)
+	if err != nil {
+		return nil, ErrWebhookNotFound
+	}
+	deliveries, err := repository.GetDeliveriesByWebhook(webhook.ID, deliveryHistoryLimit)
+	if err != nil {
+		return nil, ErrQueryWebhookFail
+	}
+	return deliveries, nil
+}
+
+// RedeliverWebhook 以原始请求体重新加入投递队列，生成一条新的投递记录
+func RedeliverWebhook(user models.User, id, deliveryID string) (*models.WebhookDelivery, error) {
+	webhook, err := repository.GetWebhookByID(id, user.ID)
+	if err != nil {
+		return nil, ErrWebhookNotFound
+	}
+	original, err := repository.GetDeliveryByID(deliveryID, webhook.ID)
+	if err != nil {
+		return nil, ErrDeliveryNotFound
+	}
+
+	deliveries := []models.WebhookDelivery{{
+		WebhookID:     webhook.ID,
+		Event:         original.Event,
+		Payload:       original.Payload,
+		Status:        "pending",
+		NextAttemptAt: time.Now(),
+	}}
+	if err := repository.CreateDeliveries(deliveries); err != nil {
+		return nil, ErrRedeliverWebhookFail
+	}
+	return &deliveries[0], nil
+}
+
+// HandleTaskEvent 为订阅了该事件的 webhook 创建投递，实际发送由后台投递器完成
+func HandleTaskEvent(e events.Event) {
+	webhooks, err := repository.GetActiveWebhooksByUser(e.UserID)
+	if err != nil {
+		log.Printf("查询 webhook 失败: %v", err)
+		return
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+
+	payload := WebhookPayload{Event: e.Type, OccurredAt: e.OccurredAt}
+	payload.Data.Task = webhookTask{Task: e.Task}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("序列化 webhook 事件失败: %v", err)
+		return
+	}
+
+	var deliveries []models.WebhookDelivery
+	for _, webhook := range webhooks {
+		if !subscribesTo(webhook, e.Type) {
+			continue
+		}
+		deliveries = append(deliveries, models.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			Event:         e.Type,
+			Payload:       string(body),
+			Status:        "pending",
+			NextAttemptAt: e.OccurredAt,
+		})
+	}
+	if err := repository.CreateDeliveries(deliveries); err != nil {
+		log.Printf("创建 webhook 投递失败: %v", err)
+	}
+}
+
+func subscribesTo(webhook models.Webhook, eventType string) bool {
+	if len(webhook.Events) == 0 {
+		return true
+	}
+	for _, typ := range webhook.Events {
+		if typ == eventType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+package service
+
+import (
+	"testing"
+
+	"myproject/internal/testutil"
+)
+
+func TestCreateWebhookRejectsPrivateHosts(t *testing.T) {
+	testutil.OpenDB(t)
+	user := testutil.CreateUser(t, "alice")
+
+	for _, rawURL := range []string{
+		"ftp://example.com/hook",
+		"http://localhost:8080/hook",
+		"http://127.0.0.1/hook",
+		"http://[::1]/hook",
+		"http://169.254.169.254/latest/meta-data/",
+		"https://10.0.0.5/hook",
+	} {
+		if _, _, err := CreateWebhook(user, rawURL, nil); err != ErrInvalidWebhookURL {
+			t.Errorf("%s: err = %v, want ErrInvalidWebhookURL", rawURL, err)
+		}
+	}
+	if _, _, err := CreateWebhook(user, "https://hooks.example.com/taskflow", nil); err != nil {
+		t.Errorf("public URL: %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+// Package testutil 提供测试共用的数据库和数据
+package testutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"myproject/config"
+	models "myproject/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// OpenDB 打开测试数据库并迁移全部表，同时替换 config.DB，测试结束后恢复。
+// 默认使用内存 SQLite，每次调用一个独立的数据库；设置 TEST_DB_DRIVER 和 TEST_DATABASE_URL 时
+// 改用指定的数据库（例如 PostgreSQL），迁移前删除已有的表，所以不要指向有数据的库
+func OpenDB(t testing.TB) *gorm.DB {
+	t.Helper()
+	return openDB(t, ":memory:")
+}
+
+// OpenFileDB 与 OpenDB 相同，但默认的 SQLite 数据库放在临时文件中，允许多个连接并发读写，
+// 用于测试并发事务
+func OpenFileDB(t testing.TB) *gorm.DB {
+	t.Helper()
+	return openDB(t, filepath.Join(t.TempDir(), "test.db"))
+}
+
+func openDB(t testing.TB, sqliteDSN string) *gorm.DB {
+	t.Helper()
+
+	driver, dsn := os.Getenv("TEST_DB_DRIVER"), os.Getenv("TEST_DATABASE_URL")
+	shared := driver != ""
+	if !shared {
+		driver = config.DriverSQLite
+		dsn = sqliteDSN
+	}
+
+	db, err := config.Open(driver, dsn)
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	db.Logger = logger.Discard
+
+	if shared {
+		tables := append(models.All(), "task_tags")
+		if err := db.Migrator().DropTable(tables...); err != nil {
+			t.Fatalf("drop tables: %v", err)
+		}
+	}
+	if err := db.AutoMigrate(models.All()...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	previous := config.DB
+	config.DB = db
+	t.Cleanup(func() {
+		config.DB = previous
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// CreateUser 创建测试用户
+func CreateUser(t testing.TB, username string) models.User {
+	t.Helper()
+
+	user := models.User{Username: username, Email: username + "@example.com", Password: "x"}
+	if err := config.DB.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
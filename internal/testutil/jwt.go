@@ -0,0 +1,30 @@
+package testutil
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"myproject/utils"
+)
+
+// UseSigningKey 生成一个 Ed25519 密钥作为令牌签名密钥，使测试可以签发访问令牌和一次性令牌
+func UseSigningKey(t testing.TB) {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ring, err := utils.NewKeyRing([]*utils.SigningKey{{
+		ID:         "test",
+		Algorithm:  "EdDSA",
+		PrivateKey: key,
+		ActiveFrom: time.Now().Add(-time.Hour),
+	}}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	utils.SetKeyRing(ring)
+}
@@ -0,0 +1,134 @@
+package testutil
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"myproject/internal/oidc"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// OIDCIdentity 模拟身份提供方登录的用户
+type OIDCIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+// OIDCProvider 只用于测试的 OpenID Connect 身份提供方，实现发现文档、令牌端点和 JWKS。
+// 浏览器在提供方登录的步骤由 Authorize 代替
+type OIDCProvider struct {
+	Issuer   string
+	ClientID string
+
+	key    ed25519.PrivateKey
+	mu     sync.Mutex
+	grants map[string]grant
+}
+
+type grant struct {
+	identity      OIDCIdentity
+	nonce         string
+	codeChallenge string
+}
+
+// StartOIDCProvider 在本机启动模拟的身份提供方，测试结束时关闭
+func StartOIDCProvider(t testing.TB, clientID string) *OIDCProvider {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &OIDCProvider{ClientID: clientID, key: key, grants: make(map[string]grant)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, oidc.Discovery{
+			Issuer:                p.Issuer,
+			AuthorizationEndpoint: p.Issuer + "/authorize",
+			TokenEndpoint:         p.Issuer + "/token",
+			JWKSURI:               p.Issuer + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		jwk, _ := oidc.NewJWK("mock", "EdDSA", key.Public())
+		writeJSON(w, oidc.JWKS{Keys: []oidc.JWK{jwk}})
+	})
+	mux.HandleFunc("/token", p.token)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	p.Issuer = server.URL
+	return p
+}
+
+// Authorize 模拟用户在提供方登录并同意授权：读取授权地址中的 nonce 和 code_challenge，
+// 返回回调时带回的授权码
+func (p *OIDCProvider) Authorize(t testing.TB, authURL string, identity OIDCIdentity) string {
+	t.Helper()
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	if query.Get("client_id") != p.ClientID || query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("unexpected authorization request %s", authURL)
+	}
+
+	code, err := oidc.RandomString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.mu.Lock()
+	p.grants[code] = grant{identity: identity, nonce: query.Get("nonce"), codeChallenge: query.Get("code_challenge")}
+	p.mu.Unlock()
+	return code
+}
+
+// token 用授权码换取 ID Token，校验 PKCE code_verifier，授权码只能使用一次
+func (p *OIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	code := r.PostFormValue("code")
+	p.mu.Lock()
+	g, ok := p.grants[code]
+	delete(p.grants, code)
+	p.mu.Unlock()
+	if !ok || oidc.CodeChallenge(r.PostFormValue("code_verifier")) != g.codeChallenge {
+		w.WriteHeader(http.StatusBadRequest)
+		writeJSON(w, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	idToken := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.MapClaims{
+		"iss":            p.Issuer,
+		"aud":            p.ClientID,
+		"sub":            g.identity.Subject,
+		"email":          g.identity.Email,
+		"email_verified": g.identity.EmailVerified,
+		"nonce":          g.nonce,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+	})
+	idToken.Header["kid"] = "mock"
+	signed, err := idToken.SignedString(p.key)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, oidc.TokenResponse{AccessToken: "mock-access-token", TokenType: "Bearer", IDToken: signed, ExpiresIn: 300})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
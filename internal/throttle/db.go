@@ -0,0 +1,64 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	models "myproject/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DBStore 把计数保存在数据库中，多个实例共享
+type DBStore struct {
+	db *gorm.DB
+}
+
+func NewDBStore(db *gorm.DB) *DBStore {
+	return &DBStore{db: db}
+}
+
+func (s *DBStore) Get(ctx context.Context, key string, now time.Time) (Counter, error) {
+	var attempt models.LoginAttempt
+	err := s.db.WithContext(ctx).Where("throttle_key = ? AND expires_at > ?", key, now).First(&attempt).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Counter{}, nil
+	}
+	if err != nil {
+		return Counter{}, err
+	}
+	return Counter{Failures: attempt.Failures, LastFailedAt: attempt.LastFailedAt}, nil
+}
+
+func (s *DBStore) Incr(ctx context.Context, key string, now time.Time, ttl time.Duration) (Counter, error) {
+	attempt := models.LoginAttempt{
+		Key:          key,
+		Failures:     1,
+		LastFailedAt: now,
+		ExpiresAt:    now.Add(ttl),
+	}
+	// 已过期的计数从 1 重新开始。MySQL 按顺序执行赋值，failures 必须在 expires_at 之前更新；
+	// PostgreSQL 中未加表名的列与 excluded 中的同名列有歧义，所以引用现有行时带上表名
+	err := s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "throttle_key"}},
+		DoUpdates: clause.Set{
+			{Column: clause.Column{Name: "failures"}, Value: gorm.Expr("CASE WHEN login_attempts.expires_at <= ? THEN 1 ELSE login_attempts.failures + 1 END", now)},
+			{Column: clause.Column{Name: "last_failed_at"}, Value: now},
+			{Column: clause.Column{Name: "expires_at"}, Value: now.Add(ttl)},
+		},
+	}).Create(&attempt).Error
+	if err != nil {
+		return Counter{}, err
+	}
+	return s.Get(ctx, key, now)
+}
+
+func (s *DBStore) Reset(ctx context.Context, key string) error {
+	return s.db.WithContext(ctx).Where("throttle_key = ?", key).Delete(&models.LoginAttempt{}).Error
+}
+
+func (s *DBStore) Prune(ctx context.Context, now time.Time) error {
+	return s.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&models.LoginAttempt{}).Error
+}
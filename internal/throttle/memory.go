@@ -0,0 +1,64 @@
+package throttle
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore 进程内的 Store，只适用于单实例部署
+type MemoryStore struct {
+	mu       sync.Mutex
+	counters map[string]memoryCounter
+}
+
+type memoryCounter struct {
+	Counter
+	expiresAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{counters: make(map[string]memoryCounter)}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string, now time.Time) (Counter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.counters[key]
+	if !ok || !now.Before(c.expiresAt) {
+		return Counter{}, nil
+	}
+	return c.Counter, nil
+}
+
+func (s *MemoryStore) Incr(ctx context.Context, key string, now time.Time, ttl time.Duration) (Counter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.counters[key]
+	if !now.Before(c.expiresAt) {
+		c = memoryCounter{}
+	}
+	c.Failures++
+	c.LastFailedAt = now
+	c.expiresAt = now.Add(ttl)
+	s.counters[key] = c
+	return c.Counter, nil
+}
+
+func (s *MemoryStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.counters, key)
+	return nil
+}
+
+func (s *MemoryStore) Prune(ctx context.Context, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, c := range s.counters {
+		if !now.Before(c.expiresAt) {
+			delete(s.counters, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package throttle
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"myproject/internal/testutil"
+)
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestDBStore(t *testing.T) {
+	testStore(t, NewDBStore(testutil.OpenDB(t)))
+}
+
+func testStore(t *testing.T, s Store) {
+	ctx := context.Background()
+	// MySQL 的 datetime 默认只保存到秒，用整秒避免比较误差
+	now := time.Now().Truncate(time.Second)
+	ttl := time.Minute
+
+	if c, err := s.Get(ctx, "ip:203.0.113.7", now); err != nil || c.Failures != 0 {
+		t.Fatalf("unknown key: %+v, %v", c, err)
+	}
+
+	for want := 1; want <= 3; want++ {
+		c, err := s.Incr(ctx, "ip:203.0.113.7", now.Add(time.Duration(want)*time.Second), ttl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Failures != want || !c.LastFailedAt.Equal(now.Add(time.Duration(want)*time.Second)) {
+			t.Errorf("incr %d: %+v", want, c)
+		}
+	}
+	if c, _ := s.Get(ctx, "ip:203.0.113.7", now.Add(10*time.Second)); c.Failures != 3 {
+		t.Errorf("get: %d failures, want 3", c.Failures)
+	}
+	// 不同的键互不影响
+	if c, _ := s.Incr(ctx, "user:alice", now, ttl); c.Failures != 1 {
+		t.Errorf("other key: %d failures, want 1", c.Failures)
+	}
+
+	// 最后一次失败 ttl 之后计数过期，再次失败从 1 开始
+	expired := now.Add(3*time.Second + ttl)
+	if c, _ := s.Get(ctx, "ip:203.0.113.7", expired); c.Failures != 0 {
+		t.Errorf("expired counter: %d failures, want 0", c.Failures)
+	}
+	if c, _ := s.Incr(ctx, "ip:203.0.113.7", expired, ttl); c.Failures != 1 {
+		t.Errorf("incr after expiry: %d failures, want 1", c.Failures)
+	}
+
+	if err := s.Reset(ctx, "ip:203.0.113.7"); err != nil {
+		t.Fatal(err)
+	}
+	if c, _ := s.Get(ctx, "ip:203.0.113.7", expired); c.Failures != 0 {
+		t.Errorf("after reset: %d failures, want 0", c.Failures)
+	}
+
+	// Prune 只删除已过期的计数
+	s.Incr(ctx, "ip:198.51.100.1", expired, ttl)
+	if err := s.Prune(ctx, expired); err != nil {
+		t.Fatal(err)
+	}
+	if c, _ := s.Get(ctx, "ip:198.51.100.1", expired); c.Failures != 1 {
+		t.Errorf("live counter pruned: %d failures, want 1", c.Failures)
+	}
+	if c, _ := s.Incr(ctx, "user:alice", expired, ttl); c.Failures != 1 {
+		t.Errorf("pruned counter: %d failures after incr, want 1", c.Failures)
+	}
+}
@@ -0,0 +1,71 @@
+// Package throttle 记录登录等操作的失败次数，按指数退避计算下一次允许尝试的时间。
+package throttle
+
+import (
+	"context"
+	"time"
+)
+
+// Counter 某个键（用户名、IP 等）的失败计数
+type Counter struct {
+	Failures     int
+	LastFailedAt time.Time
+}
+
+// Store 失败计数的存储。单实例部署可以用 MemoryStore，多实例部署需要共享的 DBStore
+type Store interface {
+	// Get 返回键的计数，不存在或已过期时返回零值
+	Get(ctx context.Context, key string, now time.Time) (Counter, error)
+	// Incr 原子地增加一次失败并返回新的计数，计数在最后一次失败 ttl 之后过期
+	Incr(ctx context.Context, key string, now time.Time, ttl time.Duration) (Counter, error)
+	// Reset 清除键的计数
+	Reset(ctx context.Context, key string) error
+	// Prune 删除已过期的计数
+	Prune(ctx context.Context, now time.Time) error
+}
+
+// Policy 退避策略：前 FreeAttempts 次失败不限制，之后每次失败的等待时间从 BaseDelay 开始翻倍，
+// 不超过 MaxDelay；失败达到 LockoutThreshold 次后锁定 LockoutDuration。计数在最后一次失败 Window 之后清零
+type Policy struct {
+	FreeAttempts     int
+	BaseDelay        time.Duration
+	MaxDelay         time.Duration
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+	Window           time.Duration
+}
+
+// Delay 失败 failures 次之后需要等待的时间
+func (p Policy) Delay(failures int) time.Duration {
+	if failures >= p.LockoutThreshold {
+		return p.LockoutDuration
+	}
+	if failures <= p.FreeAttempts {
+		return 0
+	}
+	delay := p.BaseDelay
+	for i := p.FreeAttempts + 1; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// RetryAfter 距离允许下一次尝试还需要等待的时间，0 表示现在就可以尝试
+func (p Policy) RetryAfter(c Counter, now time.Time) time.Duration {
+	if c.Failures == 0 {
+		return 0
+	}
+	wait := c.LastFailedAt.Add(p.Delay(c.Failures)).Sub(now)
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+// Locked 是否已达到锁定阈值
+func (p Policy) Locked(c Counter) bool {
+	return c.Failures >= p.LockoutThreshold
+}
@@ -0,0 +1,60 @@
+package throttle
+
+import (
+	"testing"
+	"time"
+)
+
+var testPolicy = Policy{
+	FreeAttempts:     3,
+	BaseDelay:        time.Second,
+	MaxDelay:         10 * time.Second,
+	LockoutThreshold: 10,
+	LockoutDuration:  time.Hour,
+	Window:           24 * time.Hour,
+}
+
+func TestPolicyDelay(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{3, 0},
+		{4, time.Second},
+		{5, 2 * time.Second},
+		{6, 4 * time.Second},
+		{7, 8 * time.Second},
+		{8, 10 * time.Second},
+		{9, 10 * time.Second},
+		{10, time.Hour},
+		{50, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := testPolicy.Delay(tt.failures); got != tt.want {
+			t.Errorf("Delay(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestPolicyRetryAfterAndLocked(t *testing.T) {
+	now := time.Date(2024, 5, 9, 12, 0, 0, 0, time.UTC)
+
+	if d := testPolicy.RetryAfter(Counter{}, now); d != 0 {
+		t.Errorf("no failures: retry after %v", d)
+	}
+	c := Counter{Failures: 5, LastFailedAt: now.Add(-500 * time.Millisecond)}
+	if d := testPolicy.RetryAfter(c, now); d != 1500*time.Millisecond {
+		t.Errorf("retry after %v, want 1.5s", d)
+	}
+	if d := testPolicy.RetryAfter(c, now.Add(time.Minute)); d != 0 {
+		t.Errorf("after the delay: retry after %v, want 0", d)
+	}
+
+	if testPolicy.Locked(Counter{Failures: 9}) || !testPolicy.Locked(Counter{Failures: 10}) {
+		t.Error("lockout threshold not applied at 10 failures")
+	}
+	if d := testPolicy.RetryAfter(Counter{Failures: 10, LastFailedAt: now}, now); d != time.Hour {
+		t.Errorf("locked: retry after %v, want 1h", d)
+	}
+}
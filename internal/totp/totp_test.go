@@ -0,0 +1,76 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 附录 B 的 SHA1 测试向量。RFC 给出的是 8 位验证码，这里取末 6 位
+func TestCodeAtRFC6238Vectors(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},          // 94287082
+		{1111111109, "081804"},  // 07081804
+		{1111111111, "050471"},  // 14050471
+		{1234567890, "005924"},  // 89005924
+		{2000000000, "279037"},  // 69279037
+		{20000000000, "353130"}, // 65353130
+	}
+	for _, tt := range tests {
+		got, err := CodeAt(secret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("T=%d: code = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateAllowsSkew(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+
+	for offset := int64(-2); offset <= 2; offset++ {
+		code, _ := CodeAt(secret, current+offset)
+		step, ok := Validate(secret, " "+code+" ", now, 1)
+		if want := offset >= -1 && offset <= 1; ok != want {
+			t.Errorf("offset %d: ok = %v, want %v", offset, ok, want)
+		}
+		if ok && step != current+offset {
+			t.Errorf("offset %d: step = %d, want %d", offset, step, current+offset)
+		}
+	}
+
+	if _, ok := Validate(secret, "12345", now, 1); ok {
+		t.Error("short code accepted")
+	}
+	if _, ok := Validate("not base32!", "123456", now, 1); ok {
+		t.Error("invalid secret accepted")
+	}
+}
+
+func TestDecodeSecretIgnoresCaseSpacesAndPadding(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := CodeAt(secret, 1)
+
+	for _, variant := range []string{
+		secret + "====",
+		"  " + secret[:4] + " " + secret[4:],
+		strings.ToLower(secret),
+	} {
+		if got, err := CodeAt(variant, 1); err != nil || got != want {
+			t.Errorf("CodeAt(%q) = %q, %v; want %q", variant, got, err, want)
+		}
+	}
+}
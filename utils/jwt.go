@@ -1,38 +1,70 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtSecret = []byte(os.Getenv("JWT_SECRET"))
+// 令牌的签发方和访问令牌的受众，可由 JWT_ISSUER、JWT_AUDIENCE 配置
+var (
+    issuer   = "taskflow"
+    audience = "taskflow-api"
+)
+
+// 只接受非对称签名算法，拒绝 none、HS256 等
+var validMethods = []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}
+
+var errAlgorithmMismatch = errors.New("jwt: algorithm does not match key")
+
+// 访问令牌默认有效期，可由 ACCESS_TOKEN_TTL 配置（如 15m）
+const defaultAccessTokenTTL = 15 * time.Minute
 
 type Claims struct {
-    UserID uint `json:"user_id"`
+    UserID    uint `json:"user_id"`
+    SessionID uint `json:"sid"`
     jwt.RegisteredClaims
 }
 
-// 生成JWT
-func GenerateToken(userID uint) (string, error) {
+// AccessTokenTTL 访问令牌的有效期
+func AccessTokenTTL() time.Duration {
+    if ttl, err := time.ParseDuration(os.Getenv("ACCESS_TOKEN_TTL")); err == nil && ttl > 0 {
+        return ttl
+    }
+    return defaultAccessTokenTTL
+}
+
+// 生成JWT，每个令牌带唯一的 jti，用于注销时加入黑名单；sid 为令牌所属的会话
+func GenerateToken(userID, sessionID uint) (string, error) {
+    jti := make([]byte, 16)
+    if _, err := rand.Read(jti); err != nil {
+        return "", err
+    }
+
+    now := time.Now()
     claims := Claims{
-        UserID: userID,
+        UserID:    userID,
+        SessionID: sessionID,
         RegisteredClaims: jwt.RegisteredClaims{
-            ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-            IssuedAt:  jwt.NewNumericDate(time.Now()),
+            ID:        hex.EncodeToString(jti),
+            Issuer:    issuer,
+            Audience:  jwt.ClaimStrings{audience},
+            ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenTTL())),
+            IssuedAt:  jwt.NewNumericDate(now),
         },
     }
-    
-    token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-    return token.SignedString(jwtSecret)
+
+    return signToken(claims)
 }
 
-// 解析JWT
+// 解析JWT，校验签名算法、kid、签发方和受众
 func ParseToken(tokenString string) (*Claims, error) {
-    token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-        return jwtSecret, nil
-    })
+    token, err := jwt.ParseWithClaims(tokenString, &Claims{}, verificationKey,
+        jwt.WithValidMethods(validMethods), jwt.WithIssuer(issuer), jwt.WithAudience(audience), jwt.WithExpirationRequired())
     
     if err != nil {
         return nil, err
@@ -43,4 +75,73 @@ func ParseToken(tokenString string) (*Claims, error) {
     }
     
     return nil, err
-}
\ No newline at end of file
+}
+
+// ActionClaims 邮件链接中的一次性令牌（验证邮箱、重置密码等），aud 为令牌用途
+type ActionClaims struct {
+    UserID uint   `json:"user_id"`
+    Email  string `json:"email"`
+    jwt.RegisteredClaims
+}
+
+// 生成一次性令牌，jti 由调用方保存用于判断是否已使用
+func GenerateActionToken(userID uint, email, purpose, jti string, ttl time.Duration) (string, error) {
+    now := time.Now()
+    claims := ActionClaims{
+        UserID: userID,
+        Email:  email,
+        RegisteredClaims: jwt.RegisteredClaims{
+            ID:        jti,
+            Issuer:    issuer,
+            Audience:  jwt.ClaimStrings{purpose},
+            ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+            IssuedAt:  jwt.NewNumericDate(now),
+        },
+    }
+
+    return signToken(claims)
+}
+
+// 解析一次性令牌，用途不符时返回错误
+func ParseActionToken(tokenString, purpose string) (*ActionClaims, error) {
+    claims := &ActionClaims{}
+    _, err := jwt.ParseWithClaims(tokenString, claims, verificationKey,
+        jwt.WithValidMethods(validMethods), jwt.WithIssuer(issuer), jwt.WithAudience(purpose), jwt.WithExpirationRequired())
+    if err != nil {
+        return nil, err
+    }
+    return claims, nil
+}
+
+// 用当前的签名密钥签名，并在头部写入 kid
+func signToken(claims jwt.Claims) (string, error) {
+    ring := keyRing.Load()
+    if ring == nil {
+        return "", ErrNoSigningKey
+    }
+    key, err := ring.Signing(time.Now())
+    if err != nil {
+        return "", err
+    }
+
+    token := jwt.NewWithClaims(key.method(), claims)
+    token.Header["kid"] = key.ID
+    return token.SignedString(key.PrivateKey)
+}
+
+// 按 kid 选择验证公钥，令牌声明的算法必须与密钥一致
+func verificationKey(token *jwt.Token) (interface{}, error) {
+    ring := keyRing.Load()
+    if ring == nil {
+        return nil, ErrNoSigningKey
+    }
+    kid, _ := token.Header["kid"].(string)
+    key, err := ring.Verifying(kid, time.Now())
+    if err != nil {
+        return nil, err
+    }
+    if token.Method.Alg() != key.Algorithm {
+        return nil, errAlgorithmMismatch
+    }
+    return key.PublicKey(), nil
+}
@@ -0,0 +1,244 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 签名密钥清单由 JWT_KEYS_FILE 指定，例如：
+//
+//	[
+//	  {"kid": "2026-09", "file": "2026-09.pem", "active_from": "2026-09-01T00:00:00Z"},
+//	  {"kid": "2026-10", "file": "2026-10.pem", "active_from": "2026-10-01T00:00:00Z"}
+//	]
+//
+// file 为 PEM 格式的 RSA（RS256，至少 2048 位）或 Ed25519（EdDSA）私钥，相对路径相对于清单所在目录。
+// 到达 active_from 后改用该密钥签名；上一个密钥在新密钥生效后的重叠期（JWT_KEY_OVERLAP）内仍可验证，
+// 之后被淘汰。尚未生效的密钥会提前出现在 JWKS 中，方便其它服务预先缓存。
+
+var (
+	ErrNoSigningKey = errors.New("jwt: no active signing key")
+	ErrUnknownKeyID = errors.New("jwt: unknown or retired key id")
+)
+
+// 旧密钥的默认重叠期，需要覆盖签发的令牌中最长的有效期（邮箱验证链接 48 小时）
+const defaultKeyOverlap = 72 * time.Hour
+
+// SigningKey 一个签名密钥，Algorithm 由密钥类型决定
+type SigningKey struct {
+	ID         string
+	Algorithm  string
+	PrivateKey crypto.Signer
+	ActiveFrom time.Time
+}
+
+// PublicKey 对应的公钥
+func (k *SigningKey) PublicKey() crypto.PublicKey {
+	return k.PrivateKey.Public()
+}
+
+func (k *SigningKey) method() jwt.SigningMethod {
+	return jwt.GetSigningMethod(k.Algorithm)
+}
+
+// KeyRing 按生效时间排序的签名密钥，当前签名密钥和可验证的密钥都由时间决定，轮换无需重启
+type KeyRing struct {
+	keys    []*SigningKey
+	overlap time.Duration
+}
+
+type keyEntry struct {
+	Kid        string    `json:"kid"`
+	File       string    `json:"file"`
+	ActiveFrom time.Time `json:"active_from"`
+}
+
+// NewKeyRing 校验并创建密钥环
+func NewKeyRing(keys []*SigningKey, overlap time.Duration) (*KeyRing, error) {
+	if len(keys) == 0 {
+		return nil, ErrNoSigningKey
+	}
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		if key.ID == "" {
+			return nil, errors.New("jwt: key id is required")
+		}
+		if seen[key.ID] {
+			return nil, fmt.Errorf("jwt: duplicate key id %q", key.ID)
+		}
+		seen[key.ID] = true
+		if key.method() == nil {
+			return nil, fmt.Errorf("jwt: key %q has unsupported algorithm %q", key.ID, key.Algorithm)
+		}
+	}
+
+	sorted := append([]*SigningKey(nil), keys...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].ActiveFrom.Before(sorted[j].ActiveFrom) })
+	return &KeyRing{keys: sorted, overlap: overlap}, nil
+}
+
+// LoadKeyRing 从密钥清单加载密钥环
+func LoadKeyRing(path string, overlap time.Duration) (*KeyRing, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []keyEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("jwt: parse %s: %w", path, err)
+	}
+
+	keys := make([]*SigningKey, 0, len(entries))
+	for _, entry := range entries {
+		file := entry.File
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(path), file)
+		}
+		pemData, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: key %q: %w", entry.Kid, err)
+		}
+		signer, alg, err := ParsePrivateKey(pemData)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: key %q: %w", entry.Kid, err)
+		}
+		keys = append(keys, &SigningKey{ID: entry.Kid, Algorithm: alg, PrivateKey: signer, ActiveFrom: entry.ActiveFrom})
+	}
+	return NewKeyRing(keys, overlap)
+}
+
+// ParsePrivateKey 解析 PEM 私钥（PKCS#8 或 PKCS#1），返回对应的签名算法
+func ParsePrivateKey(pemData []byte) (crypto.Signer, string, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, "", errors.New("no PEM block found")
+	}
+
+	var key any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, "", fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < 2048 {
+			return nil, "", errors.New("RSA key must be at least 2048 bits")
+		}
+		return k, jwt.SigningMethodRS256.Alg(), nil
+	case ed25519.PrivateKey:
+		return k, jwt.SigningMethodEdDSA.Alg(), nil
+	default:
+		return nil, "", fmt.Errorf("unsupported key type %T", key)
+	}
+}
+
+// Signing 返回 now 时刻用于签名的密钥，即已生效的密钥中最新的一个
+func (r *KeyRing) Signing(now time.Time) (*SigningKey, error) {
+	for i := len(r.keys) - 1; i >= 0; i-- {
+		if !r.keys[i].ActiveFrom.After(now) {
+			return r.keys[i], nil
+		}
+	}
+	return nil, ErrNoSigningKey
+}
+
+// Verifying 返回 now 时刻可以验证 kid 签名的密钥，未生效或已淘汰的密钥不可用
+func (r *KeyRing) Verifying(kid string, now time.Time) (*SigningKey, error) {
+	for i, key := range r.keys {
+		if key.ID != kid {
+			continue
+		}
+		if key.ActiveFrom.After(now) || r.retired(i, now) {
+			break
+		}
+		return key, nil
+	}
+	return nil, ErrUnknownKeyID
+}
+
+// Published 返回 now 时刻应公开的密钥：未淘汰的密钥，包括尚未生效的
+func (r *KeyRing) Published(now time.Time) []*SigningKey {
+	var keys []*SigningKey
+	for i, key := range r.keys {
+		if !r.retired(i, now) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// 后一个密钥生效超过重叠期后，前一个密钥被淘汰
+func (r *KeyRing) retired(i int, now time.Time) bool {
+	return i+1 < len(r.keys) && !now.Before(r.keys[i+1].ActiveFrom.Add(r.overlap))
+}
+
+var keyRing atomic.Pointer[KeyRing]
+
+// SetKeyRing 设置令牌签名使用的密钥环
+func SetKeyRing(ring *KeyRing) {
+	keyRing.Store(ring)
+}
+
+// PublishedKeys 当前应在 JWKS 中公开的公钥
+func PublishedKeys() []*SigningKey {
+	ring := keyRing.Load()
+	if ring == nil {
+		return nil
+	}
+	return ring.Published(time.Now())
+}
+
+// InitJWT 按环境变量加载签名密钥、签发方和受众，没有可用的签名密钥时返回错误
+func InitJWT() error {
+	path := os.Getenv("JWT_KEYS_FILE")
+	if path == "" {
+		return errors.New("JWT_KEYS_FILE is not set")
+	}
+	overlap := defaultKeyOverlap
+	if v := os.Getenv("JWT_KEY_OVERLAP"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid JWT_KEY_OVERLAP %q", v)
+		}
+		overlap = d
+	}
+
+	ring, err := LoadKeyRing(path, overlap)
+	if err != nil {
+		return err
+	}
+	if _, err := ring.Signing(time.Now()); err != nil {
+		return err
+	}
+
+	if v := os.Getenv("JWT_ISSUER"); v != "" {
+		issuer = v
+	}
+	if v := os.Getenv("JWT_AUDIENCE"); v != "" {
+		audience = v
+	}
+	SetKeyRing(ring)
+	return nil
+}